@@ -3,7 +3,6 @@ package api
 import (
 	"database/sql"
 	"errors"
-	"fmt"
 	"net/http"
 	db "simplebank/db/sqlc"
 	"simplebank/token"
@@ -19,7 +18,7 @@ type createAccountRequest struct {
 func (server *Server) createAccount(ctx *gin.Context) {
 	var req createAccountRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		respondError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
@@ -28,18 +27,17 @@ func (server *Server) createAccount(ctx *gin.Context) {
 		Owner:    payload.Username,
 		Currency: req.Currency,
 	}
-	account, err := server.store.CreateAccount(ctx, arg)
+	account, err := server.store.CreateAccountTx(ctx, arg)
 	if err != nil {
 		// 数据库报错为主键约束导致，返回403
 		if pqErr, ok := err.(*pq.Error); ok {
-			fmt.Println(pqErr.Code.Name())
 			switch pqErr.Code.Name() {
 			case "foreign_key_violation", "unique_violation":
-				ctx.JSON(http.StatusForbidden, errorResponse(err))
+				respondError(ctx, http.StatusForbidden, err)
 				return
 			}
 		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -53,32 +51,57 @@ type getAccountRequest struct {
 func (server *Server) getAccount(ctx *gin.Context) {
 	var req getAccountRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		respondError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
 	account, err := server.store.GetAccount(ctx, req.Id)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			respondError(ctx, http.StatusNotFound, err)
 			return
 		}
 
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
 	// 只能查看自己的用户信息
 	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	if account.Owner != payload.Username {
-		err := errors.New("account doesn't belong to the authencited user")
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		respondError(ctx, http.StatusUnauthorized, errAccountNotOwned)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, account)
 }
 
+type accountURIRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// validAccountOwner 检查账户是否存在且属于当前登录用户
+func (server *Server) validAccountOwner(ctx *gin.Context, accountID int64) (db.Account, bool) {
+	account, err := server.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(ctx, http.StatusNotFound, err)
+			return account, false
+		}
+
+		respondError(ctx, http.StatusInternalServerError, err)
+		return account, false
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != payload.Username {
+		respondError(ctx, http.StatusUnauthorized, errAccountNotOwned)
+		return account, false
+	}
+
+	return account, true
+}
+
 type listAccountRequest struct {
 	PageID   int32 `form:"page_id" binding:"required,min=1"`
 	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
@@ -87,7 +110,7 @@ type listAccountRequest struct {
 func (server *Server) listAccount(ctx *gin.Context) {
 	var req listAccountRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		respondError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
@@ -100,70 +123,43 @@ func (server *Server) listAccount(ctx *gin.Context) {
 	}
 	accounts, err := server.store.ListAccounts(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, accounts)
 }
 
-type deleteAccountRequest struct {
-	Id int64 `uri:"id" binding:"required,min=1"`
-}
-
-func (server *Server) deleteAccount(ctx *gin.Context) {
-	var req deleteAccountRequest
-	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+// closeAccount 销户，账户记录和流水保留，余额必须为零
+func (server *Server) closeAccount(ctx *gin.Context) {
+	var uri accountURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	arg := db.DeleteAccountParams{
-		ID:    req.Id,
-		Owner: payload.Username,
-	}
-
-	err := server.store.DeleteAccount(ctx, arg)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	if _, valid := server.validAccountOwner(ctx, uri.ID); !valid {
 		return
 	}
 
-	ctx.Status(http.StatusOK)
-}
-
-type updateAccountRequest struct {
-	Id      int64 `uri:"id" binding:"required,min=1"`
-	Balance int64 `uri:"balance" binding:"required"`
+	server.changeAccountStatus(ctx, uri.ID, db.AccountStatusClosed)
 }
 
-func (server *Server) updateAccount(ctx *gin.Context) {
-	var req updateAccountRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
-		return
-	}
-
-	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	arg := db.UpdateAccountParams{
-		ID:      req.Id,
-		Owner:   payload.Username,
-		Balance: req.Balance,
-	}
-	account, err := server.store.UpdateAccount(ctx, arg)
+// changeAccountStatus 变更账户状态，状态变更规则由 store 检查
+func (server *Server) changeAccountStatus(ctx *gin.Context, accountID int64, status db.AccountStatus) {
+	account, err := server.store.UpdateAccountStatusTx(ctx, db.UpdateAccountStatusTxParams{
+		AccountID: accountID,
+		Status:    status,
+	})
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			respondError(ctx, http.StatusNotFound, err)
+		case errors.Is(err, db.ErrInvalidStatusTransition), errors.Is(err, db.ErrNonZeroBalance):
+			respondError(ctx, http.StatusUnprocessableEntity, err)
+		default:
+			respondError(ctx, http.StatusInternalServerError, err)
 		}
-
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	db "simplebank/db/sqlc"
+	"simplebank/token"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createAdjustmentRequest struct {
+	Amount     int64               `json:"amount" binding:"required"`
+	ReasonCode db.AdjustmentReason `json:"reason_code" binding:"required,oneof=correction fee refund chargeback write_off"`
+	Note       string              `json:"note" binding:"max=255"`
+}
+
+// createAdjustment 管理员调账，通过一笔流水修正账户余额，操作人为当前登录的管理员
+func (server *Server) createAdjustment(ctx *gin.Context) {
+	var uri accountURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var req createAdjustmentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	ctx.Set(auditDetailsKey, req)
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	result, err := server.store.AdjustAccountTx(ctx, db.AdjustAccountTxParams{
+		AccountID:  uri.ID,
+		Amount:     req.Amount,
+		ReasonCode: req.ReasonCode,
+		Note:       req.Note,
+		Actor:      payload.Username,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			respondError(ctx, http.StatusNotFound, err)
+		case errors.Is(err, db.ErrInsufficientFunds), errors.Is(err, db.ErrAccountNotActive):
+			respondError(ctx, http.StatusUnprocessableEntity, err)
+		default:
+			respondError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// adminListAdjustments 查询账户的调账记录
+func (server *Server) adminListAdjustments(ctx *gin.Context) {
+	var uri accountURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var req listAccountRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	adjustments, err := server.store.ListAccountAdjustments(ctx, db.ListAccountAdjustmentsParams{
+		AccountID: uri.ID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, adjustments)
+}
@@ -0,0 +1,314 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	mockdb "simplebank/db/mock"
+	db "simplebank/db/sqlc"
+	"simplebank/token"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAdjustmentAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = db.UserRoleAdmin
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	amount := int64(-10)
+	result := db.AdjustAccountTxResult{
+		Adjustment: db.AccountAdjustment{
+			ID:         1,
+			AccountID:  account.ID,
+			EntryID:    1,
+			Amount:     amount,
+			ReasonCode: db.AdjustmentReasonFee,
+			Note:       "annual fee",
+			Actor:      admin.Username,
+		},
+		Entry: db.Entry{
+			ID:        1,
+			AccountID: account.ID,
+			Amount:    amount,
+		},
+		Account: account,
+	}
+	result.Account.Balance += amount
+
+	testCases := []struct {
+		name          string
+		accountID     int64
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			accountID: account.ID,
+			body: gin.H{
+				"amount":      amount,
+				"reason_code": "fee",
+				"note":        "annual fee",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.AdjustAccountTxParams{
+					AccountID:  account.ID,
+					Amount:     amount,
+					ReasonCode: db.AdjustmentReasonFee,
+					Note:       "annual fee",
+					Actor:      admin.Username,
+				}
+				store.EXPECT().
+					AdjustAccountTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(result, nil)
+				expectAuditLog(t, store, admin.Username, admin.Role, "POST /accounts/:id/adjustments", http.StatusOK)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var gotResult db.AdjustAccountTxResult
+				err := json.Unmarshal(recorder.Body.Bytes(), &gotResult)
+				require.NoError(t, err)
+				require.Equal(t, result, gotResult)
+			},
+		},
+		{
+			name:      "Forbidden",
+			accountID: account.ID,
+			body: gin.H{
+				"amount":      amount,
+				"reason_code": "fee",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					AdjustAccountTx(gomock.Any(), gomock.Any()).
+					Times(0)
+				// 被拒绝的调账请求同样需要记录
+				expectAuditLog(t, store, user.Username, user.Role, "POST /accounts/:id/adjustments", http.StatusForbidden)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "NoAuthorization",
+			accountID: account.ID,
+			body: gin.H{
+				"amount":      amount,
+				"reason_code": "fee",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					AdjustAccountTx(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateAuditLog(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "ZeroAmount",
+			accountID: account.ID,
+			body: gin.H{
+				"amount":      0,
+				"reason_code": "correction",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					AdjustAccountTx(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAuditLog(t, store, admin.Username, admin.Role, "POST /accounts/:id/adjustments", http.StatusBadRequest)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidReasonCode",
+			accountID: account.ID,
+			body: gin.H{
+				"amount":      amount,
+				"reason_code": "gift",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					AdjustAccountTx(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAuditLog(t, store, admin.Username, admin.Role, "POST /accounts/:id/adjustments", http.StatusBadRequest)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "NotFound",
+			accountID: account.ID,
+			body: gin.H{
+				"amount":      amount,
+				"reason_code": "fee",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					AdjustAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.AdjustAccountTxResult{}, sql.ErrNoRows)
+				expectAuditLog(t, store, admin.Username, admin.Role, "POST /accounts/:id/adjustments", http.StatusNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "InsufficientFunds",
+			accountID: account.ID,
+			body: gin.H{
+				"amount":      -account.Balance - 1,
+				"reason_code": "write_off",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					AdjustAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.AdjustAccountTxResult{}, fmt.Errorf("%w: account [%d]", db.ErrInsufficientFunds, account.ID))
+				expectAuditLog(t, store, admin.Username, admin.Role, "POST /accounts/:id/adjustments", http.StatusUnprocessableEntity)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				requireBodyMatchErrorCode(t, recorder.Body, errCodeInsufficientFunds)
+			},
+		},
+		{
+			name:      "AccountClosed",
+			accountID: account.ID,
+			body: gin.H{
+				"amount":      amount,
+				"reason_code": "fee",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					AdjustAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.AdjustAccountTxResult{}, fmt.Errorf("%w: account [%d] is closed", db.ErrAccountNotActive, account.ID))
+				expectAuditLog(t, store, admin.Username, admin.Role, "POST /accounts/:id/adjustments", http.StatusUnprocessableEntity)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				requireBodyMatchErrorCode(t, recorder.Body, errCodeAccountNotActive)
+			},
+		},
+		{
+			name:      "InternalError",
+			accountID: account.ID,
+			body: gin.H{
+				"amount":      amount,
+				"reason_code": "fee",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					AdjustAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.AdjustAccountTxResult{}, sql.ErrConnDone)
+				expectAuditLog(t, store, admin.Username, admin.Role, "POST /accounts/:id/adjustments", http.StatusInternalServerError)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		// 每个测试案例使用子测试运行
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/accounts/%d/adjustments", tc.accountID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAdminListAdjustmentsAPI(t *testing.T) {
+	auditor, _ := randomUser(t)
+	account := randomAccount(auditor.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	arg := db.ListAccountAdjustmentsParams{
+		AccountID: account.ID,
+		Limit:     5,
+		Offset:    5,
+	}
+	store.EXPECT().
+		ListAccountAdjustments(gomock.Any(), gomock.Eq(arg)).
+		Times(1).
+		Return([]db.AccountAdjustment{}, nil)
+	expectAuditLog(t, store, auditor.Username, db.UserRoleAuditor, "GET /admin/accounts/:id/adjustments", http.StatusOK)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	url := fmt.Sprintf("/admin/accounts/%d/adjustments?page_id=2&page_size=5", account.ID)
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, auditor.Username, db.UserRoleAuditor, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
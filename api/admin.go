@@ -0,0 +1,242 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	db "simplebank/db/sqlc"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 管理接口，只对员工开放，所有请求都会写入审计日志
+
+type adminUserURIRequest struct {
+	Username string `uri:"username" binding:"required"`
+}
+
+// adminGetUser 查询任意用户的信息
+func (server *Server) adminGetUser(ctx *gin.Context) {
+	var uri adminUserURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, uri.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(ctx, http.StatusNotFound, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// adminListUserAccounts 查询任意用户的账户
+func (server *Server) adminListUserAccounts(ctx *gin.Context) {
+	var uri adminUserURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var req listAccountRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	accounts, err := server.store.ListAccounts(ctx, db.ListAccountsParams{
+		Owner:  uri.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accounts)
+}
+
+type updateUserRoleRequest struct {
+	Role db.UserRole `json:"role" binding:"required,oneof=customer support admin auditor"`
+}
+
+// adminUpdateUserRole 修改用户角色，用户刷新 access token 后生效
+func (server *Server) adminUpdateUserRole(ctx *gin.Context) {
+	var uri adminUserURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var req updateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	ctx.Set(auditDetailsKey, req)
+
+	user, err := server.store.UpdateUserRole(ctx, db.UpdateUserRoleParams{
+		Username: uri.Username,
+		Role:     req.Role,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(ctx, http.StatusNotFound, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	// 已签发的 access token 中仍是原来的角色，需要吊销，用户刷新 token 后使用新的角色
+	revokedAt := time.Now()
+	_, err = server.store.RevokeUserTokens(ctx, db.RevokeUserTokensParams{
+		Username:        user.Username,
+		TokensRevokedAt: sql.NullTime{Time: revokedAt, Valid: true},
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	server.revocations.revokeUser(user.Username, revokedAt)
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// adminFreezeAccount 冻结账户，冻结后不能转入或转出
+func (server *Server) adminFreezeAccount(ctx *gin.Context) {
+	server.adminChangeAccountStatus(ctx, db.AccountStatusFrozen)
+}
+
+// adminUnfreezeAccount 解冻账户
+func (server *Server) adminUnfreezeAccount(ctx *gin.Context) {
+	server.adminChangeAccountStatus(ctx, db.AccountStatusActive)
+}
+
+// adminCloseAccount 代用户销户，余额必须为零
+func (server *Server) adminCloseAccount(ctx *gin.Context) {
+	server.adminChangeAccountStatus(ctx, db.AccountStatusClosed)
+}
+
+func (server *Server) adminChangeAccountStatus(ctx *gin.Context, status db.AccountStatus) {
+	var uri accountURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	server.changeAccountStatus(ctx, uri.ID, status)
+}
+
+type updateOverdraftLimitRequest struct {
+	OverdraftLimit *int64 `json:"overdraft_limit" binding:"required,min=0"`
+}
+
+// adminUpdateOverdraftLimit 设置账户的透支额度
+// 额度低于当前透支金额时不影响已有余额，只是在还款前不能继续扣款
+func (server *Server) adminUpdateOverdraftLimit(ctx *gin.Context) {
+	var uri accountURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var req updateOverdraftLimitRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	ctx.Set(auditDetailsKey, req)
+
+	account, err := server.store.UpdateAccountOverdraftLimit(ctx, db.UpdateAccountOverdraftLimitParams{
+		ID:             uri.ID,
+		OverdraftLimit: *req.OverdraftLimit,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(ctx, http.StatusNotFound, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, account)
+}
+
+// adminListTransfers 查询任意账户的转账记录，查询条件与 listTransfers 相同
+func (server *Server) adminListTransfers(ctx *gin.Context) {
+	var uri accountURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var req listHistoryRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	filter, err := req.filter()
+	if err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, err := server.store.GetAccount(ctx, uri.ID); err != nil {
+		if err == sql.ErrNoRows {
+			respondError(ctx, http.StatusNotFound, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	server.respondAccountTransfers(ctx, uri.ID, req.PageSize, filter)
+}
+
+type listAuditLogsRequest struct {
+	Actor    string `form:"actor"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// adminListAuditLogs 按时间倒序查询审计日志
+func (server *Server) adminListAuditLogs(ctx *gin.Context) {
+	var req listAuditLogsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	logs, err := server.store.ListAuditLogs(ctx, db.ListAuditLogsParams{
+		Actor:  sql.NullString{String: req.Actor, Valid: len(req.Actor) > 0},
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, logs)
+}
+
+// adminGetReconciliation 返回服务内最近一次定期对账的结果
+func (server *Server) adminGetReconciliation(ctx *gin.Context) {
+	report, ok := server.reconciler.Last()
+	if !ok {
+		respondError(ctx, http.StatusNotFound, errReconciliationNotRun)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
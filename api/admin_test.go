@@ -0,0 +1,640 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	mockdb "simplebank/db/mock"
+	db "simplebank/db/sqlc"
+	"simplebank/reconcile"
+	"simplebank/token"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// 每个管理接口的请求都要写入一条审计日志
+func expectAuditLog(t *testing.T, store *mockdb.MockStore, actor string, role db.UserRole, action string, statusCode int) {
+	store.EXPECT().
+		CreateAuditLog(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateAuditLogParams) (db.AuditLog, error) {
+			require.Equal(t, actor, arg.Actor)
+			require.Equal(t, role, arg.ActorRole)
+			require.Equal(t, action, arg.Action)
+			require.Equal(t, int32(statusCode), arg.StatusCode)
+			require.True(t, json.Valid(arg.Details))
+			return db.AuditLog{}, nil
+		})
+}
+
+func TestAdminAPI(t *testing.T) {
+	staff, _ := randomUser(t)
+	customer, _ := randomUser(t)
+	account := randomAccount(customer.Username)
+
+	frozenAccount := account
+	frozenAccount.Status = db.AccountStatusFrozen
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          gin.H
+		role          db.UserRole
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "GetUser",
+			method: http.MethodGet,
+			url:    "/admin/users/" + customer.Username,
+			role:   db.UserRoleSupport,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(customer.Username)).
+					Times(1).
+					Return(customer, nil)
+				expectAuditLog(t, store, staff.Username, db.UserRoleSupport, "GET /admin/users/:username", http.StatusOK)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchUser(t, recorder.Body, customer)
+			},
+		},
+		{
+			name:   "CustomerForbidden",
+			method: http.MethodGet,
+			url:    "/admin/users/" + customer.Username,
+			role:   db.UserRoleCustomer,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+				// 被拒绝的请求同样需要记录
+				expectAuditLog(t, store, staff.Username, db.UserRoleCustomer, "GET /admin/users/:username", http.StatusForbidden)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "ListUserAccounts",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/admin/users/%s/accounts?page_id=1&page_size=5", customer.Username),
+			role:   db.UserRoleAuditor,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsParams{
+					Owner:  customer.Username,
+					Limit:  5,
+					Offset: 0,
+				}
+				store.EXPECT().
+					ListAccounts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.Account{account}, nil)
+				expectAuditLog(t, store, staff.Username, db.UserRoleAuditor, "GET /admin/users/:username/accounts", http.StatusOK)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccounts(t, recorder.Body, []db.Account{account})
+			},
+		},
+		{
+			name:   "UpdateUserRole",
+			method: http.MethodPut,
+			url:    fmt.Sprintf("/admin/users/%s/role", customer.Username),
+			body:   gin.H{"role": "support"},
+			role:   db.UserRoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpdateUserRoleParams{
+					Username: customer.Username,
+					Role:     db.UserRoleSupport,
+				}
+				updated := customer
+				updated.Role = db.UserRoleSupport
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(updated, nil)
+				store.EXPECT().
+					RevokeUserTokens(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.RevokeUserTokensParams) (db.User, error) {
+						require.Equal(t, customer.Username, arg.Username)
+						require.True(t, arg.TokensRevokedAt.Valid)
+						require.WithinDuration(t, time.Now(), arg.TokensRevokedAt.Time, time.Second)
+						return updated, nil
+					})
+				expectAuditLog(t, store, staff.Username, db.UserRoleAdmin, "PUT /admin/users/:username/role", http.StatusOK)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp userResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, db.UserRoleSupport, rsp.Role)
+			},
+		},
+		{
+			name:   "UpdateUserRoleRevokeTokensError",
+			method: http.MethodPut,
+			url:    fmt.Sprintf("/admin/users/%s/role", customer.Username),
+			body:   gin.H{"role": "support"},
+			role:   db.UserRoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(customer, nil)
+				store.EXPECT().
+					RevokeUserTokens(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+				expectAuditLog(t, store, staff.Username, db.UserRoleAdmin, "PUT /admin/users/:username/role", http.StatusInternalServerError)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:   "UpdateUserRoleInvalidRole",
+			method: http.MethodPut,
+			url:    fmt.Sprintf("/admin/users/%s/role", customer.Username),
+			body:   gin.H{"role": "root"},
+			role:   db.UserRoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAuditLog(t, store, staff.Username, db.UserRoleAdmin, "PUT /admin/users/:username/role", http.StatusBadRequest)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "SupportCannotUpdateRole",
+			method: http.MethodPut,
+			url:    fmt.Sprintf("/admin/users/%s/role", customer.Username),
+			body:   gin.H{"role": "admin"},
+			role:   db.UserRoleSupport,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAuditLog(t, store, staff.Username, db.UserRoleSupport, "PUT /admin/users/:username/role", http.StatusForbidden)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "FreezeAccount",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/accounts/%d/freeze", account.ID),
+			role:   db.UserRoleSupport,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpdateAccountStatusTxParams{
+					AccountID: account.ID,
+					Status:    db.AccountStatusFrozen,
+				}
+				store.EXPECT().
+					UpdateAccountStatusTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(frozenAccount, nil)
+				expectAuditLog(t, store, staff.Username, db.UserRoleSupport, "POST /admin/accounts/:id/freeze", http.StatusOK)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, frozenAccount)
+			},
+		},
+		{
+			name:   "FreezeAccountNotFound",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/accounts/%d/freeze", account.ID),
+			role:   db.UserRoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateAccountStatusTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+				expectAuditLog(t, store, staff.Username, db.UserRoleAdmin, "POST /admin/accounts/:id/freeze", http.StatusNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "AuditorCannotFreeze",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/accounts/%d/freeze", account.ID),
+			role:   db.UserRoleAuditor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateAccountStatusTx(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAuditLog(t, store, staff.Username, db.UserRoleAuditor, "POST /admin/accounts/:id/freeze", http.StatusForbidden)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "UnfreezeAccount",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/accounts/%d/unfreeze", account.ID),
+			role:   db.UserRoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpdateAccountStatusTxParams{
+					AccountID: account.ID,
+					Status:    db.AccountStatusActive,
+				}
+				store.EXPECT().
+					UpdateAccountStatusTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(account, nil)
+				expectAuditLog(t, store, staff.Username, db.UserRoleAdmin, "POST /admin/accounts/:id/unfreeze", http.StatusOK)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, account)
+			},
+		},
+		{
+			name:   "CloseAccount",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/accounts/%d/close", account.ID),
+			role:   db.UserRoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpdateAccountStatusTxParams{
+					AccountID: account.ID,
+					Status:    db.AccountStatusClosed,
+				}
+				store.EXPECT().
+					UpdateAccountStatusTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.Account{}, fmt.Errorf("%w: account [%d] balance %d", db.ErrNonZeroBalance, account.ID, account.Balance))
+				expectAuditLog(t, store, staff.Username, db.UserRoleAdmin, "POST /admin/accounts/:id/close", http.StatusUnprocessableEntity)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				requireBodyMatchErrorCode(t, recorder.Body, errCodeNonZeroBalance)
+			},
+		},
+		{
+			name:   "SupportCannotCloseAccount",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/accounts/%d/close", account.ID),
+			role:   db.UserRoleSupport,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateAccountStatusTx(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAuditLog(t, store, staff.Username, db.UserRoleSupport, "POST /admin/accounts/:id/close", http.StatusForbidden)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "UpdateOverdraftLimit",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/admin/accounts/%d/overdraft_limit", account.ID),
+			body:   gin.H{"overdraft_limit": 500},
+			role:   db.UserRoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpdateAccountOverdraftLimitParams{
+					ID:             account.ID,
+					OverdraftLimit: 500,
+				}
+				updated := account
+				updated.OverdraftLimit = 500
+				store.EXPECT().
+					UpdateAccountOverdraftLimit(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(updated, nil)
+				expectAuditLog(t, store, staff.Username, db.UserRoleAdmin, "PATCH /admin/accounts/:id/overdraft_limit", http.StatusOK)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp db.Account
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, int64(500), rsp.OverdraftLimit)
+			},
+		},
+		{
+			// 额度为 0 表示取消透支
+			name:   "RemoveOverdraftLimit",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/admin/accounts/%d/overdraft_limit", account.ID),
+			body:   gin.H{"overdraft_limit": 0},
+			role:   db.UserRoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpdateAccountOverdraftLimitParams{
+					ID:             account.ID,
+					OverdraftLimit: 0,
+				}
+				store.EXPECT().
+					UpdateAccountOverdraftLimit(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(account, nil)
+				expectAuditLog(t, store, staff.Username, db.UserRoleAdmin, "PATCH /admin/accounts/:id/overdraft_limit", http.StatusOK)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "UpdateOverdraftLimitMissing",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/admin/accounts/%d/overdraft_limit", account.ID),
+			body:   gin.H{},
+			role:   db.UserRoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateAccountOverdraftLimit(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAuditLog(t, store, staff.Username, db.UserRoleAdmin, "PATCH /admin/accounts/:id/overdraft_limit", http.StatusBadRequest)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "UpdateOverdraftLimitNegative",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/admin/accounts/%d/overdraft_limit", account.ID),
+			body:   gin.H{"overdraft_limit": -1},
+			role:   db.UserRoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateAccountOverdraftLimit(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAuditLog(t, store, staff.Username, db.UserRoleAdmin, "PATCH /admin/accounts/:id/overdraft_limit", http.StatusBadRequest)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "UpdateOverdraftLimitNotFound",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/admin/accounts/%d/overdraft_limit", account.ID),
+			body:   gin.H{"overdraft_limit": 500},
+			role:   db.UserRoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateAccountOverdraftLimit(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+				expectAuditLog(t, store, staff.Username, db.UserRoleAdmin, "PATCH /admin/accounts/:id/overdraft_limit", http.StatusNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "SupportCannotUpdateOverdraftLimit",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/admin/accounts/%d/overdraft_limit", account.ID),
+			body:   gin.H{"overdraft_limit": 500},
+			role:   db.UserRoleSupport,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateAccountOverdraftLimit(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAuditLog(t, store, staff.Username, db.UserRoleSupport, "PATCH /admin/accounts/:id/overdraft_limit", http.StatusForbidden)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "ListTransfers",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/admin/accounts/%d/transfers?page_size=5", account.ID),
+			role:   db.UserRoleAuditor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					ListAccountTransfers(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ListAccountTransfersParams) ([]db.Transfer, error) {
+						require.Equal(t, account.ID, arg.AccountID)
+						require.Equal(t, int32(6), arg.Limit)
+						return []db.Transfer{}, nil
+					})
+				expectAuditLog(t, store, staff.Username, db.UserRoleAuditor, "GET /admin/accounts/:id/transfers", http.StatusOK)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "ListTransfersAccountNotFound",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/admin/accounts/%d/transfers?page_size=5", account.ID),
+			role:   db.UserRoleSupport,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().
+					ListAccountTransfers(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAuditLog(t, store, staff.Username, db.UserRoleSupport, "GET /admin/accounts/:id/transfers", http.StatusNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "ListAuditLogs",
+			method: http.MethodGet,
+			url:    "/admin/audit_logs?" + url.Values{"actor": {staff.Username}, "page_id": {"2"}, "page_size": {"10"}}.Encode(),
+			role:   db.UserRoleAuditor,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAuditLogsParams{
+					Actor:  sql.NullString{String: staff.Username, Valid: true},
+					Limit:  10,
+					Offset: 10,
+				}
+				store.EXPECT().
+					ListAuditLogs(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.AuditLog{}, nil)
+				expectAuditLog(t, store, staff.Username, db.UserRoleAuditor, "GET /admin/audit_logs", http.StatusOK)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "SupportCannotListAuditLogs",
+			method: http.MethodGet,
+			url:    "/admin/audit_logs?page_id=1&page_size=10",
+			role:   db.UserRoleSupport,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAuditLogs(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAuditLog(t, store, staff.Username, db.UserRoleSupport, "GET /admin/audit_logs", http.StatusForbidden)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		// 每个测试案例使用子测试运行
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body bytes.Buffer
+			if tc.body != nil {
+				err := json.NewEncoder(&body).Encode(tc.body)
+				require.NoError(t, err)
+			}
+
+			request, err := http.NewRequest(tc.method, tc.url, &body)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, staff.Username, tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAdminUpdateUserRoleRevokesTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user, _ := randomUser(t)
+	admin, _ := randomUser(t)
+	updated := user
+	updated.Role = db.UserRoleSupport
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		UpdateUserRole(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(updated, nil)
+	store.EXPECT().
+		RevokeUserTokens(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(updated, nil)
+	expectAuditLog(t, store, admin.Username, db.UserRoleAdmin, "PUT /admin/users/:username/role", http.StatusOK)
+
+	server := newTestServer(t, store)
+
+	// 角色变更之前签发的 access token 失效
+	_, payload, err := server.tokenMaker.CreateToken(user.Username, string(user.Role), token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+	require.False(t, server.revocations.isRevoked(context.Background(), payload))
+
+	data, err := json.Marshal(gin.H{"role": "support"})
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/admin/users/%s/role", user.Username), bytes.NewReader(data))
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, admin.Username, db.UserRoleAdmin, time.Minute)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.True(t, server.revocations.isRevoked(context.Background(), payload))
+}
+
+func TestAdminAPINoAuthorization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// 未认证的请求没有操作人，不写入审计日志
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		CreateAuditLog(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/admin/audit_logs?page_id=1&page_size=10", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestAdminGetReconciliationAPI(t *testing.T) {
+	auditor, _ := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		CreateAuditLog(gomock.Any(), gomock.Any()).
+		Times(2)
+	store.EXPECT().
+		ListAccountBalances(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.ListAccountBalancesRow{{ID: 1, Balance: 10, EntriesTotal: 0}}, nil)
+	store.EXPECT().
+		ListTransferEntryCounts(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.ListTransferEntryCountsRow{}, nil)
+
+	server := newTestServer(t, store)
+
+	getReconciliation := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/admin/reconciliation", nil)
+		require.NoError(t, err)
+
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, auditor.Username, db.UserRoleAuditor, time.Minute)
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// 还没有执行过对账
+	recorder := getReconciliation()
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+
+	report, err := server.reconciler.Run(context.Background())
+	require.NoError(t, err)
+
+	recorder = getReconciliation()
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var gotReport reconcile.Report
+	err = json.Unmarshal(recorder.Body.Bytes(), &gotReport)
+	require.NoError(t, err)
+	require.Equal(t, report.Discrepancies, gotReport.Discrepancies)
+	require.Equal(t, int64(1), gotReport.AccountsChecked)
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	db "simplebank/db/sqlc"
+	"simplebank/logger"
+	"simplebank/token"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 处理函数可以通过该 key 补充需要记录的操作内容
+const auditDetailsKey = "audit_details"
+
+// auditMiddleware 记录管理接口的每一次请求，包括被拒绝的请求
+// 必须在 authMiddleware 之后、roleMiddleware 之前使用
+func auditMiddleware(store db.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+		details := gin.H{}
+		if len(ctx.Params) > 0 {
+			params := make(map[string]string, len(ctx.Params))
+			for _, param := range ctx.Params {
+				params[param.Key] = param.Value
+			}
+			details["params"] = params
+		}
+		if len(ctx.Request.URL.RawQuery) > 0 {
+			details["query"] = ctx.Request.URL.RawQuery
+		}
+		if value, ok := ctx.Get(auditDetailsKey); ok {
+			details["request"] = value
+		}
+
+		data, err := json.Marshal(details)
+		if err != nil {
+			logger.Ctx(ctx).Error().Err(err).Msg("cannot marshal audit details")
+			data = []byte("{}")
+		}
+
+		// 客户端断开后也要写入审计日志
+		detached, cancel := detach(ctx)
+		defer cancel()
+		_, err = store.CreateAuditLog(detached, db.CreateAuditLogParams{
+			Actor:      payload.Username,
+			ActorRole:  db.UserRole(payload.Role),
+			Action:     ctx.Request.Method + " " + ctx.FullPath(),
+			Path:       ctx.Request.URL.Path,
+			StatusCode: int32(ctx.Writer.Status()),
+			ClientIp:   ctx.ClientIP(),
+			Details:    data,
+		})
+		if err != nil {
+			// 响应已经返回，只能记录错误
+			logger.Ctx(ctx).Error().Err(err).Msg("cannot write audit log")
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"net/http"
+	db "simplebank/db/sqlc"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type listEntriesRequest struct {
+	listHistoryRequest
+	EntryType db.EntryType `form:"entry_type" binding:"omitempty,oneof=transfer_debit transfer_credit deposit withdrawal fee interest adjustment reversal"`
+}
+
+type entryResponse struct {
+	ID        int64        `json:"id"`
+	AccountID int64        `json:"account_id"`
+	Amount    int64        `json:"amount"`
+	EntryType db.EntryType `json:"entry_type"`
+	// 非转账流水没有 transfer_id
+	TransferID *int64    `json:"transfer_id,omitempty"`
+	Memo       string    `json:"memo"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func newEntryResponse(entry db.Entry) entryResponse {
+	rsp := entryResponse{
+		ID:        entry.ID,
+		AccountID: entry.AccountID,
+		Amount:    entry.Amount,
+		EntryType: entry.EntryType,
+		Memo:      entry.Memo,
+		CreatedAt: entry.CreatedAt,
+	}
+	if entry.TransferID.Valid {
+		rsp.TransferID = &entry.TransferID.Int64
+	}
+	return rsp
+}
+
+type listEntriesResponse struct {
+	Entries    []entryResponse `json:"entries"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+func (server *Server) listEntries(ctx *gin.Context) {
+	var uri accountURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var req listEntriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	filter, err := req.filter()
+	if err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	// 只能查看自己账户的流水
+	if _, valid := server.validAccountOwner(ctx, uri.ID); !valid {
+		return
+	}
+
+	entries, err := server.store.ListAccountEntries(ctx, db.ListAccountEntriesParams{
+		AccountID:       uri.ID,
+		StartTime:       filter.StartTime,
+		EndTime:         filter.EndTime,
+		MinAmount:       filter.MinAmount,
+		MaxAmount:       filter.MaxAmount,
+		Direction:       filter.Direction,
+		EntryType:       db.NullEntryType{EntryType: req.EntryType, Valid: len(req.EntryType) > 0},
+		CursorCreatedAt: filter.CursorCreatedAt,
+		CursorID:        filter.CursorID,
+		Limit:           filter.Limit,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := listEntriesResponse{Entries: []entryResponse{}}
+	if len(entries) > int(req.PageSize) {
+		entries = entries[:req.PageSize]
+		last := entries[len(entries)-1]
+		rsp.NextCursor = encodeCursor(historyCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	for _, entry := range entries {
+		rsp.Entries = append(rsp.Entries, newEntryResponse(entry))
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,387 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	mockdb "simplebank/db/mock"
+	db "simplebank/db/sqlc"
+	"simplebank/token"
+	"simplebank/utils"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListEntriesAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	n := 6
+	pageSize := 5
+	entries := make([]db.Entry, n)
+	for i := 0; i < n; i++ {
+		entries[i] = randomEntry(account)
+	}
+
+	startTime := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	cursor := historyCursor{CreatedAt: entries[0].CreatedAt, ID: entries[0].ID}
+
+	testCases := []struct {
+		name          string
+		accountID     int64
+		query         url.Values
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			accountID: account.ID,
+			query:     url.Values{"page_size": {"5"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+
+				arg := db.ListAccountEntriesParams{
+					AccountID: account.ID,
+					Limit:     int32(pageSize + 1),
+				}
+				store.EXPECT().
+					ListAccountEntries(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(entries[:pageSize], nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				rsp := requireBodyMatchEntries(t, recorder.Body, entries[:pageSize])
+				require.Empty(t, rsp.NextCursor)
+			},
+		},
+		{
+			name:      "NextPage",
+			accountID: account.ID,
+			query:     url.Values{"page_size": {"5"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+
+				store.EXPECT().
+					ListAccountEntries(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(entries, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				rsp := requireBodyMatchEntries(t, recorder.Body, entries[:pageSize])
+
+				last := entries[pageSize-1]
+				gotCursor, err := decodeCursor(rsp.NextCursor)
+				require.NoError(t, err)
+				require.Equal(t, last.ID, gotCursor.ID)
+				require.True(t, last.CreatedAt.Equal(gotCursor.CreatedAt))
+			},
+		},
+		{
+			name:      "Filters",
+			accountID: account.ID,
+			query: url.Values{
+				"page_size":  {"5"},
+				"cursor":     {encodeCursor(cursor)},
+				"start_time": {startTime.Format(time.RFC3339)},
+				"min_amount": {"0"},
+				"max_amount": {"100"},
+				"direction":  {"outgoing"},
+				"entry_type": {"transfer_debit"},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+
+				store.EXPECT().
+					ListAccountEntries(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ListAccountEntriesParams) ([]db.Entry, error) {
+						require.Equal(t, account.ID, arg.AccountID)
+						require.True(t, arg.StartTime.Valid)
+						require.True(t, startTime.Equal(arg.StartTime.Time))
+						require.False(t, arg.EndTime.Valid)
+						require.Equal(t, sql.NullInt64{Int64: 0, Valid: true}, arg.MinAmount)
+						require.Equal(t, sql.NullInt64{Int64: 100, Valid: true}, arg.MaxAmount)
+						require.Equal(t, sql.NullString{String: "outgoing", Valid: true}, arg.Direction)
+						require.Equal(t, db.NullEntryType{EntryType: db.EntryTypeTransferDebit, Valid: true}, arg.EntryType)
+						require.True(t, cursor.CreatedAt.Equal(arg.CursorCreatedAt.Time))
+						require.Equal(t, sql.NullInt64{Int64: cursor.ID, Valid: true}, arg.CursorID)
+						return []db.Entry{}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:      "UnauthorizedUser",
+			accountID: account.ID,
+			query:     url.Values{"page_size": {"5"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized_user", db.UserRoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+
+				store.EXPECT().
+					ListAccountEntries(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "NoAuthorization",
+			accountID: account.ID,
+			query:     url.Values{"page_size": {"5"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				store.EXPECT().
+					ListAccountEntries(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "NotFound",
+			accountID: account.ID,
+			query:     url.Values{"page_size": {"5"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+
+				store.EXPECT().
+					ListAccountEntries(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidDirection",
+			accountID: account.ID,
+			query:     url.Values{"page_size": {"5"}, "direction": {"sideways"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				store.EXPECT().
+					ListAccountEntries(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidEntryType",
+			accountID: account.ID,
+			query:     url.Values{"page_size": {"5"}, "entry_type": {"gift"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				store.EXPECT().
+					ListAccountEntries(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidCursor",
+			accountID: account.ID,
+			query:     url.Values{"page_size": {"5"}, "cursor": {"not-a-cursor"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				store.EXPECT().
+					ListAccountEntries(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidAmountRange",
+			accountID: account.ID,
+			query:     url.Values{"page_size": {"5"}, "min_amount": {"100"}, "max_amount": {"10"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				store.EXPECT().
+					ListAccountEntries(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidPageSize",
+			accountID: account.ID,
+			query:     url.Values{"page_size": {"1000"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				store.EXPECT().
+					ListAccountEntries(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InternalError",
+			accountID: account.ID,
+			query:     url.Values{"page_size": {"5"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+
+				store.EXPECT().
+					ListAccountEntries(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Entry{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/entries?%s", tc.accountID, tc.query.Encode())
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func randomEntry(account db.Account) db.Entry {
+	entry := db.Entry{
+		ID:        utils.RandomInt(1, 1000),
+		AccountID: account.ID,
+		Amount:    utils.RandomInt(-1000, 1000),
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		EntryType: db.EntryTypeAdjustment,
+		Memo:      utils.RandomString(6),
+	}
+
+	// 随机生成转账流水
+	if utils.RandomInt(0, 1) == 1 {
+		entry.TransferID = sql.NullInt64{Int64: utils.RandomInt(1, 1000), Valid: true}
+		entry.EntryType = db.EntryTypeTransferCredit
+		if entry.Amount < 0 {
+			entry.EntryType = db.EntryTypeTransferDebit
+		}
+	}
+	return entry
+}
+
+func requireBodyMatchEntries(t *testing.T, body *bytes.Buffer, entries []db.Entry) listEntriesResponse {
+	data, err := ioutil.ReadAll(body)
+	require.NoError(t, err)
+
+	var rsp listEntriesResponse
+	err = json.Unmarshal(data, &rsp)
+	require.NoError(t, err)
+
+	expected := make([]entryResponse, len(entries))
+	for i, entry := range entries {
+		expected[i] = newEntryResponse(entry)
+	}
+	require.Equal(t, expected, rsp.Entries)
+
+	return rsp
+}
@@ -0,0 +1,308 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	db "simplebank/db/sqlc"
+	"simplebank/token"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/lib/pq"
+)
+
+// apiError 是所有错误响应的格式
+// code 是稳定的错误码，客户端根据 code 判断错误类型；message 根据 Accept-Language 本地化
+type apiError struct {
+	Code      string        `json:"code"`
+	Message   string        `json:"message"`
+	Details   []errorDetail `json:"details,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+}
+
+// errorDetail 描述单个字段的错误
+type errorDetail struct {
+	Field   string `json:"field,omitempty"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// 错误码，新增错误码时需要在 messages 中添加各语言的信息
+const (
+	errCodeInvalidArgument    = "invalid_argument"
+	errCodeMalformedBody      = "malformed_body"
+	errCodeUnauthenticated    = "unauthenticated"
+	errCodePermissionDenied   = "permission_denied"
+	errCodeNotFound           = "not_found"
+	errCodeAlreadyExists      = "already_exists"
+	errCodeInvalidReference   = "invalid_reference"
+	errCodeConflict           = "conflict"
+	errCodeFailedPrecondition = "failed_precondition"
+	errCodeInternal           = "internal"
+
+	errCodeInsufficientFunds         = "insufficient_funds"
+	errCodeInvalidQuote              = "invalid_quote"
+	errCodeAccountNotActive          = "account_not_active"
+	errCodeInvalidStatusTransition   = "invalid_status_transition"
+	errCodeNonZeroBalance            = "non_zero_balance"
+	errCodeInvalidReversal           = "invalid_reversal"
+	errCodeScheduledTransferFinished = "scheduled_transfer_finished"
+	errCodeIdempotencyKeyReused      = "idempotency_key_reused"
+	errCodeIdempotencyKeyInProgress  = "idempotency_key_in_progress"
+	errCodeIdempotencyKeyTooLong     = "idempotency_key_too_long"
+
+	errCodeUserNotFound              = "user_not_found"
+	errCodeIncorrectPassword         = "incorrect_password"
+	errCodeAccountNotOwned           = "account_not_owned"
+	errCodeScheduledTransferNotOwned = "scheduled_transfer_not_owned"
+	errCodeWebhookNotOwned           = "webhook_not_owned"
+	errCodeRefreshTokenNotOwned      = "refresh_token_not_owned"
+	errCodeReversalNotAllowed        = "reversal_not_allowed"
+	errCodeCurrencyMismatch          = "currency_mismatch"
+	errCodeExchangeRateUnavailable   = "exchange_rate_unavailable"
+	errCodeReconciliationNotRun      = "reconciliation_not_run"
+	errCodePublicKeysUnavailable     = "public_keys_unavailable"
+	errCodeInvalidCursor             = "invalid_cursor"
+	errCodeInvalidTimeRange          = "invalid_time_range"
+	errCodeInvalidAmountRange        = "invalid_amount_range"
+	errCodeRunAtRequired             = "run_at_required"
+	errCodeRunAtNotInFuture          = "run_at_not_in_future"
+	errCodeWebhookURLNotHTTPS        = "webhook_url_not_https"
+	errCodeInvalidLastEventID        = "invalid_last_event_id"
+	errCodeShuttingDown              = "shutting_down"
+	errCodeDatabaseUnavailable       = "database_unavailable"
+	errCodeSchemaOutdated            = "schema_outdated"
+	errCodeSchemaDirty               = "schema_dirty"
+
+	errCodeMissingAuthorization     = "missing_authorization"
+	errCodeInvalidAuthorization     = "invalid_authorization"
+	errCodeUnsupportedAuthorization = "unsupported_authorization_type"
+	errCodeInvalidToken             = "invalid_token"
+	errCodeTokenExpired             = "token_expired"
+	errCodeInvalidTokenType         = "invalid_token_type"
+	errCodeTokenRevoked             = "token_revoked"
+	errCodeRoleNotAllowed           = "role_not_allowed"
+	errCodeSessionBlocked           = "session_blocked"
+	errCodeSessionUserMismatch      = "session_user_mismatch"
+	errCodeSessionTokenMismatch     = "session_token_mismatch"
+	errCodeSessionExpired           = "session_expired"
+)
+
+// codedError 带有错误码的错误，Error() 返回英文信息，响应时按请求的语言翻译
+type codedError struct {
+	code string
+	args []interface{}
+}
+
+func newCodedError(code string, args ...interface{}) *codedError {
+	return &codedError{code: code, args: args}
+}
+
+func (err *codedError) Error() string {
+	return err.localize(languageEnglish)
+}
+
+func (err *codedError) localize(lang string) string {
+	return localize(lang, err.code, err.args...)
+}
+
+// 处理函数返回的错误
+var (
+	errUserNotFound              = newCodedError(errCodeUserNotFound)
+	errIncorrectPassword         = newCodedError(errCodeIncorrectPassword)
+	errAccountNotOwned           = newCodedError(errCodeAccountNotOwned)
+	errScheduledTransferNotOwned = newCodedError(errCodeScheduledTransferNotOwned)
+	errWebhookNotOwned           = newCodedError(errCodeWebhookNotOwned)
+	errRefreshTokenNotOwned      = newCodedError(errCodeRefreshTokenNotOwned)
+	errReversalNotAllowed        = newCodedError(errCodeReversalNotAllowed)
+	errReconciliationNotRun      = newCodedError(errCodeReconciliationNotRun)
+	errPublicKeysUnavailable     = newCodedError(errCodePublicKeysUnavailable)
+	errIdempotencyKeyReused      = newCodedError(errCodeIdempotencyKeyReused)
+	errIdempotencyKeyInProgress  = newCodedError(errCodeIdempotencyKeyInProgress)
+	errInvalidCursor             = newCodedError(errCodeInvalidCursor)
+	errInvalidTimeRange          = newCodedError(errCodeInvalidTimeRange)
+	errInvalidAmountRange        = newCodedError(errCodeInvalidAmountRange)
+	errRunAtRequired             = newCodedError(errCodeRunAtRequired)
+	errRunAtNotInFuture          = newCodedError(errCodeRunAtNotInFuture)
+	errWebhookURLNotHTTPS        = newCodedError(errCodeWebhookURLNotHTTPS)
+	errInvalidLastEventID        = newCodedError(errCodeInvalidLastEventID)
+	errShuttingDown              = newCodedError(errCodeShuttingDown)
+	errDatabaseUnavailable       = newCodedError(errCodeDatabaseUnavailable)
+	errMissingAuthorization      = newCodedError(errCodeMissingAuthorization)
+	errInvalidAuthorization      = newCodedError(errCodeInvalidAuthorization)
+	errTokenRevoked              = newCodedError(errCodeTokenRevoked)
+	errSessionBlocked            = newCodedError(errCodeSessionBlocked)
+	errSessionUserMismatch       = newCodedError(errCodeSessionUserMismatch)
+	errSessionTokenMismatch      = newCodedError(errCodeSessionTokenMismatch)
+	errSessionExpired            = newCodedError(errCodeSessionExpired)
+)
+
+// 数据层和 token 的错误对应的错误码
+var errorCodesByCause = []struct {
+	cause error
+	code  string
+}{
+	{sql.ErrNoRows, errCodeNotFound},
+	{db.ErrInsufficientFunds, errCodeInsufficientFunds},
+	{db.ErrInvalidQuote, errCodeInvalidQuote},
+	{db.ErrAccountNotActive, errCodeAccountNotActive},
+	{db.ErrInvalidStatusTransition, errCodeInvalidStatusTransition},
+	{db.ErrNonZeroBalance, errCodeNonZeroBalance},
+	{db.ErrInvalidReversal, errCodeInvalidReversal},
+	{db.ErrScheduledTransferFinished, errCodeScheduledTransferFinished},
+	{token.ErrTokenExpired, errCodeTokenExpired},
+	{token.ErrInvalidToken, errCodeInvalidToken},
+	{token.ErrInvalidTokenType, errCodeInvalidTokenType},
+}
+
+// 没有对应错误码的错误按状态码归类
+var errorCodesByStatus = map[int]string{
+	http.StatusBadRequest:          errCodeInvalidArgument,
+	http.StatusUnauthorized:        errCodeUnauthenticated,
+	http.StatusForbidden:           errCodePermissionDenied,
+	http.StatusNotFound:            errCodeNotFound,
+	http.StatusConflict:            errCodeConflict,
+	http.StatusUnprocessableEntity: errCodeFailedPrecondition,
+}
+
+// respondError 中止请求并返回错误响应，原始错误记录在 ctx.Errors 中，只用于日志
+func respondError(ctx *gin.Context, status int, err error) {
+	_ = ctx.Error(err)
+	ctx.AbortWithStatusJSON(status, newAPIError(ctx, status, err))
+}
+
+// newAPIError 将错误转换为响应，不会把数据库等内部错误的原始信息返回给客户端
+func newAPIError(ctx *gin.Context, status int, err error) apiError {
+	lang := preferredLanguage(ctx.GetHeader("Accept-Language"))
+	rsp := apiError{RequestID: ctx.GetString(requestIDKey)}
+
+	var coded *codedError
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	var pqErr *pq.Error
+	switch {
+	case errors.As(err, &coded):
+		rsp.Code = coded.code
+		rsp.Message = coded.localize(lang)
+		return rsp
+	case errors.As(err, &validationErrs):
+		rsp.Code = errCodeInvalidArgument
+		for _, fieldErr := range validationErrs {
+			rsp.Details = append(rsp.Details, newFieldErrorDetail(lang, fieldErr))
+		}
+	case errors.As(err, &typeErr):
+		rsp.Code = errCodeInvalidArgument
+		rsp.Details = []errorDetail{{
+			Field:   typeErr.Field,
+			Reason:  "type",
+			Message: localize(lang, fieldMessageKey("type"), typeErr.Field, typeErr.Type.String()),
+		}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		rsp.Code = errCodeMalformedBody
+	case errors.As(err, &pqErr):
+		rsp.Code = pqErrorCode(pqErr)
+	default:
+		rsp.Code = errorCodeOf(status, err)
+	}
+
+	rsp.Message = localize(lang, rsp.Code)
+	return rsp
+}
+
+func errorCodeOf(status int, err error) string {
+	// 5xx 错误不区分原因，避免泄露内部信息
+	if status >= http.StatusInternalServerError {
+		return errCodeInternal
+	}
+
+	for _, known := range errorCodesByCause {
+		if errors.Is(err, known.cause) {
+			return known.code
+		}
+	}
+
+	if code, ok := errorCodesByStatus[status]; ok {
+		return code
+	}
+	return errCodeInvalidArgument
+}
+
+// pqErrorCode 约束冲突可以由客户端修正，其他数据库错误都作为内部错误
+func pqErrorCode(pqErr *pq.Error) string {
+	switch pqErr.Code.Name() {
+	case "unique_violation":
+		return errCodeAlreadyExists
+	case "foreign_key_violation":
+		return errCodeInvalidReference
+	case "check_violation":
+		return errCodeInvalidArgument
+	}
+	return errCodeInternal
+}
+
+func newFieldErrorDetail(lang string, fieldErr validator.FieldError) errorDetail {
+	field := fieldErr.Field()
+	tag := fieldErr.Tag()
+
+	var message string
+	switch tag {
+	case "min", "max":
+		// 字符串限制长度，数字限制大小
+		if fieldErr.Kind().String() == "string" {
+			tag += "_length"
+		}
+		message = localize(lang, fieldMessageKey(tag), field, fieldErr.Param())
+	case "gt", "oneof":
+		message = localize(lang, fieldMessageKey(tag), field, strings.ReplaceAll(fieldErr.Param(), " ", ", "))
+	case "nefield":
+		message = localize(lang, fieldMessageKey(tag), field, snakeCase(fieldErr.Param()))
+	default:
+		if _, ok := messages[languageEnglish][fieldMessageKey(tag)]; !ok {
+			tag = "invalid"
+		}
+		message = localize(lang, fieldMessageKey(tag), field)
+	}
+
+	return errorDetail{Field: field, Reason: fieldErr.Tag(), Message: message}
+}
+
+// snakeCase 将 nefield 等规则参数中的结构体字段名转换为请求中的字段名，例如 FromAccountID -> from_account_id
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func fieldMessageKey(tag string) string {
+	return "field." + tag
+}
+
+// fieldTagName 让校验错误使用请求中的字段名，而不是结构体的字段名
+func fieldTagName(field reflect.StructField) string {
+	for _, key := range []string{"json", "form", "uri"} {
+		name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+		if name == "-" {
+			return ""
+		}
+		if len(name) > 0 {
+			return name
+		}
+	}
+	return field.Name
+}
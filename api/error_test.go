@@ -0,0 +1,333 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	mockdb "simplebank/db/mock"
+	db "simplebank/db/sqlc"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorResponse(t *testing.T) {
+	user, password := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		acceptLanguage string
+		requestID      string
+		setupAuth      func(t *testing.T, request *http.Request, server *Server)
+		buildStubs     func(store *mockdb.MockStore)
+		checkResponse  func(t *testing.T, recorder *httptest.ResponseRecorder, rsp apiError)
+	}{
+		{
+			name:   "ValidationError",
+			method: http.MethodPost,
+			url:    "/users",
+			body:   `{"username":"","password":"123","full_name":"Tom","email":"invalid"}`,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, rsp apiError) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Equal(t, errCodeInvalidArgument, rsp.Code)
+				require.Equal(t, "the request is invalid", rsp.Message)
+				require.Equal(t, []errorDetail{
+					{Field: "username", Reason: "required", Message: "username is required"},
+					{Field: "password", Reason: "min", Message: "password must be at least 6 characters"},
+					{Field: "email", Reason: "email", Message: "email must be a valid email address"},
+				}, rsp.Details)
+			},
+		},
+		{
+			name:           "ValidationErrorChinese",
+			method:         http.MethodPost,
+			url:            "/users",
+			body:           `{"username":"tom","password":"123456","full_name":"Tom"}`,
+			acceptLanguage: "zh-CN,zh;q=0.9,en;q=0.8",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, rsp apiError) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Equal(t, errCodeInvalidArgument, rsp.Code)
+				require.Equal(t, "请求参数错误", rsp.Message)
+				require.Equal(t, []errorDetail{
+					{Field: "email", Reason: "required", Message: "email 不能为空"},
+				}, rsp.Details)
+			},
+		},
+		{
+			name:   "TypeError",
+			method: http.MethodPost,
+			url:    "/users",
+			body:   `{"username":1}`,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, rsp apiError) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Equal(t, errCodeInvalidArgument, rsp.Code)
+				require.Equal(t, []errorDetail{
+					{Field: "username", Reason: "type", Message: "username must be of type string"},
+				}, rsp.Details)
+			},
+		},
+		{
+			name:   "MalformedBody",
+			method: http.MethodPost,
+			url:    "/users",
+			body:   `{"username":`,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, rsp apiError) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Equal(t, errCodeMalformedBody, rsp.Code)
+				require.Empty(t, rsp.Details)
+			},
+		},
+		{
+			name:   "UniqueViolation",
+			method: http.MethodPost,
+			url:    "/users",
+			body:   `{"username":"tom","password":"123456","full_name":"Tom","email":"tom@example.com"}`,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, &pq.Error{Code: "23505", Message: `duplicate key value violates unique constraint "users_pkey"`})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, rsp apiError) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Equal(t, errCodeAlreadyExists, rsp.Code)
+				// 不返回数据库的原始错误信息
+				require.NotContains(t, recorder.Body.String(), "users_pkey")
+			},
+		},
+		{
+			name:   "InternalError",
+			method: http.MethodPost,
+			url:    "/users/login",
+			body:   `{"username":"tom","password":"123456"}`,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, &pq.Error{Code: "57P01", Message: "terminating connection due to administrator command"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, rsp apiError) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Equal(t, errCodeInternal, rsp.Code)
+				require.Equal(t, "internal server error", rsp.Message)
+				require.NotContains(t, recorder.Body.String(), "terminating")
+			},
+		},
+		{
+			name:           "UserNotFoundChinese",
+			method:         http.MethodPost,
+			url:            "/users/login",
+			body:           `{"username":"tom","password":"123456"}`,
+			acceptLanguage: "zh",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, rsp apiError) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.Equal(t, errCodeUserNotFound, rsp.Code)
+				require.Equal(t, "用户名不存在", rsp.Message)
+			},
+		},
+		{
+			name:   "IncorrectPassword",
+			method: http.MethodPost,
+			url:    "/users/login",
+			body:   `{"username":"` + user.Username + `","password":"` + password + `x"}`,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, rsp apiError) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, errCodeIncorrectPassword, rsp.Code)
+				require.Equal(t, "incorrect password", rsp.Message)
+			},
+		},
+		{
+			name:   "CurrencyMismatch",
+			method: http.MethodPost,
+			url:    "/transfer",
+			body:   `{"from_account_id":1,"to_account_id":2,"amount":10,"currency":"EUR"}`,
+			setupAuth: func(t *testing.T, request *http.Request, server *Server) {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, db.UserRoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				account := account
+				account.ID = 1
+				account.Currency = "USD"
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(int64(1))).Times(1).Return(account, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, rsp apiError) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Equal(t, errCodeCurrencyMismatch, rsp.Code)
+				require.Equal(t, "account 1 currency mismatch: USD vs EUR", rsp.Message)
+			},
+		},
+		{
+			name:      "MissingAuthorization",
+			method:    http.MethodGet,
+			url:       "/accounts/1",
+			requestID: "request-1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, rsp apiError) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, errCodeMissingAuthorization, rsp.Code)
+				require.Equal(t, "request-1", rsp.RequestID)
+			},
+		},
+		{
+			name:   "RoleNotAllowedChinese",
+			method: http.MethodGet,
+			url:    "/admin/audit_logs?page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, server *Server) {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, db.UserRoleCustomer, time.Minute)
+			},
+			acceptLanguage: "zh-TW",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAuditLog(gomock.Any(), gomock.Any()).AnyTimes()
+				store.EXPECT().ListAuditLogs(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, rsp apiError) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Equal(t, errCodeRoleNotAllowed, rsp.Code)
+				require.Equal(t, `角色 "customer" 无权访问该资源`, rsp.Message)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			if len(tc.acceptLanguage) > 0 {
+				request.Header.Set("Accept-Language", tc.acceptLanguage)
+			}
+			if len(tc.requestID) > 0 {
+				request.Header.Set(requestIDHeaderKey, tc.requestID)
+			}
+			if tc.setupAuth != nil {
+				tc.setupAuth(t, request, server)
+			}
+
+			server.router.ServeHTTP(recorder, request)
+
+			var rsp apiError
+			err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+			// 每个错误响应都带有 request ID
+			require.NotEmpty(t, rsp.RequestID)
+			require.Equal(t, recorder.Header().Get(requestIDHeaderKey), rsp.RequestID)
+			tc.checkResponse(t, recorder, rsp)
+		})
+	}
+}
+
+func TestMessages(t *testing.T) {
+	// 每种语言都有全部的信息
+	for _, lang := range supportedLanguages {
+		require.Len(t, messages[lang], len(messages[languageEnglish]), lang)
+		for key := range messages[languageEnglish] {
+			require.Containsf(t, messages[lang], key, "%s is not translated to %s", key, lang)
+		}
+	}
+
+	for _, known := range errorCodesByCause {
+		require.Contains(t, messages[languageEnglish], known.code)
+	}
+	for _, code := range errorCodesByStatus {
+		require.Contains(t, messages[languageEnglish], code)
+	}
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	testCases := []struct {
+		acceptLanguage string
+		lang           string
+	}{
+		{"", languageEnglish},
+		{"en-US,en;q=0.9", languageEnglish},
+		{"zh-CN,zh;q=0.9", languageChinese},
+		{"zh-Hant-TW", languageChinese},
+		{"fr-FR", languageEnglish},
+		{"fr-FR, zh;q=0.5", languageChinese},
+		{"en;q=0.2, zh;q=0.8", languageChinese},
+		{";;invalid", languageEnglish},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.lang, preferredLanguage(tc.acceptLanguage), tc.acceptLanguage)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	require.Equal(t, "from_account_id", snakeCase("FromAccountID"))
+	require.Equal(t, "currency", snakeCase("Currency"))
+	require.Equal(t, "quote_id", snakeCase("QuoteID"))
+}
+
+func TestNefieldErrorDetail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+	user, _ := randomUser(t)
+	body, err := json.Marshal(gin.H{"from_currency": "USD", "to_currency": "USD"})
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/fx/quotes", bytes.NewReader(body))
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, db.UserRoleCustomer, time.Minute)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	var rsp apiError
+	err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Equal(t, []errorDetail{
+		{Field: "to_currency", Reason: "nefield", Message: "to_currency must be different from from_currency"},
+	}, rsp.Details)
+}
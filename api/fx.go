@@ -0,0 +1,74 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	db "simplebank/db/sqlc"
+	"simplebank/token"
+	"simplebank/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type createFxQuoteRequest struct {
+	FromCurrency string `json:"from_currency" binding:"required,currency"`
+	ToCurrency   string `json:"to_currency" binding:"required,currency,nefield=FromCurrency"`
+}
+
+type fxQuoteResponse struct {
+	ID           uuid.UUID `json:"id"`
+	FromCurrency string    `json:"from_currency"`
+	ToCurrency   string    `json:"to_currency"`
+	Rate         string    `json:"rate"`
+	ExpiredAt    time.Time `json:"expired_at"`
+}
+
+func newFxQuoteResponse(quote db.FxQuote) fxQuoteResponse {
+	return fxQuoteResponse{
+		ID:           quote.ID,
+		FromCurrency: quote.FromCurrency,
+		ToCurrency:   quote.ToCurrency,
+		Rate:         utils.FormatExchangeRate(quote.Rate),
+		ExpiredAt:    quote.ExpiredAt,
+	}
+}
+
+// createFxQuote 按当前汇率生成报价，汇率在报价有效期内保持不变
+func (server *Server) createFxQuote(ctx *gin.Context) {
+	var req createFxQuoteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	rate, err := server.store.GetExchangeRate(ctx, db.GetExchangeRateParams{
+		BaseCurrency:  req.FromCurrency,
+		QuoteCurrency: req.ToCurrency,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(ctx, http.StatusNotFound, newCodedError(errCodeExchangeRateUnavailable, req.FromCurrency, req.ToCurrency))
+			return
+		}
+
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	quote, err := server.store.CreateFxQuote(ctx, db.CreateFxQuoteParams{
+		Username:     payload.Username,
+		FromCurrency: rate.BaseCurrency,
+		ToCurrency:   rate.QuoteCurrency,
+		Rate:         rate.Rate,
+		ExpiredAt:    time.Now().Add(server.config.FXQuoteDuration),
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newFxQuoteResponse(quote))
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	healthStatusOK = "ok"
+	// 检查数据库的超时，避免探针请求堆积
+	readinessTimeout = 2 * time.Second
+)
+
+type healthResponse struct {
+	Status string `json:"status"`
+}
+
+type readinessResponse struct {
+	Status        string `json:"status"`
+	SchemaVersion int64  `json:"schema_version"`
+}
+
+// healthz 进程存活即返回 200，不检查依赖，避免数据库故障时所有实例被重启
+func (server *Server) healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, healthResponse{Status: healthStatusOK})
+}
+
+// readyz 检查数据库连接和迁移版本，服务关闭中或数据库不可用时返回 503，不再接收流量
+func (server *Server) readyz(ctx *gin.Context) {
+	select {
+	case <-server.shuttingDown:
+		respondError(ctx, http.StatusServiceUnavailable, errShuttingDown)
+		return
+	default:
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	if err := server.store.Ping(checkCtx); err != nil {
+		_ = ctx.Error(err)
+		respondError(ctx, http.StatusServiceUnavailable, errDatabaseUnavailable)
+		return
+	}
+
+	version, dirty, err := server.store.SchemaVersion(checkCtx)
+	if err != nil {
+		_ = ctx.Error(err)
+		respondError(ctx, http.StatusServiceUnavailable, errDatabaseUnavailable)
+		return
+	}
+	if dirty {
+		respondError(ctx, http.StatusServiceUnavailable, newCodedError(errCodeSchemaDirty, version))
+		return
+	}
+	if version < int64(server.schemaVersion) {
+		respondError(ctx, http.StatusServiceUnavailable, newCodedError(errCodeSchemaOutdated, version, server.schemaVersion))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, readinessResponse{Status: healthStatusOK, SchemaVersion: version})
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"simplebank/db/migration"
+	mockdb "simplebank/db/mock"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthzAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// 存活探针不检查数据库
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().Ping(gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/healthz", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"status":"ok"}`, recorder.Body.String())
+}
+
+func TestReadyzAPI(t *testing.T) {
+	latest, err := migration.LatestVersion()
+	require.NoError(t, err)
+	version := int64(latest)
+
+	testCases := []struct {
+		name          string
+		setupServer   func(t *testing.T, server *Server)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().SchemaVersion(gomock.Any()).Times(1).Return(version, false, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp readinessResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, readinessResponse{Status: healthStatusOK, SchemaVersion: version}, rsp)
+			},
+		},
+		{
+			name: "NewerSchema",
+			buildStubs: func(store *mockdb.MockStore) {
+				// 滚动发布时数据库可能已经迁移到新版本
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().SchemaVersion(gomock.Any()).Times(1).Return(version+1, false, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "PingError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(sql.ErrConnDone)
+				store.EXPECT().SchemaVersion(gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				requireBodyMatchErrorCode(t, recorder.Body, errCodeDatabaseUnavailable)
+			},
+		},
+		{
+			name: "NoMigrations",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().SchemaVersion(gomock.Any()).Times(1).Return(int64(0), false, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				requireBodyMatchErrorCode(t, recorder.Body, errCodeDatabaseUnavailable)
+			},
+		},
+		{
+			name: "SchemaOutdated",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().SchemaVersion(gomock.Any()).Times(1).Return(version-1, false, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				requireBodyMatchErrorCode(t, recorder.Body, errCodeSchemaOutdated)
+			},
+		},
+		{
+			name: "SchemaDirty",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().SchemaVersion(gomock.Any()).Times(1).Return(version, true, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				requireBodyMatchErrorCode(t, recorder.Body, errCodeSchemaDirty)
+			},
+		},
+		{
+			name: "ShuttingDown",
+			setupServer: func(t *testing.T, server *Server) {
+				require.NoError(t, server.Shutdown(context.Background()))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				requireBodyMatchErrorCode(t, recorder.Body, errCodeShuttingDown)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			if tc.setupServer != nil {
+				tc.setupServer(t, server)
+			}
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/readyz", nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
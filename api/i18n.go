@@ -0,0 +1,198 @@
+package api
+
+import (
+	"fmt"
+
+	"golang.org/x/text/language"
+)
+
+// 支持的语言，第一个为默认语言
+const (
+	languageEnglish = "en"
+	languageChinese = "zh"
+)
+
+var languageMatcher = language.NewMatcher([]language.Tag{language.English, language.Chinese})
+
+var supportedLanguages = []string{languageEnglish, languageChinese}
+
+// preferredLanguage 根据 Accept-Language 请求头选择语言，不支持时使用英文
+func preferredLanguage(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return languageEnglish
+	}
+
+	_, index, confidence := languageMatcher.Match(tags...)
+	if confidence == language.No {
+		return languageEnglish
+	}
+	return supportedLanguages[index]
+}
+
+// localize 返回 key 在指定语言下的信息，缺少翻译时使用英文
+func localize(lang string, key string, args ...interface{}) string {
+	message, ok := messages[lang][key]
+	if !ok {
+		message = messages[languageEnglish][key]
+	}
+
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// messages 以错误码为 key，字段校验的信息以 "field." 加校验规则为 key
+var messages = map[string]map[string]string{
+	languageEnglish: {
+		errCodeInvalidArgument:    "the request is invalid",
+		errCodeMalformedBody:      "the request body is not valid JSON",
+		errCodeUnauthenticated:    "authentication is required",
+		errCodePermissionDenied:   "permission denied",
+		errCodeNotFound:           "resource not found",
+		errCodeAlreadyExists:      "resource already exists",
+		errCodeInvalidReference:   "a referenced resource does not exist",
+		errCodeConflict:           "the request conflicts with the current state",
+		errCodeFailedPrecondition: "the request cannot be processed in the current state",
+		errCodeInternal:           "internal server error",
+
+		errCodeInsufficientFunds:         "insufficient funds",
+		errCodeInvalidQuote:              "the fx quote is invalid, expired or already used",
+		errCodeAccountNotActive:          "account is not active",
+		errCodeInvalidStatusTransition:   "the account status cannot be changed this way",
+		errCodeNonZeroBalance:            "account balance is not zero",
+		errCodeInvalidReversal:           "invalid reversal amount",
+		errCodeScheduledTransferFinished: "scheduled transfer is completed or cancelled",
+		errCodeIdempotencyKeyReused:      "idempotency key has already been used for a different request",
+		errCodeIdempotencyKeyInProgress:  "a request with the same idempotency key is still in progress",
+		errCodeIdempotencyKeyTooLong:     "idempotency key must be at most %d characters",
+
+		errCodeUserNotFound:              "user not found",
+		errCodeIncorrectPassword:         "incorrect password",
+		errCodeAccountNotOwned:           "account doesn't belong to the authenticated user",
+		errCodeScheduledTransferNotOwned: "scheduled transfer doesn't belong to the authenticated user",
+		errCodeWebhookNotOwned:           "webhook doesn't belong to the authenticated user",
+		errCodeRefreshTokenNotOwned:      "refresh token doesn't belong to the authenticated user",
+		errCodeReversalNotAllowed:        "only the recipient or an admin can reverse the transfer",
+		errCodeCurrencyMismatch:          "account %d currency mismatch: %s vs %s",
+		errCodeExchangeRateUnavailable:   "exchange rate %s/%s is not available",
+		errCodeReconciliationNotRun:      "reconciliation has not run yet",
+		errCodePublicKeysUnavailable:     "tokens are not signed with public keys",
+		errCodeInvalidCursor:             "invalid cursor",
+		errCodeInvalidTimeRange:          "end_time must be after start_time",
+		errCodeInvalidAmountRange:        "min_amount must not be greater than max_amount",
+		errCodeRunAtRequired:             "run_at is required for a one-off scheduled transfer",
+		errCodeRunAtNotInFuture:          "run_at must be in the future",
+		errCodeWebhookURLNotHTTPS:        "webhook url must use https",
+		errCodeInvalidLastEventID:        "invalid Last-Event-ID",
+		errCodeShuttingDown:              "server is shutting down",
+		errCodeDatabaseUnavailable:       "database is unavailable",
+		errCodeSchemaOutdated:            "database schema version %d is behind the required version %d",
+		errCodeSchemaDirty:               "database migration %d failed and must be fixed manually",
+
+		errCodeMissingAuthorization:     "authorization header is not provided",
+		errCodeInvalidAuthorization:     "invalid authorization header format",
+		errCodeUnsupportedAuthorization: "unsupported authorization type: %s",
+		errCodeInvalidToken:             "token is invalid",
+		errCodeTokenExpired:             "token is expired",
+		errCodeInvalidTokenType:         "token type is invalid",
+		errCodeTokenRevoked:             "token has been revoked",
+		errCodeRoleNotAllowed:           "role %q is not allowed to access this resource",
+		errCodeSessionBlocked:           "blocked session",
+		errCodeSessionUserMismatch:      "incorrect session user",
+		errCodeSessionTokenMismatch:     "mismatched session token",
+		errCodeSessionExpired:           "expired session",
+
+		"field.required":    "%s is required",
+		"field.min":         "%s must be at least %s",
+		"field.max":         "%s must be at most %s",
+		"field.min_length":  "%s must be at least %s characters",
+		"field.max_length":  "%s must be at most %s characters",
+		"field.gt":          "%s must be greater than %s",
+		"field.oneof":       "%s must be one of: %s",
+		"field.email":       "%s must be a valid email address",
+		"field.uuid":        "%s must be a valid UUID",
+		"field.currency":    "%s is not a supported currency",
+		"field.cron":        "%s is not a valid cron expression",
+		"field.webhook_url": "%s must be a public http or https URL",
+		"field.nefield":     "%s must be different from %s",
+		"field.type":        "%s must be of type %s",
+		"field.invalid":     "%s is invalid",
+	},
+	languageChinese: {
+		errCodeInvalidArgument:    "请求参数错误",
+		errCodeMalformedBody:      "请求体不是有效的 JSON",
+		errCodeUnauthenticated:    "需要登录认证",
+		errCodePermissionDenied:   "没有权限",
+		errCodeNotFound:           "资源不存在",
+		errCodeAlreadyExists:      "资源已存在",
+		errCodeInvalidReference:   "关联的资源不存在",
+		errCodeConflict:           "请求与当前状态冲突",
+		errCodeFailedPrecondition: "当前状态下无法处理该请求",
+		errCodeInternal:           "服务器内部错误",
+
+		errCodeInsufficientFunds:         "余额不足",
+		errCodeInvalidQuote:              "汇率报价无效、已过期或已使用",
+		errCodeAccountNotActive:          "账户不是正常状态",
+		errCodeInvalidStatusTransition:   "账户状态不能这样变更",
+		errCodeNonZeroBalance:            "账户余额不为零",
+		errCodeInvalidReversal:           "冲正金额无效",
+		errCodeScheduledTransferFinished: "定时转账已完成或已取消",
+		errCodeIdempotencyKeyReused:      "幂等键已用于其他请求",
+		errCodeIdempotencyKeyInProgress:  "相同幂等键的请求仍在处理中",
+		errCodeIdempotencyKeyTooLong:     "幂等键最长为 %d 个字符",
+
+		errCodeUserNotFound:              "用户名不存在",
+		errCodeIncorrectPassword:         "密码错误",
+		errCodeAccountNotOwned:           "账户不属于当前用户",
+		errCodeScheduledTransferNotOwned: "定时转账不属于当前用户",
+		errCodeWebhookNotOwned:           "webhook 不属于当前用户",
+		errCodeRefreshTokenNotOwned:      "refresh token 不属于当前用户",
+		errCodeReversalNotAllowed:        "只有收款人或管理员可以冲正该转账",
+		errCodeCurrencyMismatch:          "账户 %d 的币种与请求不一致：%s / %s",
+		errCodeExchangeRateUnavailable:   "暂不支持 %s/%s 的汇率",
+		errCodeReconciliationNotRun:      "尚未执行对账",
+		errCodePublicKeysUnavailable:     "token 未使用公钥签名",
+		errCodeInvalidCursor:             "分页游标无效",
+		errCodeInvalidTimeRange:          "end_time 必须晚于 start_time",
+		errCodeInvalidAmountRange:        "min_amount 不能大于 max_amount",
+		errCodeRunAtRequired:             "一次性定时转账必须指定 run_at",
+		errCodeRunAtNotInFuture:          "run_at 必须晚于当前时间",
+		errCodeWebhookURLNotHTTPS:        "webhook 地址必须使用 https",
+		errCodeInvalidLastEventID:        "Last-Event-ID 无效",
+		errCodeShuttingDown:              "服务正在关闭",
+		errCodeDatabaseUnavailable:       "数据库不可用",
+		errCodeSchemaOutdated:            "数据库迁移版本 %d 低于要求的版本 %d",
+		errCodeSchemaDirty:               "数据库迁移 %d 执行失败，需要人工处理",
+
+		errCodeMissingAuthorization:     "缺少 authorization 请求头",
+		errCodeInvalidAuthorization:     "authorization 请求头格式错误",
+		errCodeUnsupportedAuthorization: "不支持的认证类型：%s",
+		errCodeInvalidToken:             "token 无效",
+		errCodeTokenExpired:             "token 已过期",
+		errCodeInvalidTokenType:         "token 类型错误",
+		errCodeTokenRevoked:             "token 已被吊销",
+		errCodeRoleNotAllowed:           "角色 %q 无权访问该资源",
+		errCodeSessionBlocked:           "会话已被封禁",
+		errCodeSessionUserMismatch:      "会话用户不匹配",
+		errCodeSessionTokenMismatch:     "会话 token 不匹配",
+		errCodeSessionExpired:           "会话已过期",
+
+		"field.required":    "%s 不能为空",
+		"field.min":         "%s 不能小于 %s",
+		"field.max":         "%s 不能大于 %s",
+		"field.min_length":  "%s 至少为 %s 个字符",
+		"field.max_length":  "%s 最多为 %s 个字符",
+		"field.gt":          "%s 必须大于 %s",
+		"field.oneof":       "%s 必须是以下值之一：%s",
+		"field.email":       "%s 不是有效的邮箱地址",
+		"field.uuid":        "%s 不是有效的 UUID",
+		"field.currency":    "%s 不是支持的币种",
+		"field.cron":        "%s 不是有效的 cron 表达式",
+		"field.webhook_url": "%s 必须是公网的 http 或 https 地址",
+		"field.nefield":     "%s 不能与 %s 相同",
+		"field.type":        "%s 的类型必须为 %s",
+		"field.invalid":     "%s 无效",
+	},
+}
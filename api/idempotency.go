@@ -0,0 +1,202 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"net/http"
+	db "simplebank/db/sqlc"
+	"simplebank/logger"
+	"simplebank/token"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyKeyHeaderKey      = "Idempotency-Key"
+	idempotencyReplayedHeaderKey = "Idempotent-Replayed"
+	maxIdempotencyKeyLength      = 255
+)
+
+// bodyRecorder 在写出响应的同时记录响应内容，用于保存幂等请求的结果
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotencyMiddleware 根据 Idempotency-Key 请求头保证同一请求只执行一次
+// 必须在 authMiddleware 之后使用，key 以用户为单位隔离
+//   - 首次请求：预留 key，执行请求并保存成功的响应；请求失败时释放 key，允许客户端重试
+//   - 重复请求：请求内容相同时返回保存的响应，内容不同或首次请求仍在处理中时返回 409
+func idempotencyMiddleware(store db.Store, ttl time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(idempotencyKeyHeaderKey)
+		if len(key) == 0 {
+			ctx.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			respondError(ctx, http.StatusBadRequest, newCodedError(errCodeIdempotencyKeyTooLong, maxIdempotencyKeyLength))
+			return
+		}
+
+		// 读取请求体计算摘要，之后放回请求中供处理函数使用
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			respondError(ctx, http.StatusBadRequest, err)
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		requestHash := hashRequest(ctx.Request.Method, ctx.Request.URL.Path, body)
+
+		_, err = store.CreateIdempotencyKey(ctx, db.CreateIdempotencyKeyParams{
+			Username:       payload.Username,
+			IdempotencyKey: key,
+			RequestHash:    requestHash,
+			ExpiredAt:      time.Now().Add(ttl),
+		})
+		if err != nil {
+			if err != sql.ErrNoRows {
+				respondError(ctx, http.StatusInternalServerError, err)
+				return
+			}
+
+			// key 已被使用且未过期
+			replayIdempotentResponse(ctx, store, payload.Username, key, requestHash)
+			return
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: ctx.Writer, body: &bytes.Buffer{}}
+		ctx.Writer = recorder
+		ctx.Next()
+
+		// 客户端断开后仍然需要释放或保存幂等键
+		detached, cancel := detach(ctx)
+		defer cancel()
+
+		status := recorder.Status()
+		if status < http.StatusOK || status >= http.StatusMultipleChoices {
+			err = store.DeleteIdempotencyKey(detached, db.DeleteIdempotencyKeyParams{
+				Username:       payload.Username,
+				IdempotencyKey: key,
+			})
+			if err != nil {
+				// 释放失败时使用同一个 key 重试会返回 409，直到 key 过期
+				logger.Ctx(ctx).Error().Err(err).Str("idempotency_key", key).Msg("cannot release idempotency key")
+			}
+			return
+		}
+
+		// 响应已经返回给客户端，保存失败时 key 会一直处于处理中直到过期
+		_, err = store.UpdateIdempotencyKeyResponse(detached, db.UpdateIdempotencyKeyResponseParams{
+			Username:       payload.Username,
+			IdempotencyKey: key,
+			ResponseCode:   sql.NullInt32{Int32: int32(status), Valid: true},
+			ResponseBody:   recorder.body.Bytes(),
+		})
+		if err != nil {
+			logger.Ctx(ctx).Error().Err(err).Str("idempotency_key", key).Msg("cannot save idempotent response")
+		}
+	}
+}
+
+func replayIdempotentResponse(ctx *gin.Context, store db.Store, username, key, requestHash string) {
+	record, err := store.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{
+		Username:       username,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	if record.RequestHash != requestHash {
+		respondError(ctx, http.StatusConflict, errIdempotencyKeyReused)
+		return
+	}
+
+	if !record.ResponseCode.Valid {
+		respondError(ctx, http.StatusConflict, errIdempotencyKeyInProgress)
+		return
+	}
+
+	ctx.Header(idempotencyReplayedHeaderKey, "true")
+	ctx.Data(int(record.ResponseCode.Int32), gin.MIMEJSON+"; charset=utf-8", record.ResponseBody)
+	ctx.Abort()
+}
+
+// hashRequest 计算请求的摘要，用于判断重复请求的内容是否一致
+func hashRequest(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(path))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// purgeExpiredIdempotencyKeys 按 interval 定期删除过期的幂等键，直到 ctx 被取消
+// 过期的 key 不会再用于重放，不清理的话表会一直增长
+func purgeExpiredIdempotencyKeys(ctx context.Context, store db.Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := store.DeleteExpiredIdempotencyKeys(ctx)
+			if err != nil {
+				logger.Ctx(ctx).Error().Err(err).Msg("cannot purge expired idempotency keys")
+				continue
+			}
+			if count > 0 {
+				logger.Ctx(ctx).Debug().Int64("count", count).Msg("purged expired idempotency keys")
+			}
+		}
+	}
+}
+
+// detachedTimeout 请求结束后写入数据库的最长等待时间，避免数据库无响应时请求一直无法结束
+const detachedTimeout = 5 * time.Second
+
+// detachedContext 保留 ctx 中的值，但不会随请求被取消
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (deadline time.Time, ok bool) {
+	return
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+// detach 返回不随请求取消的 context，用于响应之后的数据库写入，最长等待 detachedTimeout
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(detachedContext{ctx}, detachedTimeout)
+}
@@ -0,0 +1,372 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	mockdb "simplebank/db/mock"
+	db "simplebank/db/sqlc"
+	"simplebank/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	username := utils.RandomOwner()
+	key := utils.RandomString(16)
+	path := "/idempotent"
+	body := []byte(`{"amount":10}`)
+	requestHash := hashRequest(http.MethodPost, path, body)
+	savedResponse := []byte(`{"id":1}`)
+
+	testCases := []struct {
+		name          string
+		key           string
+		handlerStatus int
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int)
+	}{
+		{
+			name:          "NoKey",
+			key:           "",
+			handlerStatus: http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, 1, handlerCalls)
+			},
+		},
+		{
+			name:          "FirstRequest",
+			key:           key,
+			handlerStatus: http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateIdempotencyKeyParams) (db.IdempotencyKey, error) {
+						require.Equal(t, username, arg.Username)
+						require.Equal(t, key, arg.IdempotencyKey)
+						require.Equal(t, requestHash, arg.RequestHash)
+						require.WithinDuration(t, time.Now().Add(time.Minute), arg.ExpiredAt, time.Second)
+						return db.IdempotencyKey{Username: arg.Username, IdempotencyKey: arg.IdempotencyKey}, nil
+					})
+
+				arg := db.UpdateIdempotencyKeyResponseParams{
+					Username:       username,
+					IdempotencyKey: key,
+					ResponseCode:   sql.NullInt32{Int32: http.StatusOK, Valid: true},
+					ResponseBody:   savedResponse,
+				}
+				store.EXPECT().
+					UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Eq(arg)).
+					Times(1)
+
+				store.EXPECT().
+					DeleteIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, 1, handlerCalls)
+				require.Empty(t, recorder.Header().Get(idempotencyReplayedHeaderKey))
+			},
+		},
+		{
+			name:          "Replay",
+			key:           key,
+			handlerStatus: http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrNoRows)
+
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Eq(db.GetIdempotencyKeyParams{Username: username, IdempotencyKey: key})).
+					Times(1).
+					Return(db.IdempotencyKey{
+						Username:       username,
+						IdempotencyKey: key,
+						RequestHash:    requestHash,
+						ResponseCode:   sql.NullInt32{Int32: http.StatusOK, Valid: true},
+						ResponseBody:   savedResponse,
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, 0, handlerCalls)
+				require.Equal(t, savedResponse, recorder.Body.Bytes())
+				require.Equal(t, "true", recorder.Header().Get(idempotencyReplayedHeaderKey))
+			},
+		},
+		{
+			name:          "RequestMismatch",
+			key:           key,
+			handlerStatus: http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrNoRows)
+
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{
+						RequestHash:  "other",
+						ResponseCode: sql.NullInt32{Int32: http.StatusOK, Valid: true},
+						ResponseBody: savedResponse,
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				require.Equal(t, 0, handlerCalls)
+			},
+		},
+		{
+			name:          "InProgress",
+			key:           key,
+			handlerStatus: http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrNoRows)
+
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{RequestHash: requestHash}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				require.Equal(t, 0, handlerCalls)
+			},
+		},
+		{
+			name:          "HandlerFailed",
+			key:           key,
+			handlerStatus: http.StatusInternalServerError,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, nil)
+
+				store.EXPECT().
+					UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				store.EXPECT().
+					DeleteIdempotencyKey(gomock.Any(), gomock.Eq(db.DeleteIdempotencyKeyParams{Username: username, IdempotencyKey: key})).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Equal(t, 1, handlerCalls)
+			},
+		},
+		{
+			name:          "KeyTooLong",
+			key:           strings.Repeat("k", maxIdempotencyKeyLength+1),
+			handlerStatus: http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Equal(t, 0, handlerCalls)
+			},
+		},
+		{
+			name:          "InternalError",
+			key:           key,
+			handlerStatus: http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerCalls int) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Equal(t, 0, handlerCalls)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			handlerCalls := 0
+			server.router.POST(
+				path,
+				authMiddleware(server.tokenMaker, server.revocations),
+				idempotencyMiddleware(server.store, server.config.IdempotencyKeyTTL),
+				func(ctx *gin.Context) {
+					handlerCalls++
+					// 处理函数仍然可以读取完整的请求体
+					var req gin.H
+					require.NoError(t, ctx.ShouldBindJSON(&req))
+					ctx.Data(tc.handlerStatus, gin.MIMEJSON, savedResponse)
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+			require.NoError(t, err)
+
+			if len(tc.key) > 0 {
+				request.Header.Set(idempotencyKeyHeaderKey, tc.key)
+			}
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, db.UserRoleCustomer, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, handlerCalls)
+		})
+	}
+}
+
+// 释放或保存幂等键失败时响应已经返回，只能记录日志
+func TestIdempotencyMiddlewareStoreErrors(t *testing.T) {
+	username := utils.RandomOwner()
+	key := utils.RandomString(16)
+	path := "/idempotent"
+
+	testCases := []struct {
+		name          string
+		handlerStatus int
+		buildStubs    func(store *mockdb.MockStore)
+		message       string
+	}{
+		{
+			name:          "SaveFailed",
+			handlerStatus: http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateIdempotencyKeyResponse(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotencyKey{}, sql.ErrConnDone)
+			},
+			message: "cannot save idempotent response",
+		},
+		{
+			name:          "ReleaseFailed",
+			handlerStatus: http.StatusBadRequest,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteIdempotencyKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sql.ErrConnDone)
+			},
+			message: "cannot release idempotency key",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				CreateIdempotencyKey(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.IdempotencyKey{}, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.router.POST(
+				path,
+				authMiddleware(server.tokenMaker, server.revocations),
+				idempotencyMiddleware(server.store, server.config.IdempotencyKeyTTL),
+				func(ctx *gin.Context) {
+					ctx.Data(tc.handlerStatus, gin.MIMEJSON, []byte(`{}`))
+				},
+			)
+			logs := captureLogs(t)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, path, strings.NewReader(`{}`))
+			require.NoError(t, err)
+			request.Header.Set(idempotencyKeyHeaderKey, key)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, db.UserRoleCustomer, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.handlerStatus, recorder.Code)
+			require.Contains(t, logs.String(), tc.message)
+			require.Contains(t, logs.String(), sql.ErrConnDone.Error())
+		})
+	}
+}
+
+func TestDetach(t *testing.T) {
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), authorizationPayloadKey, "value"))
+	detached, cancelDetached := detach(parent)
+	defer cancelDetached()
+
+	// 请求取消后 detached 不会被取消，但仍然有超时
+	cancel()
+	require.NoError(t, detached.Err())
+	require.Equal(t, "value", detached.Value(authorizationPayloadKey))
+
+	deadline, ok := detached.Deadline()
+	require.True(t, ok)
+	require.WithinDuration(t, time.Now().Add(detachedTimeout), deadline, time.Second)
+}
+
+func TestPurgeExpiredIdempotencyKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 清理失败时等待下次清理，不会退出
+	purged := make(chan struct{})
+	store := mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().
+			DeleteExpiredIdempotencyKeys(gomock.Any()).
+			Times(1).
+			Return(int64(0), sql.ErrConnDone),
+		store.EXPECT().
+			DeleteExpiredIdempotencyKeys(gomock.Any()).
+			Times(1).
+			DoAndReturn(func(context.Context) (int64, error) {
+				cancel()
+				close(purged)
+				return 3, nil
+			}),
+	)
+
+	done := make(chan struct{})
+	go func() {
+		purgeExpiredIdempotencyKeys(ctx, store, time.Millisecond)
+		close(done)
+	}()
+
+	<-purged
+	<-done
+}
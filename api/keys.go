@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+	"simplebank/token"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 公钥以 JWK (RFC 8037) 格式返回，kid 与 token footer 中的 kid 对应
+type publicKeyResponse struct {
+	KeyID     string `json:"kid"`
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+}
+
+type listPublicKeysResponse struct {
+	Keys []publicKeyResponse `json:"keys"`
+}
+
+func newPublicKeyResponse(key token.PublicKey) publicKeyResponse {
+	return publicKeyResponse{
+		KeyID:     key.ID,
+		KeyType:   "OKP",
+		Curve:     "Ed25519",
+		X:         base64.RawURLEncoding.EncodeToString(key.Key),
+		Use:       "sig",
+		Algorithm: "v2.public",
+	}
+}
+
+// listPublicKeys 返回验证 token 所需的公钥，对称加密的 token 没有公钥
+func (server *Server) listPublicKeys(ctx *gin.Context) {
+	keySet, ok := server.tokenMaker.(token.KeySet)
+	if !ok {
+		respondError(ctx, http.StatusNotFound, errPublicKeysUnavailable)
+		return
+	}
+
+	rsp := listPublicKeysResponse{Keys: []publicKeyResponse{}}
+	for _, key := range keySet.PublicKeys() {
+		rsp.Keys = append(rsp.Keys, newPublicKeyResponse(key))
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
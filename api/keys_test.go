@@ -0,0 +1,86 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	mockdb "simplebank/db/mock"
+	db "simplebank/db/sqlc"
+	"simplebank/utils"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListPublicKeysAPI(t *testing.T) {
+	currentPublicKey, currentPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	oldPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	config := utils.Config{
+		TokenKeyID:          "key-2",
+		TokenPrivateKey:     base64.StdEncoding.EncodeToString(currentPrivateKey.Seed()),
+		TokenPublicKeys:     "key-1:" + base64.StdEncoding.EncodeToString(oldPublicKey),
+		AccessTokenDuartion: time.Minute,
+	}
+	server, err := NewServer(config, store)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/.well-known/keys", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp listPublicKeysResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Len(t, rsp.Keys, 2)
+
+	require.Equal(t, "key-2", rsp.Keys[0].KeyID)
+	require.Equal(t, base64.RawURLEncoding.EncodeToString(currentPublicKey), rsp.Keys[0].X)
+	require.Equal(t, "key-1", rsp.Keys[1].KeyID)
+	require.Equal(t, base64.RawURLEncoding.EncodeToString(oldPublicKey), rsp.Keys[1].X)
+
+	// 签发的 token 可以通过认证
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodPost, "/sessions/invalid/block", nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, utils.RandomOwner(), db.UserRoleCustomer, time.Minute)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestListPublicKeysSymmetric(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/.well-known/keys", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestNewServerInvalidTokenKeys(t *testing.T) {
+	config := utils.Config{
+		TokenKeyID:      "key-1",
+		TokenPrivateKey: "invalid",
+	}
+	_, err := NewServer(config, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"simplebank/logger"
+	"simplebank/token"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 健康检查的路由
+var probePaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+// loggerMiddleware 在请求结束后记录一条访问日志，服务端错误记录为 error 级别
+func loggerMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		startTime := time.Now()
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		log := logger.Ctx(ctx)
+		event := log.Info()
+		switch {
+		case status >= http.StatusInternalServerError:
+			event = log.Error()
+		case probePaths[ctx.FullPath()]:
+			// 探针请求很频繁，成功时只在 debug 级别记录
+			event = log.Debug()
+		}
+
+		if value, ok := ctx.Get(authorizationPayloadKey); ok {
+			event = event.Str("username", value.(*token.Payload).Username)
+		}
+		// respondError 保存的原始错误，响应中不会返回内部错误的细节
+		if len(ctx.Errors) > 0 {
+			event = event.Strs("errors", ctx.Errors.Errors())
+		}
+
+		event.Str("method", ctx.Request.Method).
+			Str("path", ctx.Request.URL.Path).
+			Int("status", status).
+			Dur("latency", time.Since(startTime)).
+			Str("client_ip", ctx.ClientIP()).
+			Int("size", ctx.Writer.Size()).
+			Msg("request")
+	}
+}
+
+// recoveryMiddleware 捕获 handler 中的 panic，记录调用栈后返回 500
+func recoveryMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				logger.Ctx(ctx).Error().
+					Interface("panic", recovered).
+					Bytes("stack", debug.Stack()).
+					Msg("recovered from panic")
+				respondError(ctx, http.StatusInternalServerError, fmt.Errorf("panic: %v", recovered))
+			}
+		}()
+		ctx.Next()
+	}
+}
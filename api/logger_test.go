@@ -0,0 +1,148 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	mockdb "simplebank/db/mock"
+	db "simplebank/db/sqlc"
+	"simplebank/logger"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogs 把全局 logger 替换为写入 buffer 的 JSON logger，测试结束后恢复
+func captureLogs(t *testing.T) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	original := log.Logger
+	log.Logger = zerolog.New(buf)
+	t.Cleanup(func() {
+		log.Logger = original
+	})
+	return buf
+}
+
+// lastLogEntry 返回 buffer 中最后一条日志
+func lastLogEntry(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.NotEmpty(t, lines)
+
+	var entry map[string]interface{}
+	err := json.Unmarshal(lines[len(lines)-1], &entry)
+	require.NoError(t, err)
+	return entry
+}
+
+func TestLoggerMiddleware(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name       string
+		path       string
+		requestID  string
+		buildStubs func(store *mockdb.MockStore, storeRequestID *string)
+		checkLog   func(t *testing.T, entry map[string]interface{}, storeRequestID string)
+	}{
+		{
+			name:      "OK",
+			path:      fmt.Sprintf("/accounts/%d", account.ID),
+			requestID: "request-1",
+			buildStubs: func(store *mockdb.MockStore, storeRequestID *string) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, id int64) (db.Account, error) {
+						*storeRequestID = logger.RequestID(ctx)
+						return account, nil
+					})
+			},
+			checkLog: func(t *testing.T, entry map[string]interface{}, storeRequestID string) {
+				require.Equal(t, "info", entry["level"])
+				require.Equal(t, "request-1", entry["request_id"])
+				require.Equal(t, "request-1", storeRequestID)
+				require.Equal(t, http.MethodGet, entry["method"])
+				require.Equal(t, fmt.Sprintf("/accounts/%d", account.ID), entry["path"])
+				require.Equal(t, float64(http.StatusOK), entry["status"])
+				require.Equal(t, user.Username, entry["username"])
+				require.Contains(t, entry, "latency")
+				require.NotContains(t, entry, "errors")
+			},
+		},
+		{
+			name: "InternalError",
+			path: fmt.Sprintf("/accounts/%d", account.ID),
+			buildStubs: func(store *mockdb.MockStore, storeRequestID *string) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, id int64) (db.Account, error) {
+						*storeRequestID = logger.RequestID(ctx)
+						return db.Account{}, sql.ErrConnDone
+					})
+			},
+			checkLog: func(t *testing.T, entry map[string]interface{}, storeRequestID string) {
+				require.Equal(t, "error", entry["level"])
+				require.NotEmpty(t, entry["request_id"])
+				require.Equal(t, entry["request_id"], storeRequestID)
+				require.Equal(t, float64(http.StatusInternalServerError), entry["status"])
+				// 响应中不返回内部错误，日志中保留原始错误
+				require.Equal(t, []interface{}{sql.ErrConnDone.Error()}, entry["errors"])
+			},
+		},
+		{
+			name: "Panic",
+			path: "/panic",
+			buildStubs: func(store *mockdb.MockStore, storeRequestID *string) {
+			},
+			checkLog: func(t *testing.T, entry map[string]interface{}, storeRequestID string) {
+				require.Equal(t, "error", entry["level"])
+				require.Equal(t, float64(http.StatusInternalServerError), entry["status"])
+				require.Equal(t, []interface{}{"panic: boom"}, entry["errors"])
+				require.NotContains(t, entry, "username")
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var storeRequestID string
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store, &storeRequestID)
+
+			server := newTestServer(t, store)
+			server.router.GET("/panic", func(ctx *gin.Context) {
+				panic("boom")
+			})
+			buf := captureLogs(t)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, tc.path, nil)
+			require.NoError(t, err)
+			if len(tc.requestID) > 0 {
+				request.Header.Set(requestIDHeaderKey, tc.requestID)
+			}
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			entry := lastLogEntry(t, buf)
+			require.Equal(t, recorder.Header().Get(requestIDHeaderKey), entry["request_id"])
+			tc.checkLog(t, entry, storeRequestID)
+		})
+	}
+}
@@ -13,8 +13,11 @@ import (
 
 func newTestServer(t *testing.T, store db.Store) *Server {
 	config := utils.Config{
-		TokenSymmetricKey:   utils.RandomString(32),
-		AccessTokenDuartion: time.Minute,
+		TokenSymmetricKey:    utils.RandomString(32),
+		AccessTokenDuartion:  time.Minute,
+		RefreshTokenDuration: time.Minute,
+		IdempotencyKeyTTL:    time.Minute,
+		FXQuoteDuration:      time.Minute,
 	}
 
 	server, err := NewServer(config, store)
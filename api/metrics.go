@@ -0,0 +1,24 @@
+package api
+
+import (
+	"simplebank/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// metricsMiddleware 按路由模板统计请求数量和耗时，避免路径参数导致指标数量无限增长
+func metricsMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		startTime := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		method := ctx.Request.Method
+		status := strconv.Itoa(ctx.Writer.Status())
+
+		metrics.HTTPRequests.WithLabelValues(method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(method, route).Observe(time.Since(startTime).Seconds())
+	}
+}
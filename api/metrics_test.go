@@ -0,0 +1,73 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	mockdb "simplebank/db/mock"
+	"simplebank/metrics"
+	"simplebank/utils"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(account, nil)
+
+	server := newTestServer(t, store)
+	requests := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/accounts/:id", "200")
+	before := testutil.ToFloat64(requests)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d", account.ID), nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	// 按路由模板而不是实际路径统计
+	require.Equal(t, before+1, testutil.ToFloat64(requests))
+
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodGet, metrics.Path, nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `simplebank_http_requests_total{method="GET",route="/accounts/:id",status="200"}`)
+	require.Contains(t, recorder.Body.String(), `simplebank_http_request_duration_seconds_bucket{method="GET",route="/accounts/:id"`)
+}
+
+func TestMetricsOnAdminAddress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	config := utils.Config{
+		TokenSymmetricKey: utils.RandomString(32),
+		MetricsAddress:    "127.0.0.1:0",
+	}
+	server, err := NewServer(config, mockdb.NewMockStore(ctrl))
+	require.NoError(t, err)
+
+	// 配置了单独的地址时 API 端口不暴露指标
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, metrics.Path, nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
@@ -1,9 +1,8 @@
 package api
 
 import (
-	"errors"
-	"fmt"
 	"net/http"
+	db "simplebank/db/sqlc"
 	"simplebank/token"
 	"strings"
 
@@ -16,29 +15,26 @@ const (
 	authorizationPayloadKey = "authorization_payload"
 )
 
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+func authMiddleware(tokenMaker token.Maker, revocations *tokenRevocationCache) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		// 获取客户端传输的认证头信息
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
 		if len(authorizationHeader) == 0 {
-			err := errors.New("authorization header is not provided")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			respondError(ctx, http.StatusUnauthorized, errMissingAuthorization)
 			return
 		}
 
 		// 该头信息分为两部分：授权类型 + token值
 		fields := strings.Fields(authorizationHeader)
 		if len(fields) < 2 {
-			err := errors.New("invalid authorization header format")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			respondError(ctx, http.StatusUnauthorized, errInvalidAuthorization)
 			return
 		}
 
 		// 判断授权类型是否匹配
 		authorizationType := strings.ToLower(fields[0])
 		if authorizationType != authorizationTypeBearer {
-			err := fmt.Errorf("unsupported authorization type: %v", authorizationType)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			respondError(ctx, http.StatusUnauthorized, newCodedError(errCodeUnsupportedAuthorization, authorizationType))
 			return
 		}
 
@@ -46,7 +42,19 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 		accessToken := fields[1]
 		payload, err := tokenMaker.VerifyToken(accessToken)
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			respondError(ctx, http.StatusUnauthorized, err)
+			return
+		}
+
+		// refresh token 只能用于换取 access token，不能访问接口
+		if err := payload.VerifyType(token.TokenTypeAccess); err != nil {
+			respondError(ctx, http.StatusUnauthorized, err)
+			return
+		}
+
+		// 判断 token 是否已被吊销
+		if revocations.isRevoked(ctx, payload) {
+			respondError(ctx, http.StatusUnauthorized, errTokenRevoked)
 			return
 		}
 
@@ -55,3 +63,18 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+// roleMiddleware 只允许指定角色的用户访问，必须在 authMiddleware 之后使用
+func roleMiddleware(roles ...db.UserRole) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		for _, role := range roles {
+			if payload.Role == string(role) {
+				ctx.Next()
+				return
+			}
+		}
+
+		respondError(ctx, http.StatusForbidden, newCodedError(errCodeRoleNotAllowed, payload.Role))
+	}
+}
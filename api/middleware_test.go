@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	db "simplebank/db/sqlc"
 	"simplebank/token"
 	"testing"
 	"time"
@@ -18,10 +19,12 @@ func addAuthorization(
 	tokenMaker token.Maker,
 	authorizationType string,
 	username string,
+	role db.UserRole,
 	duration time.Duration,
 ) {
-	token, err := tokenMaker.CreateToken(username, duration)
+	token, payload, err := tokenMaker.CreateToken(username, string(role), token.TokenTypeAccess, duration)
 	require.NoError(t, err)
+	require.NotEmpty(t, payload)
 
 	authorizationHeader := fmt.Sprintf("%s %s", authorizationType, token)
 	request.Header.Set(authorizationHeaderKey, authorizationHeader)
@@ -36,7 +39,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "OK",
 			setupAuth: func(t *testing.T, request *http.Request,  tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user", time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user", db.UserRoleCustomer, time.Minute)
 			},
 			checkResponse: func(t *testing.T, response *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, response.Code)
@@ -53,7 +56,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "InvalidAuthonizationFormat",
 			setupAuth: func(t *testing.T, request *http.Request,  tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, "", "user", time.Minute)
+				addAuthorization(t, request, tokenMaker, "", "user", db.UserRoleCustomer, time.Minute)
 			},
 			checkResponse: func(t *testing.T, response *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, response.Code)
@@ -62,7 +65,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "NnSupportAuthonizationType",
 			setupAuth: func(t *testing.T, request *http.Request,  tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, "unsupport", "user", time.Minute)
+				addAuthorization(t, request, tokenMaker, "unsupport", "user", db.UserRoleCustomer, time.Minute)
 			},
 			checkResponse: func(t *testing.T, response *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, response.Code)
@@ -71,12 +74,25 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "ExpiredToken",
 			setupAuth: func(t *testing.T, request *http.Request,  tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user", -time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user", db.UserRoleCustomer, -time.Minute)
 			},
 			checkResponse: func(t *testing.T, response *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, response.Code)
 			},
 		},
+		{
+			name: "RefreshToken",
+			setupAuth: func(t *testing.T, request *http.Request,  tokenMaker token.Maker) {
+				// refresh token 不能作为 bearer token 使用
+				refreshToken, _, err := tokenMaker.CreateToken("user", string(db.UserRoleCustomer), token.TokenTypeRefresh, time.Minute)
+				require.NoError(t, err)
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, refreshToken))
+			},
+			checkResponse: func(t *testing.T, response *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, response.Code)
+				requireBodyMatchErrorCode(t, response.Body, errCodeInvalidTokenType)
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -84,7 +100,7 @@ func TestAuthMiddleware(t *testing.T) {
 			server := newTestServer(t, nil)
 
 			authPath := "/auth"
-			server.router.GET(authPath, authMiddleware(server.tokenMaker), func(ctx *gin.Context) {
+			server.router.GET(authPath, authMiddleware(server.tokenMaker, server.revocations), func(ctx *gin.Context) {
 				ctx.Status(http.StatusOK)
 			})
 
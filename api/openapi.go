@@ -0,0 +1,515 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	db "simplebank/db/sqlc"
+	"simplebank/utils"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+const (
+	openAPIVersion = "3.0.3"
+	openAPIPath    = "/openapi.json"
+	// 使用 Bearer token 认证的接口引用的 security scheme
+	bearerAuthScheme = "bearerAuth"
+)
+
+// 数据库枚举类型的取值，反射无法获取类型的常量
+var enumValues = map[reflect.Type][]string{
+	reflect.TypeOf(db.AccountStatus("")): {
+		string(db.AccountStatusActive), string(db.AccountStatusFrozen), string(db.AccountStatusClosed),
+	},
+	reflect.TypeOf(db.AdjustmentReason("")): {
+		string(db.AdjustmentReasonCorrection), string(db.AdjustmentReasonFee), string(db.AdjustmentReasonRefund),
+		string(db.AdjustmentReasonChargeback), string(db.AdjustmentReasonWriteOff),
+	},
+	reflect.TypeOf(db.DeliveryStatus("")): {
+		string(db.DeliveryStatusPending), string(db.DeliveryStatusSucceeded), string(db.DeliveryStatusDead),
+	},
+	reflect.TypeOf(db.EntryType("")): {
+		string(db.EntryTypeTransferDebit), string(db.EntryTypeTransferCredit), string(db.EntryTypeDeposit),
+		string(db.EntryTypeWithdrawal), string(db.EntryTypeFee), string(db.EntryTypeInterest),
+		string(db.EntryTypeAdjustment), string(db.EntryTypeReversal),
+	},
+	reflect.TypeOf(db.ExecutionStatus("")): {
+		string(db.ExecutionStatusSucceeded), string(db.ExecutionStatusFailed),
+	},
+	reflect.TypeOf(db.ReversalStatus("")): {
+		string(db.ReversalStatusNone), string(db.ReversalStatusPartial), string(db.ReversalStatusFull),
+	},
+	reflect.TypeOf(db.ScheduledTransferStatus("")): {
+		string(db.ScheduledTransferStatusActive), string(db.ScheduledTransferStatusPaused),
+		string(db.ScheduledTransferStatusCompleted), string(db.ScheduledTransferStatusCancelled),
+	},
+	reflect.TypeOf(db.UserRole("")): {
+		string(db.UserRoleCustomer), string(db.UserRoleSupport), string(db.UserRoleAdmin), string(db.UserRoleAuditor),
+	},
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*openAPISchema         `json:"schemas"`
+	SecuritySchemes map[string]*openAPISecurityScheme `json:"securitySchemes"`
+}
+
+type openAPISecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type openAPIOperation struct {
+	Summary     string                      `json:"summary"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+	Security    []map[string][]string       `json:"security,omitempty"`
+}
+
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                         `json:"required,omitempty"`
+	Content  map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Description          string                    `json:"description,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+	Minimum              *int64                    `json:"minimum,omitempty"`
+	Maximum              *int64                    `json:"maximum,omitempty"`
+	ExclusiveMinimum     bool                      `json:"exclusiveMinimum,omitempty"`
+	MinLength            *int64                    `json:"minLength,omitempty"`
+	MaxLength            *int64                    `json:"maxLength,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+}
+
+// newOpenAPIDocument 根据已注册的路由和 routeDocs 生成 OpenAPI 文档
+// 没有描述的路由不会出现在文档中，由测试保证所有路由都有描述
+func newOpenAPIDocument(routes gin.RoutesInfo) openAPIDocument {
+	schemas := newSchemaRegistry()
+	document := openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info: openAPIInfo{
+			Title:   "Simple Bank API",
+			Version: "1.0.0",
+		},
+		Paths: map[string]map[string]*openAPIOperation{},
+		Components: openAPIComponents{
+			Schemas: schemas.schemas,
+			SecuritySchemes: map[string]*openAPISecurityScheme{
+				bearerAuthScheme: {Type: "http", Scheme: "bearer", BearerFormat: "PASETO"},
+			},
+		},
+	}
+
+	for _, route := range routes {
+		doc, ok := routeDocs[routeKey(route.Method, route.Path)]
+		if !ok || doc.Hidden {
+			continue
+		}
+
+		path := openAPIPathOf(route.Path)
+		if document.Paths[path] == nil {
+			document.Paths[path] = map[string]*openAPIOperation{}
+		}
+		document.Paths[path][strings.ToLower(route.Method)] = schemas.operationOf(doc)
+	}
+
+	return document
+}
+
+// routeKey 与 routeDocs 的 key 格式相同，例如 "GET /accounts/:id"
+func routeKey(method, path string) string {
+	return method + " " + path
+}
+
+// openAPIPathOf 将 gin 的路径参数 :id 转换为 OpenAPI 的 {id}
+func openAPIPathOf(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+type schemaRegistry struct {
+	schemas map[string]*openAPISchema
+	names   map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{
+		schemas: map[string]*openAPISchema{},
+		names:   map[reflect.Type]string{},
+	}
+}
+
+func (registry *schemaRegistry) operationOf(doc routeDoc) *openAPIOperation {
+	operation := &openAPIOperation{
+		Summary:     doc.Summary,
+		Description: doc.Description,
+		Responses:   map[string]*openAPIResponse{},
+	}
+	if len(doc.Tag) > 0 {
+		operation.Tags = []string{doc.Tag}
+	}
+
+	if doc.URI != nil {
+		operation.Parameters = append(operation.Parameters, registry.parametersOf(reflect.TypeOf(doc.URI), "path", "uri")...)
+	}
+	if doc.Query != nil {
+		operation.Parameters = append(operation.Parameters, registry.parametersOf(reflect.TypeOf(doc.Query), "query", "form")...)
+	}
+	if doc.Idempotent {
+		operation.Parameters = append(operation.Parameters, &openAPIParameter{
+			Name:        idempotencyKeyHeaderKey,
+			In:          "header",
+			Description: "Replays the saved response when the same request is retried with the same key.",
+			Schema:      &openAPISchema{Type: "string", MaxLength: int64Ptr(maxIdempotencyKeyLength)},
+		})
+	}
+
+	if doc.Body != nil {
+		operation.RequestBody = &openAPIRequestBody{
+			Required: !doc.OptionalBody,
+			Content: map[string]*openAPIMediaType{
+				gin.MIMEJSON: {Schema: registry.schemaOf(reflect.TypeOf(doc.Body))},
+			},
+		}
+	}
+
+	operation.Responses[strconv.Itoa(doc.status())] = registry.responseOf(doc)
+
+	statuses := append([]int{}, doc.Errors...)
+	if !doc.Public {
+		operation.Security = []map[string][]string{{bearerAuthScheme: {}}}
+		statuses = append(statuses, http.StatusUnauthorized)
+	}
+	if len(doc.Roles) > 0 {
+		roles := make([]string, len(doc.Roles))
+		for i, role := range doc.Roles {
+			roles[i] = string(role)
+		}
+		operation.Description = strings.TrimSpace(operation.Description + "\n\nRequired role: " + strings.Join(roles, ", ") + ".")
+		statuses = append(statuses, http.StatusForbidden)
+	}
+	if doc.Idempotent {
+		statuses = append(statuses, http.StatusConflict)
+	}
+	for _, code := range statuses {
+		operation.Responses[strconv.Itoa(code)] = registry.errorResponseOf(code)
+	}
+
+	return operation
+}
+
+func (registry *schemaRegistry) responseOf(doc routeDoc) *openAPIResponse {
+	response := &openAPIResponse{Description: http.StatusText(doc.status())}
+	if doc.Response == nil {
+		return response
+	}
+
+	contentType := doc.ContentType
+	if len(contentType) == 0 {
+		contentType = gin.MIMEJSON
+	}
+	response.Content = map[string]*openAPIMediaType{
+		contentType: {Schema: registry.schemaOf(reflect.TypeOf(doc.Response))},
+	}
+	return response
+}
+
+// errorResponseOf 所有错误响应的格式相同，code 的取值为全部错误码
+func (registry *schemaRegistry) errorResponseOf(code int) *openAPIResponse {
+	schema := registry.schemaOf(reflect.TypeOf(apiError{}))
+	registry.schemas[registry.register(reflect.TypeOf(apiError{}))].Properties["code"].Enum = errorCodes()
+
+	return &openAPIResponse{
+		Description: http.StatusText(code),
+		Content: map[string]*openAPIMediaType{
+			gin.MIMEJSON: {Schema: schema},
+		},
+	}
+}
+
+// errorCodes 返回 messages 中定义的全部错误码
+func errorCodes() []string {
+	var codes []string
+	for key := range messages[languageEnglish] {
+		if !strings.HasPrefix(key, fieldMessageKey("")) {
+			codes = append(codes, key)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// parametersOf 将绑定 uri 或 query 的结构体字段转换为参数
+func (registry *schemaRegistry) parametersOf(t reflect.Type, in string, tagKey string) []*openAPIParameter {
+	var parameters []*openAPIParameter
+	for _, field := range structFields(t) {
+		name := fieldName(field, tagKey)
+		if len(name) == 0 {
+			continue
+		}
+
+		schema := registry.schemaOf(field.Type)
+		required := applyBinding(schema, field.Tag.Get("binding"))
+		parameters = append(parameters, &openAPIParameter{
+			Name: name,
+			In:   in,
+			// 路径参数总是必填
+			Required: required || in == "path",
+			Schema:   schema,
+		})
+	}
+	return parameters
+}
+
+// schemaOf 返回类型对应的 schema，结构体注册到 components 中并返回引用
+func (registry *schemaRegistry) schemaOf(t reflect.Type) *openAPISchema {
+	if t.Kind() == reflect.Pointer {
+		schema := registry.schemaOf(t.Elem())
+		if len(schema.Ref) == 0 {
+			schema.Nullable = true
+		}
+		return schema
+	}
+
+	if values, ok := enumValues[t]; ok {
+		return &openAPISchema{Type: "string", Enum: values}
+	}
+
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	case reflect.TypeOf(uuid.UUID{}):
+		return &openAPISchema{Type: "string", Format: "uuid"}
+	case reflect.TypeOf(uuid.NullUUID{}):
+		return &openAPISchema{Type: "string", Format: "uuid", Nullable: true}
+	case reflect.TypeOf(json.RawMessage{}):
+		return &openAPISchema{Description: "Arbitrary JSON value."}
+	case reflect.TypeOf([]byte{}):
+		return &openAPISchema{Type: "string", Format: "byte"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &openAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &openAPISchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &openAPISchema{Type: "array", Items: registry.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: registry.schemaOf(t.Elem())}
+	case reflect.Struct:
+		return &openAPISchema{Ref: "#/components/schemas/" + registry.register(t)}
+	}
+
+	return &openAPISchema{}
+}
+
+// register 将结构体注册到 components 中，其他包的类型带上包名，例如 db.Account
+func (registry *schemaRegistry) register(t reflect.Type) string {
+	if name, ok := registry.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if t.PkgPath() != reflect.TypeOf(Server{}).PkgPath() {
+		name = t.String()
+	}
+	// 先占位，避免结构体引用自身时无限递归
+	registry.names[t] = name
+
+	schema := &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+	registry.schemas[name] = schema
+	for _, field := range structFields(t) {
+		name := fieldName(field, "json")
+		if len(name) == 0 {
+			continue
+		}
+
+		property := registry.schemaOf(field.Type)
+		if applyBinding(property, field.Tag.Get("binding")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+	sort.Strings(schema.Required)
+
+	return registry.names[t]
+}
+
+// structFields 返回结构体的导出字段，匿名嵌入的结构体字段展开到外层
+func structFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && len(field.Tag.Get("json")) == 0 {
+			fields = append(fields, structFields(field.Type)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// fieldName 按照 tag 获取字段名，没有 tag 时与 encoding/json 一样使用字段名
+func fieldName(field reflect.StructField, tagKey string) string {
+	tag := field.Tag.Get(tagKey)
+	if tag == "-" {
+		return ""
+	}
+
+	name, _, _ := strings.Cut(tag, ",")
+	if len(name) == 0 && tagKey == "json" {
+		name = field.Name
+	}
+	return name
+}
+
+// applyBinding 将 binding tag 中的校验规则转换为 schema 的约束，返回字段是否必填
+func applyBinding(schema *openAPISchema, binding string) (required bool) {
+	if len(binding) == 0 {
+		return false
+	}
+
+	isString := schema.Type == "string"
+	for _, rule := range strings.Split(binding, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "min", "max", "gt":
+			n, err := strconv.ParseInt(param, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch {
+			case isString && name == "min":
+				schema.MinLength = int64Ptr(n)
+			case isString && name == "max":
+				schema.MaxLength = int64Ptr(n)
+			case name == "min":
+				schema.Minimum = int64Ptr(n)
+			case name == "max":
+				schema.Maximum = int64Ptr(n)
+			default:
+				schema.Minimum = int64Ptr(n)
+				schema.ExclusiveMinimum = true
+			}
+		case "oneof":
+			schema.Enum = strings.Fields(param)
+		case "email":
+			schema.Format = "email"
+		case "uuid":
+			schema.Format = "uuid"
+		case "webhook_url":
+			schema.Format = "uri"
+			schema.Description = "A public http or https URL, https is required in production."
+		case "currency":
+			schema.Enum = []string{utils.USD, utils.RMB, utils.EUR}
+		case "cron":
+			schema.Description = "Standard five-field cron expression."
+		case "nefield":
+			schema.Description = fmt.Sprintf("Must be different from %s.", param)
+		}
+	}
+	return required
+}
+
+func int64Ptr(n int64) *int64 {
+	return &n
+}
+
+// getOpenAPISpec 返回启动时生成的 OpenAPI 文档
+func (server *Server) getOpenAPISpec(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, server.openAPISpec)
+}
+
+// swagger-initializer.js 默认加载示例文档，替换为本服务的文档
+var swaggerInitializer = []byte(`window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: "` + openAPIPath + `",
+    dom_id: '#swagger-ui',
+    deepLinking: true,
+    presets: [
+      SwaggerUIBundle.presets.apis,
+      SwaggerUIStandalonePreset
+    ],
+    plugins: [
+      SwaggerUIBundle.plugins.DownloadUrl
+    ],
+    layout: "StandaloneLayout"
+  });
+};
+`)
+
+// swaggerUI 提供内嵌的 Swagger UI 静态文件
+func (server *Server) swaggerUI(ctx *gin.Context) {
+	filepath := ctx.Param("filepath")
+	if filepath == "/swagger-initializer.js" {
+		ctx.Data(http.StatusOK, "application/javascript; charset=utf-8", swaggerInitializer)
+		return
+	}
+
+	ctx.FileFromFS(filepath, http.FS(swaggerFiles.FS))
+}
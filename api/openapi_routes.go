@@ -0,0 +1,398 @@
+package api
+
+import (
+	"net/http"
+	db "simplebank/db/sqlc"
+	"simplebank/reconcile"
+)
+
+// routeDoc 描述一个路由的请求和响应，用于生成 OpenAPI 文档
+// URI、Query、Body、Response 为对应结构体的零值，通过反射生成 schema
+type routeDoc struct {
+	Summary     string
+	Description string
+	Tag         string
+	URI         interface{}
+	Query       interface{}
+	Body        interface{}
+	// 请求体可以为空
+	OptionalBody bool
+	// 为 nil 时成功返回 204，没有响应体
+	Response interface{}
+	// 响应的 Content-Type，默认为 application/json
+	ContentType string
+	// 处理函数返回的错误状态码，401、403、409 根据 Public、Roles、Idempotent 自动添加
+	Errors []int
+	// 不需要认证的接口
+	Public bool
+	// 允许访问的角色，为空时不限制
+	Roles []db.UserRole
+	// 支持 Idempotency-Key 请求头
+	Idempotent bool
+	// 不出现在文档中，例如 Swagger UI 的静态文件
+	Hidden bool
+}
+
+func (doc routeDoc) status() int {
+	if doc.Response == nil {
+		return http.StatusNoContent
+	}
+	return http.StatusOK
+}
+
+var (
+	staffRoles    = []db.UserRole{db.UserRoleSupport, db.UserRoleAdmin, db.UserRoleAuditor}
+	operatorRoles = []db.UserRole{db.UserRoleSupport, db.UserRoleAdmin}
+	adminRoles    = []db.UserRole{db.UserRoleAdmin}
+	auditorRoles  = []db.UserRole{db.UserRoleAdmin, db.UserRoleAuditor}
+)
+
+// routeDocs 以 "METHOD /path" 为 key，setupRouter 中注册的每个路由都必须在这里描述
+var routeDocs = map[string]routeDoc{
+	"GET /openapi.json": {
+		Summary:  "OpenAPI specification of this API",
+		Tag:      "docs",
+		Response: map[string]interface{}{},
+		Public:   true,
+	},
+	"GET /healthz": {
+		Summary:     "Liveness probe",
+		Description: "Returns 200 while the process is running, without checking dependencies.",
+		Tag:         "health",
+		Response:    healthResponse{},
+		Public:      true,
+	},
+	"GET /readyz": {
+		Summary:     "Readiness probe",
+		Description: "Checks the database connection and that migrations are up to date. Returns 503 while the server is shutting down.",
+		Tag:         "health",
+		Response:    readinessResponse{},
+		Errors:      []int{http.StatusServiceUnavailable},
+		Public:      true,
+	},
+	"GET /swagger/*filepath": {
+		Summary: "Swagger UI",
+		Public:  true,
+		Hidden:  true,
+	},
+	"GET /metrics": {
+		Summary: "Prometheus metrics, only served here when METRICS_ADDRESS is empty",
+		Public:  true,
+		Hidden:  true,
+	},
+
+	"POST /users": {
+		Summary:  "Create a user",
+		Tag:      "users",
+		Body:     createUserRequest{},
+		Response: userResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError},
+		Public:   true,
+	},
+	"POST /users/login": {
+		Summary:  "Log in and create a session",
+		Tag:      "users",
+		Body:     loginUserRequest{},
+		Response: loginUserResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError},
+		Public:   true,
+	},
+	"POST /users/logout": {
+		Summary:      "Revoke the access token, and the session of the refresh token if given",
+		Tag:          "users",
+		Body:         logoutUserRequest{},
+		OptionalBody: true,
+		Errors:       []int{http.StatusBadRequest, http.StatusInternalServerError},
+	},
+	"POST /users/logout_all": {
+		Summary: "Revoke all tokens and sessions of the user",
+		Tag:     "users",
+		Errors:  []int{http.StatusNotFound, http.StatusInternalServerError},
+	},
+	"POST /tokens/renew_access": {
+		Summary:  "Renew the access token with a refresh token",
+		Tag:      "users",
+		Body:     renewAccessTokenRequest{},
+		Response: renewAccessTokenResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError},
+		Public:   true,
+	},
+	"GET /.well-known/keys": {
+		Summary:     "Public keys for verifying tokens",
+		Description: "Returns 404 when tokens are encrypted with a symmetric key.",
+		Tag:         "users",
+		Response:    listPublicKeysResponse{},
+		Errors:      []int{http.StatusNotFound},
+		Public:      true,
+	},
+	"POST /sessions/:id/block": {
+		Summary:  "Block a session so that its refresh token can no longer be used",
+		Tag:      "users",
+		URI:      sessionURIRequest{},
+		Response: sessionResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+
+	"POST /accounts": {
+		Summary:    "Create an account",
+		Tag:        "accounts",
+		Body:       createAccountRequest{},
+		Response:   db.Account{},
+		Errors:     []int{http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError},
+		Idempotent: true,
+	},
+	"GET /accounts/:id": {
+		Summary:  "Get an account",
+		Tag:      "accounts",
+		URI:      getAccountRequest{},
+		Response: db.Account{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	"GET /accounts": {
+		Summary:  "List accounts of the user",
+		Tag:      "accounts",
+		Query:    listAccountRequest{},
+		Response: []db.Account{},
+		Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+	},
+	"DELETE /accounts/:id": {
+		Summary:  "Close an account with zero balance",
+		Tag:      "accounts",
+		URI:      accountURIRequest{},
+		Response: db.Account{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+	},
+	"GET /accounts/:id/entries": {
+		Summary:  "List entries of an account",
+		Tag:      "accounts",
+		URI:      accountURIRequest{},
+		Query:    listEntriesRequest{},
+		Response: listEntriesResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	"GET /accounts/:id/transfers": {
+		Summary:  "List transfers of an account",
+		Tag:      "accounts",
+		URI:      accountURIRequest{},
+		Query:    listHistoryRequest{},
+		Response: listTransfersResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	"GET /accounts/:id/stream": {
+		Summary: "Stream new entries and balance changes of an account",
+		Description: "Server-sent events. `entry` events carry an entry and its id; `balance` events carry the account balance. " +
+			"Reconnect with the `Last-Event-ID` header to receive the entries missed in between.",
+		Tag:         "accounts",
+		URI:         accountURIRequest{},
+		Response:    "",
+		ContentType: "text/event-stream",
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	"POST /accounts/:id/adjustments": {
+		Summary:    "Adjust the balance of an account",
+		Tag:        "accounts",
+		URI:        accountURIRequest{},
+		Body:       createAdjustmentRequest{},
+		Response:   db.AdjustAccountTxResult{},
+		Errors:     []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+		Roles:      adminRoles,
+		Idempotent: true,
+	},
+
+	"POST /transfer": {
+		Summary:     "Transfer money between two accounts",
+		Description: "Set `quote_id` to transfer between accounts of different currencies.",
+		Tag:         "transfers",
+		Body:        TransferRequest{},
+		Response:    db.TransferTxResult{},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+		Idempotent:  true,
+	},
+	"POST /transfers/:id/reverse": {
+		Summary:      "Reverse a transfer fully or partially",
+		Description:  "Only the recipient or an admin can reverse a transfer.",
+		Tag:          "transfers",
+		URI:          transferURIRequest{},
+		Body:         reverseTransferRequest{},
+		OptionalBody: true,
+		Response:     db.ReverseTransferTxResult{},
+		Errors:       []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+		Idempotent:   true,
+	},
+	"POST /fx/quotes": {
+		Summary:  "Lock an exchange rate for a cross-currency transfer",
+		Tag:      "transfers",
+		Body:     createFxQuoteRequest{},
+		Response: fxQuoteResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+
+	"POST /scheduled_transfers": {
+		Summary:    "Schedule a one-off or recurring transfer",
+		Tag:        "scheduled transfers",
+		Body:       createScheduledTransferRequest{},
+		Response:   db.ScheduledTransfer{},
+		Errors:     []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		Idempotent: true,
+	},
+	"GET /scheduled_transfers": {
+		Summary:  "List scheduled transfers of the user",
+		Tag:      "scheduled transfers",
+		Query:    listAccountRequest{},
+		Response: []db.ScheduledTransfer{},
+		Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+	},
+	"GET /scheduled_transfers/:id": {
+		Summary:  "Get a scheduled transfer",
+		Tag:      "scheduled transfers",
+		URI:      scheduledTransferURIRequest{},
+		Response: db.ScheduledTransfer{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	"PATCH /scheduled_transfers/:id": {
+		Summary:  "Update, pause or resume a scheduled transfer",
+		Tag:      "scheduled transfers",
+		URI:      scheduledTransferURIRequest{},
+		Body:     updateScheduledTransferRequest{},
+		Response: db.ScheduledTransfer{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+	},
+	"DELETE /scheduled_transfers/:id": {
+		Summary:  "Cancel a scheduled transfer",
+		Tag:      "scheduled transfers",
+		URI:      scheduledTransferURIRequest{},
+		Response: db.ScheduledTransfer{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+	},
+	"GET /scheduled_transfers/:id/executions": {
+		Summary:  "List executions of a scheduled transfer",
+		Tag:      "scheduled transfers",
+		URI:      scheduledTransferURIRequest{},
+		Query:    listAccountRequest{},
+		Response: []db.ScheduledTransferExecution{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+
+	"POST /webhooks": {
+		Summary:     "Register a webhook",
+		Description: "The secret for verifying the `X-Signature` header is only returned once.",
+		Tag:         "webhooks",
+		Body:        createWebhookRequest{},
+		Response:    createWebhookResponse{},
+		Errors:      []int{http.StatusBadRequest, http.StatusInternalServerError},
+	},
+	"GET /webhooks": {
+		Summary:  "List webhooks of the user",
+		Tag:      "webhooks",
+		Query:    listAccountRequest{},
+		Response: []webhookResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+	},
+	"DELETE /webhooks/:id": {
+		Summary: "Delete a webhook",
+		Tag:     "webhooks",
+		URI:     webhookURIRequest{},
+		Errors:  []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	"GET /webhooks/:id/deliveries": {
+		Summary:  "List deliveries of a webhook",
+		Tag:      "webhooks",
+		URI:      webhookURIRequest{},
+		Query:    listWebhookDeliveriesRequest{},
+		Response: []db.WebhookDelivery{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+
+	"GET /admin/users/:username": {
+		Summary:  "Get a user",
+		Tag:      "admin",
+		URI:      adminUserURIRequest{},
+		Response: userResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		Roles:    staffRoles,
+	},
+	"GET /admin/users/:username/accounts": {
+		Summary:  "List accounts of a user",
+		Tag:      "admin",
+		URI:      adminUserURIRequest{},
+		Query:    listAccountRequest{},
+		Response: []db.Account{},
+		Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+		Roles:    staffRoles,
+	},
+	"PUT /admin/users/:username/role": {
+		Summary:  "Change the role of a user",
+		Tag:      "admin",
+		URI:      adminUserURIRequest{},
+		Body:     updateUserRoleRequest{},
+		Response: userResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		Roles:    adminRoles,
+	},
+	"POST /admin/accounts/:id/freeze": {
+		Summary:  "Freeze an account",
+		Tag:      "admin",
+		URI:      accountURIRequest{},
+		Response: db.Account{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+		Roles:    operatorRoles,
+	},
+	"POST /admin/accounts/:id/unfreeze": {
+		Summary:  "Unfreeze an account",
+		Tag:      "admin",
+		URI:      accountURIRequest{},
+		Response: db.Account{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+		Roles:    operatorRoles,
+	},
+	"POST /admin/accounts/:id/close": {
+		Summary:  "Close an account with zero balance",
+		Tag:      "admin",
+		URI:      accountURIRequest{},
+		Response: db.Account{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+		Roles:    adminRoles,
+	},
+	"PATCH /admin/accounts/:id/overdraft_limit": {
+		Summary:  "Set the overdraft limit of an account",
+		Tag:      "admin",
+		URI:      accountURIRequest{},
+		Body:     updateOverdraftLimitRequest{},
+		Response: db.Account{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		Roles:    adminRoles,
+	},
+	"GET /admin/accounts/:id/transfers": {
+		Summary:  "List transfers of any account",
+		Tag:      "admin",
+		URI:      accountURIRequest{},
+		Query:    listHistoryRequest{},
+		Response: listTransfersResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		Roles:    staffRoles,
+	},
+	"GET /admin/accounts/:id/adjustments": {
+		Summary:  "List adjustments of an account",
+		Tag:      "admin",
+		URI:      accountURIRequest{},
+		Query:    listAccountRequest{},
+		Response: []db.AccountAdjustment{},
+		Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+		Roles:    staffRoles,
+	},
+	"GET /admin/audit_logs": {
+		Summary:  "List audit logs, newest first",
+		Tag:      "admin",
+		Query:    listAuditLogsRequest{},
+		Response: []db.AuditLog{},
+		Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+		Roles:    auditorRoles,
+	},
+	"GET /admin/reconciliation": {
+		Summary:  "Result of the latest scheduled reconciliation",
+		Tag:      "admin",
+		Response: reconcile.Report{},
+		Errors:   []int{http.StatusNotFound},
+		Roles:    auditorRoles,
+	},
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	mockdb "simplebank/db/mock"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// 新增路由时必须在 routeDocs 中描述，否则文档会缺少该接口
+func TestRouteDocs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+	registered := map[string]bool{}
+	for _, route := range server.router.Routes() {
+		key := routeKey(route.Method, route.Path)
+		registered[key] = true
+
+		doc, ok := routeDocs[key]
+		require.Truef(t, ok, "route %s is not described in routeDocs", key)
+		if doc.Hidden {
+			continue
+		}
+
+		// 路径参数都要有描述
+		operation := server.openAPISpec.Paths[openAPIPathOf(route.Path)][strings.ToLower(route.Method)]
+		require.NotNil(t, operation, key)
+		for _, match := range regexp.MustCompile(`:(\w+)`).FindAllStringSubmatch(route.Path, -1) {
+			found := false
+			for _, parameter := range operation.Parameters {
+				if parameter.In == "path" && parameter.Name == match[1] {
+					found = true
+				}
+			}
+			require.Truef(t, found, "path parameter %s of route %s is not described", match[1], key)
+		}
+	}
+
+	for key := range routeDocs {
+		require.Truef(t, registered[key], "route %s is described but not registered", key)
+	}
+}
+
+func TestGetOpenAPISpecAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/openapi.json", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var spec openAPIDocument
+	err = json.Unmarshal(recorder.Body.Bytes(), &spec)
+	require.NoError(t, err)
+	require.Equal(t, openAPIVersion, spec.OpenAPI)
+
+	// 请求体引用 components 中的 schema
+	transfer := spec.Paths["/transfer"]["post"]
+	require.NotNil(t, transfer)
+	require.Equal(t, "#/components/schemas/TransferRequest", transfer.RequestBody.Content["application/json"].Schema.Ref)
+	require.Equal(t, "#/components/schemas/db.TransferTxResult", transfer.Responses["200"].Content["application/json"].Schema.Ref)
+	require.Equal(t, "#/components/schemas/apiError", transfer.Responses["422"].Content["application/json"].Schema.Ref)
+	require.Equal(t, "#/components/schemas/apiError", transfer.Responses["400"].Content["application/json"].Schema.Ref)
+	require.Equal(t, []map[string][]string{{bearerAuthScheme: {}}}, transfer.Security)
+
+	transferRequest := spec.Components.Schemas["TransferRequest"]
+	require.NotNil(t, transferRequest)
+	require.ElementsMatch(t, []string{"from_account_id", "to_account_id", "amount", "currency"}, transferRequest.Required)
+	require.Equal(t, []string{"USD", "RMB", "EUR"}, transferRequest.Properties["currency"].Enum)
+	require.Equal(t, int64(0), *transferRequest.Properties["amount"].Minimum)
+	require.True(t, transferRequest.Properties["amount"].ExclusiveMinimum)
+	require.Equal(t, "uuid", transferRequest.Properties["quote_id"].Format)
+	require.Equal(t, int64(255), *transferRequest.Properties["memo"].MaxLength)
+
+	// 公开接口不需要认证
+	createUser := spec.Paths["/users"]["post"]
+	require.NotNil(t, createUser)
+	require.Empty(t, createUser.Security)
+	require.NotContains(t, createUser.Responses, "401")
+
+	createUserRequest := spec.Components.Schemas["createUserRequest"]
+	require.NotNil(t, createUserRequest)
+	require.Equal(t, "email", createUserRequest.Properties["email"].Format)
+	require.Equal(t, int64(6), *createUserRequest.Properties["password"].MinLength)
+
+	// 路径参数和查询参数
+	entries := spec.Paths["/accounts/{id}/entries"]["get"]
+	require.NotNil(t, entries)
+	parameters := map[string]*openAPIParameter{}
+	for _, parameter := range entries.Parameters {
+		parameters[parameter.Name] = parameter
+	}
+	require.Equal(t, "path", parameters["id"].In)
+	require.True(t, parameters["id"].Required)
+	require.Equal(t, "query", parameters["page_size"].In)
+	require.True(t, parameters["page_size"].Required)
+	require.Equal(t, "date-time", parameters["start_time"].Schema.Format)
+	require.Contains(t, parameters["entry_type"].Schema.Enum, "reversal")
+
+	// 需要角色的接口
+	freeze := spec.Paths["/admin/accounts/{id}/freeze"]["post"]
+	require.NotNil(t, freeze)
+	require.Contains(t, freeze.Responses, "403")
+
+	// 支持幂等的接口
+	createAccount := spec.Paths["/accounts"]["post"]
+	require.NotNil(t, createAccount)
+	require.Contains(t, createAccount.Responses, "409")
+	require.Equal(t, idempotencyKeyHeaderKey, createAccount.Parameters[0].Name)
+
+	require.Contains(t, spec.Components.Schemas["apiError"].Properties["code"].Enum, errCodeInsufficientFunds)
+	require.NotContains(t, spec.Components.Schemas["apiError"].Properties["code"].Enum, fieldMessageKey("required"))
+	require.Contains(t, spec.Components.Schemas, "errorDetail")
+	require.Equal(t, "date-time", spec.Components.Schemas["db.Account"].Properties["created_at"].Format)
+	require.Equal(t, []string{"active", "frozen", "closed"}, spec.Components.Schemas["db.Account"].Properties["status"].Enum)
+}
+
+func TestSwaggerUI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/swagger/", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "swagger-ui")
+
+	// Swagger UI 加载本服务的文档
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodGet, "/swagger/swagger-initializer.js", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `url: "/openapi.json"`)
+
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodGet, "/swagger/swagger-ui.css", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
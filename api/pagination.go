@@ -0,0 +1,103 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 历史记录的查询条件，账户流水与转账记录共用
+type listHistoryRequest struct {
+	PageSize  int32     `form:"page_size" binding:"required,min=5,max=100"`
+	Cursor    string    `form:"cursor"`
+	StartTime time.Time `form:"start_time" time_format:"2006-01-02T15:04:05Z07:00"`
+	EndTime   time.Time `form:"end_time" time_format:"2006-01-02T15:04:05Z07:00"`
+	MinAmount *int64    `form:"min_amount" binding:"omitempty,min=0"`
+	MaxAmount *int64    `form:"max_amount" binding:"omitempty,min=0"`
+	Direction string    `form:"direction" binding:"omitempty,oneof=incoming outgoing"`
+}
+
+// 游标指向上一页最后一条记录，下一页从 (created_at, id) 更小的记录开始
+type historyCursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+// 解析后的查询条件，可直接用于 sqlc 的查询参数
+type historyFilter struct {
+	StartTime       sql.NullTime
+	EndTime         sql.NullTime
+	MinAmount       sql.NullInt64
+	MaxAmount       sql.NullInt64
+	Direction       sql.NullString
+	CursorCreatedAt sql.NullTime
+	CursorID        sql.NullInt64
+	// 多查询一条记录，用于判断是否还有下一页
+	Limit int32
+}
+
+func (req listHistoryRequest) filter() (historyFilter, error) {
+	if !req.StartTime.IsZero() && !req.EndTime.IsZero() && !req.EndTime.After(req.StartTime) {
+		return historyFilter{}, errInvalidTimeRange
+	}
+
+	if req.MinAmount != nil && req.MaxAmount != nil && *req.MinAmount > *req.MaxAmount {
+		return historyFilter{}, errInvalidAmountRange
+	}
+
+	filter := historyFilter{
+		StartTime: sql.NullTime{Time: req.StartTime, Valid: !req.StartTime.IsZero()},
+		EndTime:   sql.NullTime{Time: req.EndTime, Valid: !req.EndTime.IsZero()},
+		Direction: sql.NullString{String: req.Direction, Valid: len(req.Direction) > 0},
+		Limit:     req.PageSize + 1,
+	}
+	if req.MinAmount != nil {
+		filter.MinAmount = sql.NullInt64{Int64: *req.MinAmount, Valid: true}
+	}
+	if req.MaxAmount != nil {
+		filter.MaxAmount = sql.NullInt64{Int64: *req.MaxAmount, Valid: true}
+	}
+
+	if len(req.Cursor) > 0 {
+		cursor, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return historyFilter{}, err
+		}
+		filter.CursorCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		filter.CursorID = sql.NullInt64{Int64: cursor.ID, Valid: true}
+	}
+
+	return filter, nil
+}
+
+func encodeCursor(cursor historyCursor) string {
+	raw := fmt.Sprintf("%s,%d", cursor.CreatedAt.UTC().Format(time.RFC3339Nano), cursor.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (historyCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return historyCursor{}, errInvalidCursor
+	}
+
+	fields := strings.Split(string(raw), ",")
+	if len(fields) != 2 {
+		return historyCursor{}, errInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		return historyCursor{}, errInvalidCursor
+	}
+
+	id, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return historyCursor{}, errInvalidCursor
+	}
+
+	return historyCursor{CreatedAt: createdAt, ID: id}, nil
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"simplebank/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	requestIDHeaderKey = "X-Request-ID"
+	requestIDKey       = logger.RequestIDKey
+	// 客户端传入的 request ID 过长时重新生成
+	maxRequestIDLength = 128
+)
+
+// requestIDMiddleware 使用客户端传入的 X-Request-ID，没有时生成一个，并在响应头中返回
+// request ID 同时写入请求的 context，store 中的日志可以与访问日志关联
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(requestIDHeaderKey)
+		if len(requestID) == 0 || len(requestID) > maxRequestIDLength {
+			requestID = uuid.NewString()
+		}
+
+		ctx.Set(requestIDKey, requestID)
+		ctx.Request = ctx.Request.WithContext(logger.WithRequestID(ctx.Request.Context(), requestID))
+		ctx.Header(requestIDHeaderKey, requestID)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	db "simplebank/db/sqlc"
+	"simplebank/token"
+
+	"github.com/gin-gonic/gin"
+)
+
+type transferURIRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type reverseTransferRequest struct {
+	// 冲正金额，使用转入账户的币种，不填时冲正剩余的全部金额
+	Amount int64  `json:"amount" binding:"omitempty,gt=0"`
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+// reverseTransfer 全额或部分冲正一笔转账，只有收款人或管理员可以操作
+func (server *Server) reverseTransfer(ctx *gin.Context) {
+	var uri transferURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	// 请求体可以为空，表示全额冲正
+	var req reverseTransferRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil && err != io.EOF {
+			respondError(ctx, http.StatusBadRequest, err)
+			return
+		}
+	}
+	ctx.Set(auditDetailsKey, req)
+
+	transfer, err := server.store.GetTransfer(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(ctx, http.StatusNotFound, err)
+			return
+		}
+
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if db.UserRole(payload.Role) != db.UserRoleAdmin {
+		toAccount, err := server.store.GetAccount(ctx, transfer.ToAccountID)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+		if toAccount.Owner != payload.Username {
+			respondError(ctx, http.StatusForbidden, errReversalNotAllowed)
+			return
+		}
+	}
+
+	result, err := server.store.ReverseTransferTx(ctx, db.ReverseTransferTxParams{
+		TransferID: transfer.ID,
+		ToAmount:   req.Amount,
+		Reason:     req.Reason,
+		Actor:      payload.Username,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			respondError(ctx, http.StatusNotFound, err)
+		case errors.Is(err, db.ErrInvalidReversal),
+			errors.Is(err, db.ErrInsufficientFunds),
+			errors.Is(err, db.ErrAccountNotActive):
+			respondError(ctx, http.StatusUnprocessableEntity, err)
+		default:
+			respondError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,398 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	mockdb "simplebank/db/mock"
+	db "simplebank/db/sqlc"
+	"simplebank/token"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReverseTransferAPI(t *testing.T) {
+	sender, _ := randomUser(t)
+	recipient, _ := randomUser(t)
+	admin, _ := randomUser(t)
+	admin.Role = db.UserRoleAdmin
+
+	fromAccount := randomAccount(sender.Username)
+	toAccount := randomAccount(recipient.Username)
+	toAccount.Currency = fromAccount.Currency
+
+	transfer := db.Transfer{
+		ID:             1,
+		FromAccountID:  fromAccount.ID,
+		ToAccountID:    toAccount.ID,
+		Amount:         100,
+		ToAmount:       100,
+		ReversalStatus: db.ReversalStatusNone,
+	}
+
+	amount := int64(40)
+	result := db.ReverseTransferTxResult{
+		Reversal: db.TransferReversal{
+			ID:         1,
+			TransferID: transfer.ID,
+			Amount:     amount,
+			ToAmount:   amount,
+			Reason:     "refund",
+			Actor:      recipient.Username,
+		},
+		Transfer:    transfer,
+		FromAccount: fromAccount,
+		ToAccount:   toAccount,
+	}
+	result.Transfer.ReversalStatus = db.ReversalStatusPartial
+	result.Transfer.ReversedAmount = amount
+	result.Transfer.ReversedToAmount = amount
+
+	action := "POST /transfers/:id/reverse"
+
+	testCases := []struct {
+		name          string
+		transferID    int64
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "OK",
+			transferID: transfer.ID,
+			body: gin.H{
+				"amount": amount,
+				"reason": "refund",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, recipient.Username, recipient.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).
+					Return(transfer, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
+					Times(1).
+					Return(toAccount, nil)
+
+				arg := db.ReverseTransferTxParams{
+					TransferID: transfer.ID,
+					ToAmount:   amount,
+					Reason:     "refund",
+					Actor:      recipient.Username,
+				}
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(result, nil)
+				expectAuditLog(t, store, recipient.Username, recipient.Role, action, http.StatusOK)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var gotResult db.ReverseTransferTxResult
+				err := json.Unmarshal(recorder.Body.Bytes(), &gotResult)
+				require.NoError(t, err)
+				require.Equal(t, result, gotResult)
+			},
+		},
+		{
+			name:       "FullReversalWithoutBody",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, recipient.Username, recipient.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).
+					Return(transfer, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
+					Times(1).
+					Return(toAccount, nil)
+
+				// 金额为 0 表示冲正剩余的全部金额
+				arg := db.ReverseTransferTxParams{
+					TransferID: transfer.ID,
+					Actor:      recipient.Username,
+				}
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(result, nil)
+				expectAuditLog(t, store, recipient.Username, recipient.Role, action, http.StatusOK)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:       "AdminOK",
+			transferID: transfer.ID,
+			body: gin.H{
+				"reason": "chargeback",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).
+					Return(transfer, nil)
+				// 管理员不需要检查收款账户
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				arg := db.ReverseTransferTxParams{
+					TransferID: transfer.ID,
+					Reason:     "chargeback",
+					Actor:      admin.Username,
+				}
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(result, nil)
+				expectAuditLog(t, store, admin.Username, admin.Role, action, http.StatusOK)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:       "SenderForbidden",
+			transferID: transfer.ID,
+			body: gin.H{
+				"amount": amount,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, sender.Username, sender.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).
+					Return(transfer, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
+					Times(1).
+					Return(toAccount, nil)
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAuditLog(t, store, sender.Username, sender.Role, action, http.StatusForbidden)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireBodyMatchErrorCode(t, recorder.Body, errCodeReversalNotAllowed)
+			},
+		},
+		{
+			name:       "NoAuthorization",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateAuditLog(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:       "InvalidID",
+			transferID: 0,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, recipient.Username, recipient.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAuditLog(t, store, recipient.Username, recipient.Role, action, http.StatusBadRequest)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:       "NegativeAmount",
+			transferID: transfer.ID,
+			body: gin.H{
+				"amount": -1,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, recipient.Username, recipient.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAuditLog(t, store, recipient.Username, recipient.Role, action, http.StatusBadRequest)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:       "TransferNotFound",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, recipient.Username, recipient.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).
+					Return(db.Transfer{}, sql.ErrNoRows)
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAuditLog(t, store, recipient.Username, recipient.Role, action, http.StatusNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:       "ExceedsTransferAmount",
+			transferID: transfer.ID,
+			body: gin.H{
+				"amount": transfer.ToAmount + 1,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).
+					Return(transfer, nil)
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ReverseTransferTxResult{}, fmt.Errorf("%w: transfer [%d]", db.ErrInvalidReversal, transfer.ID))
+				expectAuditLog(t, store, admin.Username, admin.Role, action, http.StatusUnprocessableEntity)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				requireBodyMatchErrorCode(t, recorder.Body, errCodeInvalidReversal)
+			},
+		},
+		{
+			name:       "InsufficientFunds",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).
+					Return(transfer, nil)
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ReverseTransferTxResult{}, fmt.Errorf("%w: account [%d]", db.ErrInsufficientFunds, toAccount.ID))
+				expectAuditLog(t, store, admin.Username, admin.Role, action, http.StatusUnprocessableEntity)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				requireBodyMatchErrorCode(t, recorder.Body, errCodeInsufficientFunds)
+			},
+		},
+		{
+			name:       "AccountNotActive",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).
+					Return(transfer, nil)
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ReverseTransferTxResult{}, fmt.Errorf("%w: account [%d] status is frozen", db.ErrAccountNotActive, fromAccount.ID))
+				expectAuditLog(t, store, admin.Username, admin.Role, action, http.StatusUnprocessableEntity)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				requireBodyMatchErrorCode(t, recorder.Body, errCodeAccountNotActive)
+			},
+		},
+		{
+			name:       "InternalError",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).
+					Return(transfer, nil)
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ReverseTransferTxResult{}, sql.ErrConnDone)
+				expectAuditLog(t, store, admin.Username, admin.Role, action, http.StatusInternalServerError)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		// 每个测试案例使用子测试运行
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body io.Reader = http.NoBody
+			if tc.body != nil {
+				data, err := json.Marshal(tc.body)
+				require.NoError(t, err)
+				body = bytes.NewReader(data)
+			}
+
+			url := fmt.Sprintf("/transfers/%d/reverse", tc.transferID)
+			request, err := http.NewRequest(http.MethodPost, url, body)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
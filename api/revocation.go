@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	db "simplebank/db/sqlc"
+	"simplebank/logger"
+	"simplebank/token"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// tokenRevocationCache 在内存中缓存已吊销的 token，认证时不需要每次查询数据库
+// 本实例吊销的 token 立即生效，其他实例吊销的 token 在下次刷新缓存后生效
+// refreshInterval 为 0 时只使用本实例的吊销记录
+type tokenRevocationCache struct {
+	store           db.Store
+	refreshInterval time.Duration
+	// 只需要关注 token 有效期内的吊销记录
+	// 认证时只接受 access token，refresh token 通过封禁会话失效，因此使用 access token 的有效期
+	tokenDuration time.Duration
+
+	mu          sync.RWMutex
+	tokens      map[uuid.UUID]time.Time // token ID -> token 过期时间
+	users       map[string]time.Time    // username -> 在此时间之前签发的 token 失效
+	refreshedAt time.Time
+}
+
+func newTokenRevocationCache(store db.Store, refreshInterval, tokenDuration time.Duration) *tokenRevocationCache {
+	return &tokenRevocationCache{
+		store:           store,
+		refreshInterval: refreshInterval,
+		tokenDuration:   tokenDuration,
+		tokens:          make(map[uuid.UUID]time.Time),
+		users:           make(map[string]time.Time),
+	}
+}
+
+// isRevoked checks if the token has been revoked
+func (cache *tokenRevocationCache) isRevoked(ctx context.Context, payload *token.Payload) bool {
+	cache.refreshIfStale(ctx)
+
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	if _, ok := cache.tokens[payload.ID]; ok {
+		return true
+	}
+
+	revokedAt, ok := cache.users[payload.Username]
+	return ok && !payload.IssuedAt.After(revokedAt)
+}
+
+// revokeToken 吊销单个 token
+func (cache *tokenRevocationCache) revokeToken(payload *token.Payload) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	// 顺便清理已过期的 token，吊销操作不频繁
+	now := time.Now()
+	for id, expiredAt := range cache.tokens {
+		if !expiredAt.After(now) {
+			delete(cache.tokens, id)
+		}
+	}
+	cache.tokens[payload.ID] = payload.ExpiredAt
+}
+
+// revokeUser 吊销用户在 revokedAt 之前签发的全部 token
+func (cache *tokenRevocationCache) revokeUser(username string, revokedAt time.Time) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if revokedAt.After(cache.users[username]) {
+		cache.users[username] = revokedAt
+	}
+}
+
+func (cache *tokenRevocationCache) refreshIfStale(ctx context.Context) {
+	if cache.refreshInterval <= 0 {
+		return
+	}
+
+	cache.mu.Lock()
+	// 其他请求可能已经刷新过或正在刷新
+	if time.Since(cache.refreshedAt) < cache.refreshInterval {
+		cache.mu.Unlock()
+		return
+	}
+	// 刷新失败时继续使用旧的缓存，等待下次刷新，避免每个请求都查询数据库
+	cache.refreshedAt = time.Now()
+	cache.mu.Unlock()
+
+	// 查询时不持有锁，避免数据库变慢时阻塞所有请求的认证
+	// 刷新结果供所有请求使用，不应因触发刷新的请求被取消而失败
+	detached, cancel := detach(ctx)
+	defer cancel()
+
+	if err := cache.refresh(detached); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("cannot refresh token revocation cache")
+	}
+}
+
+// refresh 从数据库重新加载吊销记录，并清理数据库中已过期的记录
+func (cache *tokenRevocationCache) refresh(ctx context.Context) error {
+	revokedTokens, err := cache.store.ListRevokedTokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	since := time.Now().Add(-cache.tokenDuration)
+	revokedUsers, err := cache.store.ListUserTokenRevocations(ctx, sql.NullTime{Time: since, Valid: true})
+	if err != nil {
+		return err
+	}
+
+	// 已过期的 token 不需要再记录，清理失败不影响本次刷新
+	if err := cache.store.DeleteExpiredRevokedTokens(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("cannot delete expired revoked tokens")
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	now := time.Now()
+	tokens := make(map[uuid.UUID]time.Time, len(revokedTokens))
+	for _, revokedToken := range revokedTokens {
+		tokens[revokedToken.ID] = revokedToken.ExpiredAt
+	}
+	// 保留本实例中尚未过期的吊销记录，包括查询期间新增的记录
+	for id, expiredAt := range cache.tokens {
+		if expiredAt.After(now) {
+			tokens[id] = expiredAt
+		}
+	}
+
+	users := make(map[string]time.Time, len(revokedUsers))
+	for _, user := range revokedUsers {
+		users[user.Username] = user.TokensRevokedAt.Time
+	}
+	for username, revokedAt := range cache.users {
+		if revokedAt.After(since) && revokedAt.After(users[username]) {
+			users[username] = revokedAt
+		}
+	}
+
+	cache.tokens = tokens
+	cache.users = users
+	return nil
+}
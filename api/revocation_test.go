@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	mockdb "simplebank/db/mock"
+	db "simplebank/db/sqlc"
+	"simplebank/token"
+	"simplebank/utils"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func randomPayload(t *testing.T, username string) *token.Payload {
+	payload, err := token.NewPayload(username, string(db.UserRoleCustomer), token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+	return payload
+}
+
+func TestTokenRevocationCacheLocal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// 刷新间隔为 0 时不会查询数据库
+	store := mockdb.NewMockStore(ctrl)
+	cache := newTokenRevocationCache(store, 0, time.Minute)
+
+	username := utils.RandomOwner()
+	payload1 := randomPayload(t, username)
+	payload2 := randomPayload(t, username)
+	require.False(t, cache.isRevoked(context.Background(), payload1))
+
+	cache.revokeToken(payload1)
+	require.True(t, cache.isRevoked(context.Background(), payload1))
+	require.False(t, cache.isRevoked(context.Background(), payload2))
+
+	// 吊销之前签发的 token 失效，之后签发的 token 不受影响
+	cache.revokeUser(username, time.Now())
+	require.True(t, cache.isRevoked(context.Background(), payload2))
+
+	payload3 := randomPayload(t, username)
+	require.False(t, cache.isRevoked(context.Background(), payload3))
+	require.False(t, cache.isRevoked(context.Background(), randomPayload(t, utils.RandomOwner())))
+}
+
+func TestTokenRevocationCacheRefresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	cache := newTokenRevocationCache(store, time.Hour, time.Minute)
+
+	username := utils.RandomOwner()
+	payload1 := randomPayload(t, username)
+	payload2 := randomPayload(t, utils.RandomOwner())
+	revokedAt := time.Now()
+
+	// 缓存未过期时只查询一次数据库
+	store.EXPECT().
+		ListRevokedTokens(gomock.Any()).
+		Times(1).
+		Return([]db.RevokedToken{{ID: payload2.ID, Username: payload2.Username, ExpiredAt: payload2.ExpiredAt}}, nil)
+	store.EXPECT().
+		ListUserTokenRevocations(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.ListUserTokenRevocationsRow{
+			{Username: username, TokensRevokedAt: sql.NullTime{Time: revokedAt, Valid: true}},
+		}, nil)
+	store.EXPECT().
+		DeleteExpiredRevokedTokens(gomock.Any()).
+		Times(1).
+		Return(nil)
+
+	require.True(t, cache.isRevoked(context.Background(), payload1))
+	require.True(t, cache.isRevoked(context.Background(), payload2))
+	require.False(t, cache.isRevoked(context.Background(), randomPayload(t, username)))
+}
+
+func TestTokenRevocationCacheRefreshError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	cache := newTokenRevocationCache(store, time.Hour, time.Minute)
+
+	payload := randomPayload(t, utils.RandomOwner())
+	cache.revokeToken(payload)
+
+	// 刷新失败时保留本地的吊销记录，并且不会在每个请求中重试
+	store.EXPECT().
+		ListRevokedTokens(gomock.Any()).
+		Times(1).
+		Return(nil, sql.ErrConnDone)
+
+	require.True(t, cache.isRevoked(context.Background(), payload))
+	require.False(t, cache.isRevoked(context.Background(), &token.Payload{ID: uuid.New()}))
+}
+
+func TestTokenRevocationCacheRefreshWithoutLock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	cache := newTokenRevocationCache(store, time.Hour, time.Minute)
+
+	payload1 := randomPayload(t, utils.RandomOwner())
+	payload2 := randomPayload(t, utils.RandomOwner())
+
+	// 触发刷新的请求已被取消
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// 查询不受请求取消的影响，并且有超时；查询期间不持有锁，其他请求可以吊销 token
+	store.EXPECT().
+		ListRevokedTokens(gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context) ([]db.RevokedToken, error) {
+			require.NoError(t, ctx.Err())
+			_, ok := ctx.Deadline()
+			require.True(t, ok)
+
+			cache.revokeToken(payload2)
+			return nil, nil
+		})
+	store.EXPECT().
+		ListUserTokenRevocations(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil, nil)
+	store.EXPECT().
+		DeleteExpiredRevokedTokens(gomock.Any()).
+		Times(1).
+		Return(sql.ErrConnDone)
+
+	require.False(t, cache.isRevoked(ctx, payload1))
+	require.True(t, cache.isRevoked(ctx, payload2))
+}
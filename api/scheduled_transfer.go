@@ -0,0 +1,261 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	db "simplebank/db/sqlc"
+	"simplebank/token"
+	"simplebank/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createScheduledTransferRequest struct {
+	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1,nefield=FromAccountID"`
+	Amount        int64  `json:"amount" binding:"required,gt=0"`
+	Currency      string `json:"currency" binding:"required,currency"`
+	Memo          string `json:"memo" binding:"max=255"`
+	// 周期转账的 cron 表达式，为空时为一次性转账
+	CronExpr string `json:"cron_expr" binding:"omitempty,cron"`
+	// 一次性转账的执行时间，或周期转账的首次执行时间
+	RunAt time.Time `json:"run_at"`
+}
+
+// createScheduledTransfer 创建一次性或周期执行的定时转账，由后台 worker 按计划执行
+func (server *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req createScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	now := time.Now()
+	nextRunAt := req.RunAt
+	if nextRunAt.IsZero() {
+		if len(req.CronExpr) == 0 {
+			respondError(ctx, http.StatusBadRequest, errRunAtRequired)
+			return
+		}
+
+		// cron_expr 已经通过校验
+		nextRunAt, _ = utils.NextRunTime(req.CronExpr, now)
+	} else if nextRunAt.Before(now) {
+		respondError(ctx, http.StatusBadRequest, errRunAtNotInFuture)
+		return
+	}
+
+	fromAccount, valid := server.validCurrency(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != payload.Username {
+		respondError(ctx, http.StatusUnauthorized, errAccountNotOwned)
+		return
+	}
+
+	if _, valid = server.validCurrency(ctx, req.ToAccountID, req.Currency); !valid {
+		return
+	}
+
+	scheduled, err := server.store.CreateScheduledTransfer(ctx, db.CreateScheduledTransferParams{
+		Owner:         payload.Username,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Memo:          req.Memo,
+		CronExpr:      req.CronExpr,
+		NextRunAt:     nextRunAt,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+type scheduledTransferURIRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) getScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	scheduled, valid := server.validScheduledTransferOwner(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+func (server *Server) listScheduledTransfers(ctx *gin.Context) {
+	var req listAccountRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	scheduledTransfers, err := server.store.ListScheduledTransfers(ctx, db.ListScheduledTransfersParams{
+		Owner:  payload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduledTransfers)
+}
+
+type updateScheduledTransferRequest struct {
+	Amount *int64  `json:"amount" binding:"omitempty,gt=0"`
+	Memo   *string `json:"memo" binding:"omitempty,max=255"`
+	// 设置为空字符串时改为一次性转账
+	CronExpr *string    `json:"cron_expr" binding:"omitempty,cron"`
+	RunAt    *time.Time `json:"run_at"`
+	// 暂停或恢复，恢复时清零失败次数
+	Status db.ScheduledTransferStatus `json:"status" binding:"omitempty,oneof=active paused"`
+}
+
+// updateScheduledTransfer 修改、暂停或恢复定时转账，只修改请求中出现的字段
+func (server *Server) updateScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var req updateScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.RunAt != nil && req.RunAt.Before(time.Now()) {
+		respondError(ctx, http.StatusBadRequest, errRunAtNotInFuture)
+		return
+	}
+
+	if _, valid := server.validScheduledTransferOwner(ctx, uri.ID); !valid {
+		return
+	}
+
+	arg := db.UpdateScheduledTransferTxParams{
+		ID:     uri.ID,
+		Status: req.Status,
+	}
+	if req.Amount != nil {
+		arg.Amount = sql.NullInt64{Int64: *req.Amount, Valid: true}
+	}
+	if req.Memo != nil {
+		arg.Memo = sql.NullString{String: *req.Memo, Valid: true}
+	}
+	if req.CronExpr != nil {
+		arg.CronExpr = sql.NullString{String: *req.CronExpr, Valid: true}
+	}
+	if req.RunAt != nil {
+		arg.NextRunAt = sql.NullTime{Time: *req.RunAt, Valid: true}
+	}
+
+	server.respondUpdateScheduledTransfer(ctx, arg)
+}
+
+// cancelScheduledTransfer 取消定时转账，保留执行记录
+func (server *Server) cancelScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, valid := server.validScheduledTransferOwner(ctx, uri.ID); !valid {
+		return
+	}
+
+	server.respondUpdateScheduledTransfer(ctx, db.UpdateScheduledTransferTxParams{
+		ID:     uri.ID,
+		Status: db.ScheduledTransferStatusCancelled,
+	})
+}
+
+func (server *Server) respondUpdateScheduledTransfer(ctx *gin.Context, arg db.UpdateScheduledTransferTxParams) {
+	scheduled, err := server.store.UpdateScheduledTransferTx(ctx, arg)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			respondError(ctx, http.StatusNotFound, err)
+		case errors.Is(err, db.ErrScheduledTransferFinished):
+			respondError(ctx, http.StatusUnprocessableEntity, err)
+		default:
+			respondError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+// listScheduledTransferExecutions 查询定时转账每次执行的结果，最新的在前
+func (server *Server) listScheduledTransferExecutions(ctx *gin.Context) {
+	var uri scheduledTransferURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var req listAccountRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, valid := server.validScheduledTransferOwner(ctx, uri.ID); !valid {
+		return
+	}
+
+	executions, err := server.store.ListScheduledTransferExecutions(ctx, db.ListScheduledTransferExecutionsParams{
+		ScheduledTransferID: uri.ID,
+		Limit:               req.PageSize,
+		Offset:              (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, executions)
+}
+
+// 只能查看或修改自己创建的定时转账
+func (server *Server) validScheduledTransferOwner(ctx *gin.Context, id int64) (db.ScheduledTransfer, bool) {
+	scheduled, err := server.store.GetScheduledTransfer(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(ctx, http.StatusNotFound, err)
+			return scheduled, false
+		}
+
+		respondError(ctx, http.StatusInternalServerError, err)
+		return scheduled, false
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if scheduled.Owner != payload.Username {
+		respondError(ctx, http.StatusUnauthorized, errScheduledTransferNotOwned)
+		return scheduled, false
+	}
+
+	return scheduled, true
+}
@@ -0,0 +1,528 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	mockdb "simplebank/db/mock"
+	db "simplebank/db/sqlc"
+	"simplebank/token"
+	"simplebank/utils"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func randomScheduledTransfer(owner string, fromAccount, toAccount db.Account) db.ScheduledTransfer {
+	return db.ScheduledTransfer{
+		ID:            utils.RandomInt(1, 1000),
+		Owner:         owner,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        utils.RandomInt(1, 100),
+		Memo:          "rent",
+		CronExpr:      "@monthly",
+		NextRunAt:     time.Now().Add(time.Hour).Truncate(time.Second).UTC(),
+		Status:        db.ScheduledTransferStatusActive,
+	}
+}
+
+func TestCreateScheduledTransferAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	fromAccount := randomAccount(user.Username)
+	fromAccount.Currency = utils.RMB
+	toAccount := randomAccount(user2.Username)
+	toAccount.ID = fromAccount.ID + 1
+	toAccount.Currency = utils.RMB
+
+	scheduled := randomScheduledTransfer(user.Username, fromAccount, toAccount)
+	runAt := time.Now().Add(24 * time.Hour).Truncate(time.Second).UTC()
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OKRecurring",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          scheduled.Amount,
+				"currency":        utils.RMB,
+				"memo":            "rent",
+				"cron_expr":       "@monthly",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+
+				// 未指定 run_at 时按 cron 表达式计算首次执行时间
+				nextRunAt, err := utils.NextRunTime("@monthly", time.Now())
+				require.NoError(t, err)
+				store.EXPECT().
+					CreateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateScheduledTransferParams) (db.ScheduledTransfer, error) {
+						require.Equal(t, user.Username, arg.Owner)
+						require.Equal(t, fromAccount.ID, arg.FromAccountID)
+						require.Equal(t, toAccount.ID, arg.ToAccountID)
+						require.Equal(t, scheduled.Amount, arg.Amount)
+						require.Equal(t, "rent", arg.Memo)
+						require.Equal(t, "@monthly", arg.CronExpr)
+						require.WithinDuration(t, nextRunAt, arg.NextRunAt, time.Second)
+						return scheduled, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchScheduledTransfer(t, recorder.Body, scheduled)
+			},
+		},
+		{
+			name: "OKOneOff",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          scheduled.Amount,
+				"currency":        utils.RMB,
+				"run_at":          runAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+
+				arg := db.CreateScheduledTransferParams{
+					Owner:         user.Username,
+					FromAccountID: fromAccount.ID,
+					ToAccountID:   toAccount.ID,
+					Amount:        scheduled.Amount,
+					NextRunAt:     runAt,
+				}
+				store.EXPECT().
+					CreateScheduledTransfer(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(scheduled, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "MissingRunAt",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          scheduled.Amount,
+				"currency":        utils.RMB,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "RunAtInThePast",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          scheduled.Amount,
+				"currency":        utils.RMB,
+				"run_at":          time.Now().Add(-time.Hour),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCronExpr",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          scheduled.Amount,
+				"currency":        utils.RMB,
+				"cron_expr":       "every month",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "SameAccount",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   fromAccount.ID,
+				"amount":          scheduled.Amount,
+				"currency":        utils.RMB,
+				"cron_expr":       "@monthly",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UnauthorizedUser",
+			body: gin.H{
+				"from_account_id": toAccount.ID,
+				"to_account_id":   fromAccount.ID,
+				"amount":          scheduled.Amount,
+				"currency":        utils.RMB,
+				"cron_expr":       "@monthly",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          scheduled.Amount,
+				"currency":        utils.USD,
+				"cron_expr":       "@monthly",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          scheduled.Amount,
+				"currency":        utils.RMB,
+				"cron_expr":       "@monthly",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().
+					CreateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ScheduledTransfer{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/scheduled_transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateScheduledTransferAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	fromAccount := randomAccount(user.Username)
+	toAccount := randomAccount(user.Username)
+	scheduled := randomScheduledTransfer(user.Username, fromAccount, toAccount)
+
+	paused := scheduled
+	paused.Status = db.ScheduledTransferStatusPaused
+
+	testCases := []struct {
+		name          string
+		method        string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Pause",
+			method: http.MethodPatch,
+			body: gin.H{
+				"status": "paused",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).Times(1).Return(scheduled, nil)
+
+				arg := db.UpdateScheduledTransferTxParams{
+					ID:     scheduled.ID,
+					Status: db.ScheduledTransferStatusPaused,
+				}
+				store.EXPECT().
+					UpdateScheduledTransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(paused, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchScheduledTransfer(t, recorder.Body, paused)
+			},
+		},
+		{
+			name:   "UpdateFields",
+			method: http.MethodPatch,
+			body: gin.H{
+				"amount":    500,
+				"memo":      "",
+				"cron_expr": "0 9 1 * *",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).Times(1).Return(scheduled, nil)
+
+				// 请求中出现的字段才会修改，包括空字符串
+				arg := db.UpdateScheduledTransferTxParams{
+					ID:       scheduled.ID,
+					Amount:   sql.NullInt64{Int64: 500, Valid: true},
+					Memo:     sql.NullString{String: "", Valid: true},
+					CronExpr: sql.NullString{String: "0 9 1 * *", Valid: true},
+				}
+				store.EXPECT().
+					UpdateScheduledTransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(scheduled, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidStatus",
+			method: http.MethodPatch,
+			body: gin.H{
+				"status": "completed",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateScheduledTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "Cancel",
+			method: http.MethodDelete,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).Times(1).Return(scheduled, nil)
+
+				arg := db.UpdateScheduledTransferTxParams{
+					ID:     scheduled.ID,
+					Status: db.ScheduledTransferStatusCancelled,
+				}
+				store.EXPECT().
+					UpdateScheduledTransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(scheduled, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "AlreadyFinished",
+			method: http.MethodDelete,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).Times(1).Return(scheduled, nil)
+				store.EXPECT().
+					UpdateScheduledTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ScheduledTransfer{}, fmt.Errorf("%w: scheduled transfer [%d] status is cancelled", db.ErrScheduledTransferFinished, scheduled.ID))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				requireBodyMatchErrorCode(t, recorder.Body, errCodeScheduledTransferFinished)
+			},
+		},
+		{
+			name:   "UnauthorizedUser",
+			method: http.MethodPatch,
+			body: gin.H{
+				"status": "paused",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized_user", db.UserRoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).Times(1).Return(scheduled, nil)
+				store.EXPECT().UpdateScheduledTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "NotFound",
+			method: http.MethodDelete,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+					Times(1).
+					Return(db.ScheduledTransfer{}, sql.ErrNoRows)
+				store.EXPECT().UpdateScheduledTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "NoAuthorization",
+			method: http.MethodDelete,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body *bytes.Reader
+			if tc.body != nil {
+				data, err := json.Marshal(tc.body)
+				require.NoError(t, err)
+				body = bytes.NewReader(data)
+			} else {
+				body = bytes.NewReader(nil)
+			}
+
+			url := fmt.Sprintf("/scheduled_transfers/%d", scheduled.ID)
+			request, err := http.NewRequest(tc.method, url, body)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListScheduledTransferExecutionsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	scheduled := randomScheduledTransfer(user.Username, randomAccount(user.Username), randomAccount(user.Username))
+
+	executions := []db.ScheduledTransferExecution{
+		{
+			ID:                  2,
+			ScheduledTransferID: scheduled.ID,
+			Status:              db.ExecutionStatusFailed,
+			Error:               "insufficient funds",
+			ScheduledAt:         scheduled.NextRunAt,
+		},
+		{
+			ID:                  1,
+			ScheduledTransferID: scheduled.ID,
+			TransferID:          sql.NullInt64{Int64: 10, Valid: true},
+			Status:              db.ExecutionStatusSucceeded,
+			ScheduledAt:         scheduled.NextRunAt.AddDate(0, -1, 0),
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).Times(1).Return(scheduled, nil)
+	store.EXPECT().
+		ListScheduledTransferExecutions(gomock.Any(), gomock.Eq(db.ListScheduledTransferExecutionsParams{
+			ScheduledTransferID: scheduled.ID,
+			Limit:               5,
+			Offset:              0,
+		})).
+		Times(1).
+		Return(executions, nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	url := fmt.Sprintf("/scheduled_transfers/%d/executions?page_id=1&page_size=5", scheduled.ID)
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var gotExecutions []db.ScheduledTransferExecution
+	err = json.Unmarshal(recorder.Body.Bytes(), &gotExecutions)
+	require.NoError(t, err)
+	require.Equal(t, executions, gotExecutions)
+}
+
+func requireBodyMatchScheduledTransfer(t *testing.T, body *bytes.Buffer, scheduled db.ScheduledTransfer) {
+	var gotScheduled db.ScheduledTransfer
+	err := json.Unmarshal(body.Bytes(), &gotScheduled)
+	require.NoError(t, err)
+	require.Equal(t, scheduled, gotScheduled)
+}
@@ -1,14 +1,23 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"simplebank/db/migration"
 	db "simplebank/db/sqlc"
+	"simplebank/metrics"
+	"simplebank/reconcile"
+	"simplebank/stream"
 	"simplebank/token"
 	"simplebank/utils"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
 )
 
 // Server servers HTTP requests for our banking service.
@@ -17,11 +26,29 @@ type Server struct {
 	store      db.Store
 	tokenMaker token.Maker
 	router     *gin.Engine
+	// 已吊销的 token
+	revocations *tokenRevocationCache
+	reconciler  *reconcile.Reconciler
+	// 分发账户动态给 SSE 连接
+	broker *stream.Broker
+	// 启动时根据路由生成的 OpenAPI 文档
+	openAPISpec openAPIDocument
+	// 服务要求的数据库迁移版本
+	schemaVersion uint
+	httpServer    *http.Server
+	// 开始关闭时被关闭，readyz 返回 503，SSE 连接断开
+	shuttingDown chan struct{}
+	shutdownOnce sync.Once
 }
 
 // NewServer creates a new HTTP server and setup routing.
 func NewServer(config utils.Config, store db.Store) (*Server, error) {
-	maker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	maker, err := newTokenMaker(config)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaVersion, err := migration.LatestVersion()
 	if err != nil {
 		return nil, err
 	}
@@ -30,46 +57,195 @@ func NewServer(config utils.Config, store db.Store) (*Server, error) {
 		config:     config,
 		store:      store,
 		tokenMaker: maker,
+		revocations: newTokenRevocationCache(store,
+			config.TokenRevocationRefreshInterval, config.AccessTokenDuartion),
+		reconciler:    reconcile.NewReconciler(store, config.ReconcileBatchSize),
+		broker:        stream.NewBroker(),
+		schemaVersion: schemaVersion,
+		shuttingDown:  make(chan struct{}),
 	}
 
-	// 注册 currency 检查器
+	// 注册 currency、cron、webhook_url 检查器，校验错误使用请求中的字段名
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("cron", validCronExpr)
+		v.RegisterValidation("webhook_url", validWebhookURL)
+		v.RegisterTagNameFunc(fieldTagName)
 	}
 
 	server.setupRouter()
+	server.httpServer = &http.Server{
+		Addr:              config.ServerAddress,
+		Handler:           server.router,
+		ReadHeaderTimeout: config.HTTPReadTimeout,
+		ReadTimeout:       config.HTTPReadTimeout,
+		WriteTimeout:      config.HTTPWriteTimeout,
+		IdleTimeout:       config.HTTPIdleTimeout,
+	}
 	return server, nil
 }
 
+// newTokenMaker 配置了私钥时使用 Ed25519 签名的 token，否则使用对称加密的 token
+// TOKEN_PUBLIC_KEYS 为轮换前的旧公钥，格式为 "id:base64,id:base64"
+func newTokenMaker(config utils.Config) (token.Maker, error) {
+	if len(config.TokenPrivateKey) == 0 {
+		return token.NewPasetoMaker(config.TokenSymmetricKey)
+	}
+
+	privateKey, err := token.ParsePrivateKey(config.TokenPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKeys, err := token.ParsePublicKeys(config.TokenPublicKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return token.NewAsymmetricPasetoMaker(config.TokenKeyID, privateKey, publicKeys...)
+}
+
 func (server *Server) setupRouter() {
-	router := gin.Default()
+	// 访问日志需要 request ID 和 span，panic 恢复后的 500 也要记录到访问日志和指标中
+	router := gin.New()
+	// *gin.Context 的 Value、Done 使用请求的 context，handler 直接把 *gin.Context 传给 store 时
+	// 查询可以取得请求的 span，客户端断开后查询也会被取消
+	router.ContextWithFallback = true
+	router.Use(requestIDMiddleware(), tracingMiddleware(), loggerMiddleware(), metricsMiddleware(), recoveryMiddleware())
+
+	// 配置了 METRICS_ADDRESS 时指标由单独的管理端口提供
+	if len(server.config.MetricsAddress) == 0 {
+		router.GET(metrics.Path, gin.WrapH(metrics.Handler()))
+	}
+
+	router.GET("/healthz", server.healthz)
+	router.GET("/readyz", server.readyz)
+	router.GET(openAPIPath, server.getOpenAPISpec)
+	router.GET("/swagger/*filepath", server.swaggerUI)
 
 	router.POST("/users", server.createUser)
 	router.POST("/users/login", server.loginUser)
+	router.POST("/tokens/renew_access", server.renewAccessToken)
+	router.GET("/.well-known/keys", server.listPublicKeys)
 
-	authRouters := router.Group("/").Use(authMiddleware(server.tokenMaker))
+	authRouters := router.Group("/").Use(authMiddleware(server.tokenMaker, server.revocations))
+	idempotency := idempotencyMiddleware(server.store, server.config.IdempotencyKeyTTL)
 	{
-		authRouters.POST("/accounts", server.createAccount)
+		authRouters.POST("/users/logout", server.logoutUser)
+		authRouters.POST("/users/logout_all", server.logoutAllUser)
+
+		authRouters.POST("/accounts", idempotency, server.createAccount)
 		authRouters.GET("/accounts/:id", server.getAccount)
 		authRouters.GET("/accounts", server.listAccount)
-		authRouters.DELETE("/accounts/:id", server.deleteAccount)
-		authRouters.PUT("/accounts", server.updateAccount)
+		authRouters.DELETE("/accounts/:id", server.closeAccount)
+		authRouters.GET("/accounts/:id/entries", server.listEntries)
+		authRouters.GET("/accounts/:id/transfers", server.listTransfers)
+		authRouters.GET("/accounts/:id/stream", server.streamAccount)
+		// 调账只允许管理员操作，并与管理接口一样写入审计日志
+		authRouters.POST("/accounts/:id/adjustments",
+			auditMiddleware(server.store), roleMiddleware(db.UserRoleAdmin), idempotency, server.createAdjustment)
+
+		authRouters.POST("/transfer", idempotency, server.createTransfer)
+		authRouters.POST("/transfers/:id/reverse", auditMiddleware(server.store), idempotency, server.reverseTransfer)
+
+		authRouters.POST("/fx/quotes", server.createFxQuote)
 
-		authRouters.POST("/transfer", server.createTransfer)
+		authRouters.POST("/scheduled_transfers", idempotency, server.createScheduledTransfer)
+		authRouters.GET("/scheduled_transfers", server.listScheduledTransfers)
+		authRouters.GET("/scheduled_transfers/:id", server.getScheduledTransfer)
+		authRouters.PATCH("/scheduled_transfers/:id", server.updateScheduledTransfer)
+		authRouters.DELETE("/scheduled_transfers/:id", server.cancelScheduledTransfer)
+		authRouters.GET("/scheduled_transfers/:id/executions", server.listScheduledTransferExecutions)
+
+		authRouters.POST("/webhooks", server.createWebhook)
+		authRouters.GET("/webhooks", server.listWebhooks)
+		authRouters.DELETE("/webhooks/:id", server.deleteWebhook)
+		authRouters.GET("/webhooks/:id/deliveries", server.listWebhookDeliveries)
+
+		authRouters.POST("/sessions/:id/block", server.blockSession)
+	}
+
+	// 管理接口按角色授权，审计日志需要记录被拒绝的请求，因此在角色检查之前
+	adminRouters := router.Group("/admin").Use(
+		authMiddleware(server.tokenMaker, server.revocations),
+		auditMiddleware(server.store),
+	)
+	staff := roleMiddleware(db.UserRoleSupport, db.UserRoleAdmin, db.UserRoleAuditor)
+	operator := roleMiddleware(db.UserRoleSupport, db.UserRoleAdmin)
+	admin := roleMiddleware(db.UserRoleAdmin)
+	auditor := roleMiddleware(db.UserRoleAdmin, db.UserRoleAuditor)
+	{
+		adminRouters.GET("/users/:username", staff, server.adminGetUser)
+		adminRouters.GET("/users/:username/accounts", staff, server.adminListUserAccounts)
+		adminRouters.PUT("/users/:username/role", admin, server.adminUpdateUserRole)
+
+		adminRouters.POST("/accounts/:id/freeze", operator, server.adminFreezeAccount)
+		adminRouters.POST("/accounts/:id/unfreeze", operator, server.adminUnfreezeAccount)
+		adminRouters.POST("/accounts/:id/close", admin, server.adminCloseAccount)
+		adminRouters.PATCH("/accounts/:id/overdraft_limit", admin, server.adminUpdateOverdraftLimit)
+		adminRouters.GET("/accounts/:id/transfers", staff, server.adminListTransfers)
+		adminRouters.GET("/accounts/:id/adjustments", staff, server.adminListAdjustments)
+
+		adminRouters.GET("/audit_logs", auditor, server.adminListAuditLogs)
+		adminRouters.GET("/reconciliation", auditor, server.adminGetReconciliation)
 	}
 
 	server.router = router
+	server.openAPISpec = newOpenAPIDocument(router.Routes())
+}
+
+// StartReconciler 按 RECONCILE_INTERVAL 定期对账，直到 ctx 被取消，未配置间隔时直接返回
+func (server *Server) StartReconciler(ctx context.Context) {
+	if server.config.ReconcileInterval <= 0 {
+		return
+	}
+
+	log.Info().Dur("interval", server.config.ReconcileInterval).Msg("reconciliation scheduled")
+	server.reconciler.Start(ctx, server.config.ReconcileInterval)
+}
+
+// StartIdempotencyKeyPurger 按 IDEMPOTENCY_KEY_PURGE_INTERVAL 定期清理过期的幂等键，直到 ctx 被取消
+// 未配置间隔时直接返回
+func (server *Server) StartIdempotencyKeyPurger(ctx context.Context) {
+	if server.config.IdempotencyKeyPurgeInterval <= 0 {
+		return
+	}
+
+	purgeExpiredIdempotencyKeys(ctx, server.store, server.config.IdempotencyKeyPurgeInterval)
+}
+
+// StartActivityListener 监听数据库的账户动态通知并推送给 SSE 连接，直到 ctx 被取消
+func (server *Server) StartActivityListener(ctx context.Context) error {
+	return server.broker.Start(ctx, server.config.DBSource)
+}
+
+// TokenMaker returns the token maker, shared with the gRPC server.
+func (server *Server) TokenMaker() token.Maker {
+	return server.tokenMaker
+}
+
+// IsTokenRevoked 判断 token 是否已被吊销，供 gRPC 服务认证时与 HTTP 服务共用吊销缓存
+func (server *Server) IsTokenRevoked(ctx context.Context, payload *token.Payload) bool {
+	return server.revocations.isRevoked(ctx, payload)
 }
 
 // Start runs the HTTP server on a specific address.
+// Shutdown 之后返回 nil
 func (server *Server) Start() error {
-	err := server.router.Run(server.config.ServerAddress)
-	if err != nil {
+	log.Info().Str("address", server.config.ServerAddress).Msg("start HTTP server")
+	err := server.httpServer.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return fmt.Errorf("cannot start server: %w", err)
 	}
 	return nil
 }
 
-func errorResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
+// Shutdown 停止接收新的请求并等待处理中的请求完成，ctx 超时后返回错误
+// 调用后 readyz 返回 503，SSE 连接断开
+func (server *Server) Shutdown(ctx context.Context) error {
+	server.shutdownOnce.Do(func() {
+		close(server.shuttingDown)
+	})
+
+	return server.httpServer.Shutdown(ctx)
 }
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	db "simplebank/db/sqlc"
+	"simplebank/token"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type sessionURIRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type sessionResponse struct {
+	ID        uuid.UUID `json:"id"`
+	UserAgent string    `json:"user_agent"`
+	ClientIp  string    `json:"client_ip"`
+	IsBlocked bool      `json:"is_blocked"`
+	ExpiredAt time.Time `json:"expired_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 不返回 refresh token
+func newSessionResponse(session db.Session) sessionResponse {
+	return sessionResponse{
+		ID:        session.ID,
+		UserAgent: session.UserAgent,
+		ClientIp:  session.ClientIp,
+		IsBlocked: session.IsBlocked,
+		ExpiredAt: session.ExpiredAt,
+		CreatedAt: session.CreatedAt,
+	}
+}
+
+// blockSession 封禁当前用户的某个会话，用于 refresh token 泄露后使其失效
+func (server *Server) blockSession(ctx *gin.Context) {
+	var req sessionURIRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	session, err := server.store.BlockSession(ctx, db.BlockSessionParams{
+		ID:       uuid.MustParse(req.ID),
+		Username: payload.Username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(ctx, http.StatusNotFound, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newSessionResponse(session))
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	db "simplebank/db/sqlc"
+	"simplebank/logger"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultHeartbeatInterval = 15 * time.Second
+	// 每次查询新流水的数量，积压的流水分多次推送
+	streamBatchSize = 100
+)
+
+// 推送的事件类型
+const (
+	streamEventEntry   = "entry"
+	streamEventBalance = "balance"
+)
+
+type balanceEvent struct {
+	AccountID int64  `json:"account_id"`
+	Balance   int64  `json:"balance"`
+	Currency  string `json:"currency"`
+}
+
+// streamAccount 通过 SSE 推送账户的新流水和余额变化
+// 流水事件的 id 为流水 id，断线重连时客户端带上 Last-Event-ID 即可补发期间的流水
+func (server *Server) streamAccount(ctx *gin.Context) {
+	var uri accountURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var lastEventID int64
+	if header := ctx.GetHeader("Last-Event-ID"); len(header) > 0 {
+		id, err := strconv.ParseInt(header, 10, 64)
+		if err != nil || id < 0 {
+			respondError(ctx, http.StatusBadRequest, errInvalidLastEventID)
+			return
+		}
+		lastEventID = id
+	}
+
+	account, valid := server.validAccountOwner(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	// 先订阅再查询，避免丢失查询期间产生的流水
+	notifications, unsubscribe := server.broker.Subscribe(account.ID)
+	defer unsubscribe()
+
+	if lastEventID == 0 {
+		// 新连接只推送之后的流水
+		id, err := server.store.GetLastAccountEntryID(ctx, account.ID)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		lastEventID = id
+	}
+
+	// SSE 连接长期保持，不受 HTTP_WRITE_TIMEOUT 限制
+	err := http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{})
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// 禁止反向代理缓冲响应
+	header.Set("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	// 补发断线期间的流水，并推送当前余额
+	lastEventID, err = server.sendNewEntries(ctx, account.ID, lastEventID)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("account_id", account.ID).Msg("cannot stream account")
+		return
+	}
+
+	interval := server.config.StreamHeartbeatInterval
+	if interval <= 0 {
+		interval = defaultHeartbeatInterval
+	}
+	heartbeat := time.NewTicker(interval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-server.shuttingDown:
+			// 服务关闭时断开连接，客户端带上 Last-Event-ID 重连到其他实例
+			return
+		case <-heartbeat.C:
+			// 注释行不会触发客户端事件，只用于保持连接
+			if _, err := io.WriteString(ctx.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			ctx.Writer.Flush()
+		case <-notifications:
+			lastEventID, err = server.sendNewEntries(ctx, account.ID, lastEventID)
+			if err != nil {
+				// 关闭连接，由客户端带上 Last-Event-ID 重连
+				logger.Ctx(ctx).Error().Err(err).Int64("account_id", account.ID).Msg("cannot stream account")
+				return
+			}
+		}
+	}
+}
+
+// sendNewEntries 推送 afterID 之后的流水和账户的最新余额，返回最后推送的流水 id
+func (server *Server) sendNewEntries(ctx *gin.Context, accountID int64, afterID int64) (int64, error) {
+	for {
+		entries, err := server.store.ListAccountEntriesAfter(ctx, db.ListAccountEntriesAfterParams{
+			AccountID: accountID,
+			AfterID:   afterID,
+			Limit:     streamBatchSize,
+		})
+		if err != nil {
+			return afterID, err
+		}
+
+		for _, entry := range entries {
+			if err := writeEvent(ctx.Writer, strconv.FormatInt(entry.ID, 10), streamEventEntry, entry); err != nil {
+				return afterID, err
+			}
+			afterID = entry.ID
+		}
+
+		if len(entries) < streamBatchSize {
+			break
+		}
+	}
+
+	account, err := server.store.GetAccount(ctx, accountID)
+	if err != nil {
+		return afterID, err
+	}
+
+	// 余额事件不带 id，不影响客户端的 Last-Event-ID
+	err = writeEvent(ctx.Writer, "", streamEventBalance, balanceEvent{
+		AccountID: account.ID,
+		Balance:   account.Balance,
+		Currency:  account.Currency,
+	})
+	if err != nil {
+		return afterID, err
+	}
+
+	ctx.Writer.Flush()
+	return afterID, nil
+}
+
+func writeEvent(w io.Writer, id string, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	if len(id) > 0 {
+		if _, err = fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
@@ -0,0 +1,314 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	mockdb "simplebank/db/mock"
+	db "simplebank/db/sqlc"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+type sseEvent struct {
+	ID      string
+	Event   string
+	Data    string
+	Comment string
+}
+
+// readEvent 读取一个以空行结束的 SSE 事件
+func readEvent(t *testing.T, reader *bufio.Reader) sseEvent {
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+
+		line = strings.TrimSuffix(line, "\n")
+		if len(line) == 0 {
+			return event
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "":
+			event.Comment = value
+		case "id":
+			event.ID = value
+		case "event":
+			event.Event = value
+		case "data":
+			event.Data = value
+		}
+	}
+}
+
+func requireEntryEvent(t *testing.T, event sseEvent, entry db.Entry) {
+	require.Equal(t, streamEventEntry, event.Event)
+	require.Equal(t, strconv.FormatInt(entry.ID, 10), event.ID)
+
+	var gotEntry db.Entry
+	require.NoError(t, json.Unmarshal([]byte(event.Data), &gotEntry))
+	require.Equal(t, entry, gotEntry)
+}
+
+func requireBalanceEvent(t *testing.T, event sseEvent, account db.Account) {
+	require.Equal(t, streamEventBalance, event.Event)
+	require.Empty(t, event.ID)
+
+	var got balanceEvent
+	require.NoError(t, json.Unmarshal([]byte(event.Data), &got))
+	require.Equal(t, balanceEvent{AccountID: account.ID, Balance: account.Balance, Currency: account.Currency}, got)
+}
+
+// openStream 连接 SSE 接口，返回的 reader 用于读取事件，测试结束时关闭连接
+func openStream(t *testing.T, server *Server, username string, accountID int64, lastEventID string) *bufio.Reader {
+	ts := httptest.NewServer(server.router)
+	t.Cleanup(ts.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	url := fmt.Sprintf("%s/accounts/%d/stream", ts.URL, accountID)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	if len(lastEventID) > 0 {
+		request.Header.Set("Last-Event-ID", lastEventID)
+	}
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, db.UserRoleCustomer, time.Minute)
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	t.Cleanup(func() { response.Body.Close() })
+
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	return bufio.NewReader(response.Body)
+}
+
+func TestStreamAccountAPIResume(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	entries := make([]db.Entry, 3)
+	for i := range entries {
+		entries[i] = randomEntry(account)
+		entries[i].ID = int64(i + 6)
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(3).Return(account, nil)
+	// 客户端带上 Last-Event-ID 时不查询最新的流水 id
+	store.EXPECT().GetLastAccountEntryID(gomock.Any(), gomock.Any()).Times(0)
+	store.EXPECT().
+		ListAccountEntriesAfter(gomock.Any(), gomock.Eq(db.ListAccountEntriesAfterParams{
+			AccountID: account.ID,
+			AfterID:   5,
+			Limit:     streamBatchSize,
+		})).
+		Times(1).
+		Return(entries[:2], nil)
+	store.EXPECT().
+		ListAccountEntriesAfter(gomock.Any(), gomock.Eq(db.ListAccountEntriesAfterParams{
+			AccountID: account.ID,
+			AfterID:   7,
+			Limit:     streamBatchSize,
+		})).
+		Times(1).
+		Return(entries[2:], nil)
+
+	server := newTestServer(t, store)
+	reader := openStream(t, server, user.Username, account.ID, "5")
+
+	// 补发断线期间的流水
+	requireEntryEvent(t, readEvent(t, reader), entries[0])
+	requireEntryEvent(t, readEvent(t, reader), entries[1])
+	requireBalanceEvent(t, readEvent(t, reader), account)
+
+	// 收到数据库通知后推送新流水
+	server.broker.Publish(account.ID)
+	requireEntryEvent(t, readEvent(t, reader), entries[2])
+	requireBalanceEvent(t, readEvent(t, reader), account)
+}
+
+func TestStreamAccountAPIHeartbeat(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(2).Return(account, nil)
+	// 新连接从最新的流水之后开始推送
+	store.EXPECT().GetLastAccountEntryID(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(int64(10), nil)
+	store.EXPECT().
+		ListAccountEntriesAfter(gomock.Any(), gomock.Eq(db.ListAccountEntriesAfterParams{
+			AccountID: account.ID,
+			AfterID:   10,
+			Limit:     streamBatchSize,
+		})).
+		Times(1).
+		Return([]db.Entry{}, nil)
+
+	server := newTestServer(t, store)
+	server.config.StreamHeartbeatInterval = 10 * time.Millisecond
+	reader := openStream(t, server, user.Username, account.ID, "")
+
+	requireBalanceEvent(t, readEvent(t, reader), account)
+	require.Equal(t, sseEvent{Comment: "heartbeat"}, readEvent(t, reader))
+}
+
+func TestStreamAccountAPIErrors(t *testing.T) {
+	user, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name          string
+		username      string
+		lastEventID   string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:        "InvalidLastEventID",
+			username:    user.Username,
+			lastEventID: "abc",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "NotFound",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "UnauthorizedUser",
+			username: user2.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetLastAccountEntryID(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "InternalError",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetLastAccountEntryID(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d/stream", account.ID), nil)
+			require.NoError(t, err)
+			if len(tc.lastEventID) > 0 {
+				request.Header.Set("Last-Event-ID", tc.lastEventID)
+			}
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestStreamAccountAPIShutdown(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(2).Return(account, nil)
+	store.EXPECT().GetLastAccountEntryID(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(int64(10), nil)
+	store.EXPECT().ListAccountEntriesAfter(gomock.Any(), gomock.Any()).Times(1).Return([]db.Entry{}, nil)
+
+	server := newTestServer(t, store)
+	reader := openStream(t, server, user.Username, account.ID, "")
+	requireBalanceEvent(t, readEvent(t, reader), account)
+
+	// 服务关闭时断开 SSE 连接，不等待到关闭超时
+	require.NoError(t, server.Shutdown(context.Background()))
+	_, err := reader.ReadString('\n')
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestStreamAccountAPIWriteTimeout(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(2).Return(account, nil)
+	store.EXPECT().GetLastAccountEntryID(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(int64(10), nil)
+	store.EXPECT().ListAccountEntriesAfter(gomock.Any(), gomock.Any()).Times(1).Return([]db.Entry{}, nil)
+
+	server := newTestServer(t, store)
+	server.config.StreamHeartbeatInterval = 50 * time.Millisecond
+
+	// SSE 连接在写超时之后仍然可以推送心跳
+	ts := httptest.NewUnstartedServer(server.router)
+	ts.Config.WriteTimeout = 20 * time.Millisecond
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/accounts/%d/stream", ts.URL, account.ID), nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, db.UserRoleCustomer, time.Minute)
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	reader := bufio.NewReader(response.Body)
+	requireBalanceEvent(t, readEvent(t, reader), account)
+	require.Equal(t, sseEvent{Comment: "heartbeat"}, readEvent(t, reader))
+	require.Equal(t, sseEvent{Comment: "heartbeat"}, readEvent(t, reader))
+}
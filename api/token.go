@@ -0,0 +1,92 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"simplebank/token"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type renewAccessTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type renewAccessTokenResponse struct {
+	AccessToken          string    `json:"access_token"`
+	AccessTokenExpiredAt time.Time `json:"access_token_expired_at"`
+}
+
+// renewAccessToken 使用 refresh token 换取新的 access token
+func (server *Server) renewAccessToken(ctx *gin.Context) {
+	var req renewAccessTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken)
+	if err != nil {
+		respondError(ctx, http.StatusUnauthorized, err)
+		return
+	}
+
+	if err := refreshPayload.VerifyType(token.TokenTypeRefresh); err != nil {
+		respondError(ctx, http.StatusUnauthorized, err)
+		return
+	}
+
+	session, err := server.store.GetSession(ctx, refreshPayload.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(ctx, http.StatusNotFound, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	if session.IsBlocked {
+		respondError(ctx, http.StatusUnauthorized, errSessionBlocked)
+		return
+	}
+
+	if session.Username != refreshPayload.Username {
+		respondError(ctx, http.StatusUnauthorized, errSessionUserMismatch)
+		return
+	}
+
+	if session.RefreshToken != req.RefreshToken {
+		respondError(ctx, http.StatusUnauthorized, errSessionTokenMismatch)
+		return
+	}
+
+	if time.Now().After(session.ExpiredAt) {
+		respondError(ctx, http.StatusUnauthorized, errSessionExpired)
+		return
+	}
+
+	// 使用用户当前的角色，角色变更后刷新的 access token 立即生效
+	user, err := server.store.GetUser(ctx, session.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(ctx, http.StatusNotFound, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, string(user.Role), token.TokenTypeAccess, server.config.AccessTokenDuartion)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := renewAccessTokenResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpiredAt: accessPayload.ExpiredAt,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
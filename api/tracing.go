@@ -0,0 +1,50 @@
+package api
+
+import (
+	"simplebank/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/semconv/v1.17.0/httpconv"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingMiddleware 为每个请求创建一个 span，请求头中带有 W3C traceparent 时作为上游 span 的子 span
+// span 写入请求的 context，handler 把 *gin.Context 传给 store 时查询的 span 也会关联到这个请求
+func tracingMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		route := ctx.FullPath()
+		spanName := ctx.Request.Method + " " + route
+		if len(route) == 0 {
+			spanName = ctx.Request.Method
+		}
+
+		parent := otel.GetTextMapPropagator().Extract(ctx.Request.Context(), propagation.HeaderCarrier(ctx.Request.Header))
+		spanCtx, span := tracing.Tracer().Start(parent, spanName,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethodKey.String(ctx.Request.Method),
+				semconv.HTTPRouteKey.String(route),
+				semconv.HTTPTargetKey.String(ctx.Request.URL.RequestURI()),
+				semconv.HTTPClientIPKey.String(ctx.ClientIP()),
+			),
+		)
+		defer span.End()
+
+		ctx.Request = ctx.Request.WithContext(spanCtx)
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+		if requestID := ctx.GetString(requestIDKey); len(requestID) > 0 {
+			span.SetAttributes(attribute.String(requestIDKey, requestID))
+		}
+		if len(ctx.Errors) > 0 {
+			span.RecordError(ctx.Errors.Last())
+		}
+		span.SetStatus(httpconv.ServerStatus(status))
+	}
+}
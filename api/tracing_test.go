@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	mockdb "simplebank/db/mock"
+	db "simplebank/db/sqlc"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans 把全局的 TracerProvider 替换为记录到内存的 provider，测试结束后恢复
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	originalProvider := otel.GetTracerProvider()
+	originalPropagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(originalProvider)
+		otel.SetTextMapPropagator(originalPropagator)
+	})
+	return recorder
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	// 上游服务的 span
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentSpanID := "00f067aa0ba902b7"
+	traceparent := fmt.Sprintf("00-%s-%s-01", traceID, parentSpanID)
+
+	testCases := []struct {
+		name       string
+		status     int
+		buildStubs func(store *mockdb.MockStore, storeSpan *trace.SpanContext)
+		checkSpan  func(t *testing.T, span sdktrace.ReadOnlySpan)
+	}{
+		{
+			name:   "OK",
+			status: http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore, storeSpan *trace.SpanContext) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, id int64) (db.Account, error) {
+						*storeSpan = trace.SpanContextFromContext(ctx)
+						return account, nil
+					})
+			},
+			checkSpan: func(t *testing.T, span sdktrace.ReadOnlySpan) {
+				require.Equal(t, codes.Unset, span.Status().Code)
+			},
+		},
+		{
+			name:   "InternalError",
+			status: http.StatusInternalServerError,
+			buildStubs: func(store *mockdb.MockStore, storeSpan *trace.SpanContext) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, id int64) (db.Account, error) {
+						*storeSpan = trace.SpanContextFromContext(ctx)
+						return db.Account{}, context.DeadlineExceeded
+					})
+			},
+			checkSpan: func(t *testing.T, span sdktrace.ReadOnlySpan) {
+				require.Equal(t, codes.Error, span.Status().Code)
+				require.Len(t, span.Events(), 1)
+				require.Equal(t, semconv.ExceptionEventName, span.Events()[0].Name)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var storeSpan trace.SpanContext
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store, &storeSpan)
+
+			server := newTestServer(t, store)
+			spans := recordSpans(t)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d", account.ID), nil)
+			require.NoError(t, err)
+			request.Header.Set("traceparent", traceparent)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.status, recorder.Code)
+
+			ended := spans.Ended()
+			require.Len(t, ended, 1)
+			span := ended[0]
+			require.Equal(t, "GET /accounts/:id", span.Name())
+			require.Equal(t, trace.SpanKindServer, span.SpanKind())
+			require.Equal(t, traceID, span.SpanContext().TraceID().String())
+			require.Equal(t, parentSpanID, span.Parent().SpanID().String())
+			require.True(t, span.Parent().IsRemote())
+
+			attributes := map[string]interface{}{}
+			for _, attribute := range span.Attributes() {
+				attributes[string(attribute.Key)] = attribute.Value.AsInterface()
+			}
+			require.Equal(t, int64(tc.status), attributes[string(semconv.HTTPStatusCodeKey)])
+			require.Equal(t, "/accounts/:id", attributes[string(semconv.HTTPRouteKey)])
+			require.Equal(t, recorder.Header().Get(requestIDHeaderKey), attributes[requestIDKey])
+
+			// store 收到的 context 中带有请求的 span
+			require.Equal(t, span.SpanContext().SpanID(), storeSpan.SpanID())
+			tc.checkSpan(t, span)
+		})
+	}
+}
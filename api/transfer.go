@@ -3,12 +3,12 @@ package api
 import (
 	"database/sql"
 	"errors"
-	"fmt"
 	"net/http"
 	db "simplebank/db/sqlc"
 	"simplebank/token"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type TransferRequest struct {
@@ -16,12 +16,16 @@ type TransferRequest struct {
 	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
 	Amount        int64  `json:"amount" binding:"required,gt=0"`
 	Currency      string `json:"currency" binding:"required,currency"`
+	// 跨币种转账时使用的汇率报价，Amount 与 Currency 为转出账户的币种
+	QuoteID string `json:"quote_id" binding:"omitempty,uuid"`
+	// 附言，记录在双方的流水中
+	Memo string `json:"memo" binding:"max=255"`
 }
 
 func (server *Server) createTransfer(ctx *gin.Context) {
 	var req TransferRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		respondError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
@@ -34,44 +38,127 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 	// 判断转账发起者是否为本人
 	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	if fromAccount.Owner != payload.Username {
-		err := errors.New("from account doesn't belon to the authencited user")
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		respondError(ctx, http.StatusUnauthorized, errAccountNotOwned)
 		return
 	}
 
-	_, valid = server.validCurrency(ctx, req.ToAccountID, req.Currency)
-	if !valid {
+	var result db.TransferTxResult
+	var err error
+	if len(req.QuoteID) == 0 {
+		_, valid = server.validCurrency(ctx, req.ToAccountID, req.Currency)
+		if !valid {
+			return
+		}
+
+		arg := db.TransferTxParams{
+			FromAccountId: req.FromAccountID,
+			ToAccountId:   req.ToAccountID,
+			Amount:        req.Amount,
+			Memo:          req.Memo,
+		}
+		result, err = server.store.TransferTx(ctx, arg)
+	} else {
+		// 转入账户的币种由报价决定，在事务中校验
+		arg := db.FXTransferTxParams{
+			FromAccountId: req.FromAccountID,
+			ToAccountId:   req.ToAccountID,
+			Amount:        req.Amount,
+			QuoteID:       uuid.MustParse(req.QuoteID),
+			Memo:          req.Memo,
+		}
+		result, err = server.store.FXTransferTx(ctx, arg)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			respondError(ctx, http.StatusNotFound, err)
+		case errors.Is(err, db.ErrInsufficientFunds),
+			errors.Is(err, db.ErrInvalidQuote),
+			errors.Is(err, db.ErrAccountNotActive):
+			respondError(ctx, http.StatusUnprocessableEntity, err)
+		default:
+			respondError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+type listTransfersResponse struct {
+	Transfers  []db.Transfer `json:"transfers"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+func (server *Server) listTransfers(ctx *gin.Context) {
+	var uri accountURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	arg := db.TransferTxParams{
-		FromAccountId: req.FromAccountID,
-		ToAccountId:   req.ToAccountID,
-		Amount:        req.Amount,
+	var req listHistoryRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
 	}
-	result, err := server.store.TransferTx(ctx, arg)
+
+	filter, err := req.filter()
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, result)
+	// 只能查看自己账户的转账记录
+	if _, valid := server.validAccountOwner(ctx, uri.ID); !valid {
+		return
+	}
+
+	server.respondAccountTransfers(ctx, uri.ID, req.PageSize, filter)
+}
+
+// respondAccountTransfers 按查询条件返回账户的一页转账记录，调用方负责权限检查
+func (server *Server) respondAccountTransfers(ctx *gin.Context, accountID int64, pageSize int32, filter historyFilter) {
+	transfers, err := server.store.ListAccountTransfers(ctx, db.ListAccountTransfersParams{
+		AccountID:       accountID,
+		Direction:       filter.Direction,
+		StartTime:       filter.StartTime,
+		EndTime:         filter.EndTime,
+		MinAmount:       filter.MinAmount,
+		MaxAmount:       filter.MaxAmount,
+		CursorCreatedAt: filter.CursorCreatedAt,
+		CursorID:        filter.CursorID,
+		Limit:           filter.Limit,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := listTransfersResponse{Transfers: transfers}
+	if len(transfers) > int(pageSize) {
+		rsp.Transfers = transfers[:pageSize]
+		last := rsp.Transfers[len(rsp.Transfers)-1]
+		rsp.NextCursor = encodeCursor(historyCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
 }
 
 func (server *Server) validCurrency(ctx *gin.Context, accountId int64, currency string) (db.Account, bool) {
 	account, err := server.store.GetAccount(ctx, accountId)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			respondError(ctx, http.StatusNotFound, err)
 			return account, false
 		}
 
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, http.StatusInternalServerError, err)
 		return account, false
 	}
 
 	if account.Currency != currency {
-		ctx.JSON(http.StatusBadRequest, fmt.Sprintf("account [%d] currency mismatch: [%v] vs [%v]", accountId, account.Currency, currency))
+		respondError(ctx, http.StatusBadRequest, newCodedError(errCodeCurrencyMismatch, accountId, account.Currency, currency))
 		return account, false
 	}
 
@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	mockdb "simplebank/db/mock"
 	db "simplebank/db/sqlc"
 	"simplebank/token"
@@ -17,12 +18,14 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
 func TestCreateTransfer(t *testing.T) {
 	user, _ := randomUser(t)
 	transferTxResult := randomTransferTxResult(t, user.Username)
+	quoteID := uuid.New()
 
 	// 全部测试案例
 	testCases := []struct {
@@ -39,9 +42,10 @@ func TestCreateTransfer(t *testing.T) {
 				"to_account_id":   transferTxResult.ToAccount.ID,
 				"amount":          transferTxResult.Transfer.Amount,
 				"currency":        utils.RMB,
+				"memo":            "dinner",
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -58,6 +62,7 @@ func TestCreateTransfer(t *testing.T) {
 					FromAccountId: transferTxResult.Transfer.FromAccountID,
 					ToAccountId:   transferTxResult.Transfer.ToAccountID,
 					Amount:        transferTxResult.Transfer.Amount,
+					Memo:          "dinner",
 				}
 
 				store.EXPECT().
@@ -79,7 +84,7 @@ func TestCreateTransfer(t *testing.T) {
 				"currency":        utils.RMB,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized_user", time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized_user", db.UserRoleCustomer, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -109,7 +114,7 @@ func TestCreateTransfer(t *testing.T) {
 				"currency":        utils.RMB,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -137,7 +142,7 @@ func TestCreateTransfer(t *testing.T) {
 				"currency":        utils.USD,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -166,7 +171,7 @@ func TestCreateTransfer(t *testing.T) {
 				"currency":        utils.RMB,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -195,7 +200,7 @@ func TestCreateTransfer(t *testing.T) {
 				"currency":        utils.RMB,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -224,7 +229,7 @@ func TestCreateTransfer(t *testing.T) {
 				"currency":        utils.RMB,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -254,7 +259,7 @@ func TestCreateTransfer(t *testing.T) {
 				"currency":        utils.RMB,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -282,6 +287,178 @@ func TestCreateTransfer(t *testing.T) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
+		{
+			name: "InsufficientFunds",
+			body: gin.H{
+				"from_account_id": transferTxResult.FromAccount.ID,
+				"to_account_id":   transferTxResult.ToAccount.ID,
+				"amount":          transferTxResult.Transfer.Amount,
+				"currency":        utils.RMB,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(transferTxResult.FromAccount.ID)).
+					Times(1).
+					Return(transferTxResult.FromAccount, nil)
+
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(transferTxResult.ToAccount.ID)).
+					Times(1).
+					Return(transferTxResult.ToAccount, nil)
+
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, fmt.Errorf("%w: account [%d]", db.ErrInsufficientFunds, transferTxResult.FromAccount.ID))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+				var body gin.H
+				err := json.Unmarshal(recorder.Body.Bytes(), &body)
+				require.NoError(t, err)
+				require.Equal(t, errCodeInsufficientFunds, body["code"])
+			},
+		},
+		{
+			name: "AccountNotActive",
+			body: gin.H{
+				"from_account_id": transferTxResult.FromAccount.ID,
+				"to_account_id":   transferTxResult.ToAccount.ID,
+				"amount":          transferTxResult.Transfer.Amount,
+				"currency":        utils.RMB,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(transferTxResult.FromAccount.ID)).
+					Times(1).
+					Return(transferTxResult.FromAccount, nil)
+
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(transferTxResult.ToAccount.ID)).
+					Times(1).
+					Return(transferTxResult.ToAccount, nil)
+
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, fmt.Errorf("%w: account [%d]", db.ErrAccountNotActive, transferTxResult.ToAccount.ID))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+				var body gin.H
+				err := json.Unmarshal(recorder.Body.Bytes(), &body)
+				require.NoError(t, err)
+				require.Equal(t, errCodeAccountNotActive, body["code"])
+			},
+		},
+		{
+			name: "FXTransfer",
+			body: gin.H{
+				"from_account_id": transferTxResult.FromAccount.ID,
+				"to_account_id":   transferTxResult.ToAccount.ID,
+				"amount":          transferTxResult.Transfer.Amount,
+				"currency":        utils.RMB,
+				"quote_id":        quoteID.String(),
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(transferTxResult.FromAccount.ID)).
+					Times(1).
+					Return(transferTxResult.FromAccount, nil)
+
+				// 转入账户币种由报价校验，不再单独查询
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(transferTxResult.ToAccount.ID)).
+					Times(0)
+
+				arg := db.FXTransferTxParams{
+					FromAccountId: transferTxResult.Transfer.FromAccountID,
+					ToAccountId:   transferTxResult.Transfer.ToAccountID,
+					Amount:        transferTxResult.Transfer.Amount,
+					QuoteID:       quoteID,
+				}
+				store.EXPECT().
+					FXTransferTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(transferTxResult, nil)
+
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchTransferTxResult(t, recorder.Body, transferTxResult)
+			},
+		},
+		{
+			name: "InvalidQuote",
+			body: gin.H{
+				"from_account_id": transferTxResult.FromAccount.ID,
+				"to_account_id":   transferTxResult.ToAccount.ID,
+				"amount":          transferTxResult.Transfer.Amount,
+				"currency":        utils.RMB,
+				"quote_id":        quoteID.String(),
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(transferTxResult.FromAccount.ID)).
+					Times(1).
+					Return(transferTxResult.FromAccount, nil)
+
+				store.EXPECT().
+					FXTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, fmt.Errorf("%w: quote [%s] expired", db.ErrInvalidQuote, quoteID))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+				var body gin.H
+				err := json.Unmarshal(recorder.Body.Bytes(), &body)
+				require.NoError(t, err)
+				require.Equal(t, errCodeInvalidQuote, body["code"])
+			},
+		},
+		{
+			name: "MalformedQuoteID",
+			body: gin.H{
+				"from_account_id": transferTxResult.FromAccount.ID,
+				"to_account_id":   transferTxResult.ToAccount.ID,
+				"amount":          transferTxResult.Transfer.Amount,
+				"currency":        utils.RMB,
+				"quote_id":        "not-a-uuid",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				store.EXPECT().
+					FXTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -358,3 +535,146 @@ func requireBodyMatchTransferTxResult(t *testing.T, body *bytes.Buffer, transfer
 	require.NoError(t, err)
 	require.Equal(t, transferTxResult, gottransferTxResult)
 }
+
+func TestListTransfersAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	n := 6
+	pageSize := 5
+	transfers := make([]db.Transfer, n)
+	for i := 0; i < n; i++ {
+		transfers[i] = db.Transfer{
+			ID:            utils.RandomInt(1, 1000),
+			FromAccountID: account.ID,
+			ToAccountID:   utils.RandomInt(1, 1000),
+			Amount:        utils.RandomMoney(),
+			CreatedAt:     time.Now().UTC().Truncate(time.Microsecond),
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: url.Values{"page_size": {"5"}, "direction": {"incoming"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+
+				arg := db.ListAccountTransfersParams{
+					AccountID: account.ID,
+					Direction: sql.NullString{String: "incoming", Valid: true},
+					Limit:     int32(pageSize + 1),
+				}
+				store.EXPECT().
+					ListAccountTransfers(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(transfers, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp listTransfersResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, transfers[:pageSize], rsp.Transfers)
+
+				cursor, err := decodeCursor(rsp.NextCursor)
+				require.NoError(t, err)
+				require.Equal(t, transfers[pageSize-1].ID, cursor.ID)
+			},
+		},
+		{
+			name:  "UnauthorizedUser",
+			query: url.Values{"page_size": {"5"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized_user", db.UserRoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+
+				store.EXPECT().
+					ListAccountTransfers(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidTimeRange",
+			query: url.Values{"page_size": {"5"}, "start_time": {"2023-03-02T00:00:00Z"}, "end_time": {"2023-03-01T00:00:00Z"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				store.EXPECT().
+					ListAccountTransfers(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: url.Values{"page_size": {"5"}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+
+				store.EXPECT().
+					ListAccountTransfers(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Transfer{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/transfers?%s", account.ID, tc.query.Encode())
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
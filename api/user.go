@@ -2,14 +2,15 @@ package api
 
 import (
 	"database/sql"
-	"errors"
-	"fmt"
+	"io"
 	"net/http"
 	db "simplebank/db/sqlc"
+	"simplebank/token"
 	"simplebank/utils"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/lib/pq"
 )
 
@@ -22,6 +23,7 @@ type createUserRequest struct {
 
 type userResponse struct {
 	Username          string       `json:"username"`
+	Role              db.UserRole  `json:"role"`
 	FullName          string       `json:"full_name"`
 	Email             string       `json:"email"`
 	PasswordChangedAt sql.NullTime `json:"password_changed_at"`
@@ -31,6 +33,7 @@ type userResponse struct {
 func newUserResponse(user db.User) userResponse {
 	return userResponse{
 		Username:          user.Username,
+		Role:              user.Role,
 		FullName:          user.FullName,
 		Email:             user.Email,
 		CreatedAt:         user.CreatedAt,
@@ -41,13 +44,13 @@ func newUserResponse(user db.User) userResponse {
 func (server *Server) createUser(ctx *gin.Context) {
 	var req createUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		respondError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -58,17 +61,16 @@ func (server *Server) createUser(ctx *gin.Context) {
 		Email:          req.Email,
 	}
 
-	user, err := server.store.CreateUser(ctx, arg)
+	user, err := server.store.CreateUserTx(ctx, arg)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
-			fmt.Println(pqErr.Code.Name())
 			switch pqErr.Code.Name() {
 			case "unique_violation":
-				ctx.JSON(http.StatusForbidden, errorResponse(err))
+				respondError(ctx, http.StatusForbidden, err)
 				return
 			}
 		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -82,42 +84,161 @@ type loginUserRequest struct {
 }
 
 type loginUserResponse struct {
-	AccessToken string       `json:"access_token"`
-	User        userResponse `json:"user"`
+	SessionID             uuid.UUID    `json:"session_id"`
+	AccessToken           string       `json:"access_token"`
+	AccessTokenExpiredAt  time.Time    `json:"access_token_expired_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiredAt time.Time    `json:"refresh_token_expired_at"`
+	User                  userResponse `json:"user"`
 }
 
 func (server *Server) loginUser(ctx *gin.Context) {
 	var req loginUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		respondError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
 	user, err := server.store.GetUser(ctx, req.Username)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("用户名不存在")))
+			respondError(ctx, http.StatusNotFound, errUserNotFound)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
 	err = utils.CheckPassword(req.Password, user.HashedPassword)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("密码错误")))
+		respondError(ctx, http.StatusUnauthorized, errIncorrectPassword)
 		return
 	}
 
-	token, err := server.tokenMaker.CreateToken(req.Username, server.config.AccessTokenDuartion)
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, string(user.Role), token.TokenTypeAccess, server.config.AccessTokenDuartion)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(user.Username, string(user.Role), token.TokenTypeRefresh, server.config.RefreshTokenDuration)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	// 保存 refresh token 对应的会话，会话被封禁后 refresh token 立即失效
+	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
+		ID:           refreshPayload.ID,
+		Username:     user.Username,
+		RefreshToken: refreshToken,
+		UserAgent:    ctx.Request.UserAgent(),
+		ClientIp:     ctx.ClientIP(),
+		IsBlocked:    false,
+		ExpiredAt:    refreshPayload.ExpiredAt,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
 	rsp := loginUserResponse{
-		AccessToken: token,
-		User:        newUserResponse(user),
+		SessionID:             session.ID,
+		AccessToken:           accessToken,
+		AccessTokenExpiredAt:  accessPayload.ExpiredAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiredAt: refreshPayload.ExpiredAt,
+		User:                  newUserResponse(user),
 	}
 	ctx.JSON(http.StatusOK, rsp)
 }
+
+type logoutUserRequest struct {
+	// 同时封禁 refresh token 对应的会话，避免继续刷新 access token
+	RefreshToken string `json:"refresh_token"`
+}
+
+// logoutUser 吊销当前的 access token
+func (server *Server) logoutUser(ctx *gin.Context) {
+	// 请求体可以为空
+	var req logoutUserRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil && err != io.EOF {
+			respondError(ctx, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var refreshPayload *token.Payload
+	if len(req.RefreshToken) > 0 {
+		var err error
+		refreshPayload, err = server.tokenMaker.VerifyToken(req.RefreshToken)
+		if err != nil {
+			respondError(ctx, http.StatusUnauthorized, err)
+			return
+		}
+		if err = refreshPayload.VerifyType(token.TokenTypeRefresh); err != nil {
+			respondError(ctx, http.StatusUnauthorized, err)
+			return
+		}
+
+		if refreshPayload.Username != payload.Username {
+			respondError(ctx, http.StatusUnauthorized, errRefreshTokenNotOwned)
+			return
+		}
+	}
+
+	err := server.store.CreateRevokedToken(ctx, db.CreateRevokedTokenParams{
+		ID:        payload.ID,
+		Username:  payload.Username,
+		ExpiredAt: payload.ExpiredAt,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	server.revocations.revokeToken(payload)
+
+	if refreshPayload != nil {
+		_, err = server.store.BlockSession(ctx, db.BlockSessionParams{
+			ID:       refreshPayload.ID,
+			Username: payload.Username,
+		})
+		if err != nil && err != sql.ErrNoRows {
+			respondError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// logoutAllUser 吊销当前用户此前签发的全部 token，并封禁全部会话
+func (server *Server) logoutAllUser(ctx *gin.Context) {
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	revokedAt := time.Now()
+	_, err := server.store.RevokeUserTokens(ctx, db.RevokeUserTokensParams{
+		Username:        payload.Username,
+		TokensRevokedAt: sql.NullTime{Time: revokedAt, Valid: true},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(ctx, http.StatusNotFound, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	server.revocations.revokeUser(payload.Username, revokedAt)
+
+	err = server.store.BlockUserSessions(ctx, payload.Username)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -2,17 +2,21 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	mockdb "simplebank/db/mock"
 	db "simplebank/db/sqlc"
+	"simplebank/token"
 	"simplebank/utils"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
@@ -76,7 +80,7 @@ func TestCreateUserAPI(t *testing.T) {
 				}
 
 				store.EXPECT().
-					CreateUser(gomock.Any(), EqCreateUser(arg, password)).
+					CreateUserTx(gomock.Any(), EqCreateUser(arg, password)).
 					Times(1).
 					Return(user, nil)
 			},
@@ -95,7 +99,7 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -118,7 +122,7 @@ func TestCreateUserAPI(t *testing.T) {
 				}
 
 				store.EXPECT().
-					CreateUser(gomock.Any(), EqCreateUser(arg, password)).
+					CreateUserTx(gomock.Any(), EqCreateUser(arg, password)).
 					Times(1).
 					Return(db.User{}, sql.ErrConnDone)
 			},
@@ -142,7 +146,7 @@ func TestCreateUserAPI(t *testing.T) {
 				}
 
 				store.EXPECT().
-					CreateUser(gomock.Any(), EqCreateUser(arg, password)).
+					CreateUserTx(gomock.Any(), EqCreateUser(arg, password)).
 					Times(1).
 					Return(db.User{}, &pq.Error{Code: "23505"})
 			},
@@ -201,12 +205,51 @@ func TestLoginUserAPI(t *testing.T) {
 					GetUser(gomock.Any(), gomock.Eq(user.Username)).
 					Times(1).
 					Return(user, nil)
+
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateSessionParams) (db.Session, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NotEmpty(t, arg.RefreshToken)
+						require.Equal(t, "simplebank-test", arg.UserAgent)
+						require.False(t, arg.IsBlocked)
+						return db.Session{
+							ID:           arg.ID,
+							Username:     arg.Username,
+							RefreshToken: arg.RefreshToken,
+							UserAgent:    arg.UserAgent,
+							ClientIp:     arg.ClientIp,
+							ExpiredAt:    arg.ExpiredAt,
+						}, nil
+					})
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
 				requireBodyMatchLoginUser(t, recorder.Body, user)
 			},
 		},
+		{
+			name: "CreateSessionInternalError",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
 		{
 			name: "BadRequest",
 			body: gin.H{
@@ -292,6 +335,7 @@ func TestLoginUserAPI(t *testing.T) {
 
 			request, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(data))
 			require.NoError(t, err)
+			request.Header.Set("User-Agent", "simplebank-test")
 
 			server.router.ServeHTTP(recorder, request)
 
@@ -310,6 +354,7 @@ func randomUser(t *testing.T) (db.User, string) {
 		HashedPassword: hashedPassword,
 		FullName:       utils.RandomOwner(),
 		Email:          utils.RandomEmail(),
+		Role:           db.UserRoleCustomer,
 	}, password
 }
 
@@ -339,4 +384,280 @@ func requireBodyMatchLoginUser(t *testing.T, body *bytes.Buffer, user db.User) {
 	require.Equal(t, user.CreatedAt, gotLoginUser.User.CreatedAt)
 	require.Equal(t, user.PasswordChangedAt, gotLoginUser.User.PasswordChangedAt)
 	require.NotEmpty(t, gotLoginUser.AccessToken)
+	require.NotEmpty(t, gotLoginUser.RefreshToken)
+	require.NotZero(t, gotLoginUser.SessionID)
+}
+
+func TestLogoutUserAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          func(t *testing.T, tokenMaker token.Maker) gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return nil
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateRevokedToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateRevokedTokenParams) error {
+						require.Equal(t, user.Username, arg.Username)
+						require.NotZero(t, arg.ID)
+						return nil
+					})
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name: "BlockSession",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				refreshToken, _, err := tokenMaker.CreateToken(user.Username, string(user.Role), token.TokenTypeRefresh, time.Hour)
+				require.NoError(t, err)
+				return gin.H{"refresh_token": refreshToken}
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateRevokedToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.BlockSessionParams) (db.Session, error) {
+						require.Equal(t, user.Username, arg.Username)
+						return db.Session{ID: arg.ID, Username: arg.Username, IsBlocked: true}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name: "RefreshTokenOfOtherUser",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				refreshToken, _, err := tokenMaker.CreateToken("other_user", string(db.UserRoleCustomer), token.TokenTypeRefresh, time.Hour)
+				require.NoError(t, err)
+				return gin.H{"refresh_token": refreshToken}
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateRevokedToken(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return nil
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateRevokedToken(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return nil
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateRevokedToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		// 每个测试案例使用子测试运行
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body io.Reader = http.NoBody
+			if data := tc.body(t, server.tokenMaker); data != nil {
+				raw, err := json.Marshal(data)
+				require.NoError(t, err)
+				body = bytes.NewReader(raw)
+			}
+
+			request, err := http.NewRequest(http.MethodPost, "/users/logout", body)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLogoutAllUserAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeUserTokens(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.RevokeUserTokensParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.True(t, arg.TokensRevokedAt.Valid)
+						require.WithinDuration(t, time.Now(), arg.TokensRevokedAt.Time, time.Second)
+						return user, nil
+					})
+				store.EXPECT().
+					BlockUserSessions(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name: "UserNotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeUserTokens(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().
+					BlockUserSessions(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "BlockSessionsInternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeUserTokens(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					BlockUserSessions(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		// 每个测试案例使用子测试运行
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/users/logout_all", nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// 吊销后的 token 无法再通过认证
+func TestRevokedTokenRejected(t *testing.T) {
+	user, _ := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		CreateRevokedToken(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil)
+	store.EXPECT().
+		RevokeUserTokens(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(user, nil)
+	store.EXPECT().
+		BlockUserSessions(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil)
+
+	server := newTestServer(t, store)
+	accessToken, _, err := server.tokenMaker.CreateToken(user.Username, string(user.Role), token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+	otherToken, _, err := server.tokenMaker.CreateToken(user.Username, string(user.Role), token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	send := func(url, accessToken string) int {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, url, nil)
+		require.NoError(t, err)
+		request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+		server.router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	require.Equal(t, http.StatusNoContent, send("/users/logout", accessToken))
+	require.Equal(t, http.StatusUnauthorized, send("/users/logout", accessToken))
+
+	// 退出全部登录后，之前签发的其他 token 也失效
+	require.Equal(t, http.StatusNoContent, send("/users/logout_all", otherToken))
+	require.Equal(t, http.StatusUnauthorized, send("/users/logout_all", otherToken))
 }
@@ -2,6 +2,7 @@ package api
 
 import (
 	"simplebank/utils"
+	"simplebank/webhook"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -13,3 +14,20 @@ var validCurrency validator.Func = func(fl validator.FieldLevel) bool {
 	}
 	return false
 }
+
+var validCronExpr validator.Func = func(fl validator.FieldLevel) bool {
+	if expr, ok := fl.Field().Interface().(string); ok {
+		_, err := utils.ParseCronExpr(expr)
+		return err == nil
+	}
+	return false
+}
+
+// webhook 地址必须是带 host 的 http/https 绝对地址，不能指向本机或内网地址
+// 生产环境是否要求 https 取决于配置，由 createWebhook 检查
+var validWebhookURL validator.Func = func(fl validator.FieldLevel) bool {
+	if rawURL, ok := fl.Field().Interface().(string); ok {
+		return webhook.ValidateURL(rawURL, false) == nil
+	}
+	return false
+}
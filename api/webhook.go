@@ -0,0 +1,183 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	db "simplebank/db/sqlc"
+	"simplebank/logger"
+	"simplebank/token"
+	"simplebank/webhook"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createWebhookRequest struct {
+	URL string `json:"url" binding:"required,max=2048,webhook_url"`
+}
+
+// 签名密钥只在创建时返回一次
+type createWebhookResponse struct {
+	webhookResponse
+	Secret string `json:"secret"`
+}
+
+type webhookResponse struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newWebhookResponse(hook db.Webhook) webhookResponse {
+	return webhookResponse{
+		ID:        hook.ID,
+		URL:       hook.Url,
+		CreatedAt: hook.CreatedAt,
+	}
+}
+
+// createWebhook 订阅当前用户相关的账本事件，事件请求使用返回的密钥签名
+func (server *Server) createWebhook(ctx *gin.Context) {
+	var req createWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	// 生产环境中事件不能明文传输
+	if server.config.Environment == logger.EnvironmentProduction && webhook.ValidateURL(req.URL, true) != nil {
+		respondError(ctx, http.StatusBadRequest, errWebhookURLNotHTTPS)
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	hook, err := server.store.CreateWebhook(ctx, db.CreateWebhookParams{
+		Owner:  payload.Username,
+		Url:    req.URL,
+		Secret: secret,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createWebhookResponse{
+		webhookResponse: newWebhookResponse(hook),
+		Secret:          hook.Secret,
+	})
+}
+
+func (server *Server) listWebhooks(ctx *gin.Context) {
+	var req listAccountRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	hooks, err := server.store.ListWebhooks(ctx, db.ListWebhooksParams{
+		Owner:  payload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := make([]webhookResponse, 0, len(hooks))
+	for _, hook := range hooks {
+		rsp = append(rsp, newWebhookResponse(hook))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type webhookURIRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// deleteWebhook 删除订阅，尚未投递的事件一并丢弃
+func (server *Server) deleteWebhook(ctx *gin.Context) {
+	var uri webhookURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, valid := server.validWebhookOwner(ctx, uri.ID); !valid {
+		return
+	}
+
+	if err := server.store.DeleteWebhook(ctx, uri.ID); err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type listWebhookDeliveriesRequest struct {
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=10"`
+	Status   string `form:"status" binding:"omitempty,oneof=pending succeeded dead"`
+}
+
+// listWebhookDeliveries 查询投递记录，status=dead 即为死信列表
+func (server *Server) listWebhookDeliveries(ctx *gin.Context) {
+	var uri webhookURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var req listWebhookDeliveriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, valid := server.validWebhookOwner(ctx, uri.ID); !valid {
+		return
+	}
+
+	deliveries, err := server.store.ListWebhookDeliveries(ctx, db.ListWebhookDeliveriesParams{
+		WebhookID: uri.ID,
+		Status:    sql.NullString{String: req.Status, Valid: len(req.Status) > 0},
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+// 只能查看或删除自己创建的 webhook
+func (server *Server) validWebhookOwner(ctx *gin.Context, id int64) (db.Webhook, bool) {
+	hook, err := server.store.GetWebhook(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondError(ctx, http.StatusNotFound, err)
+			return hook, false
+		}
+
+		respondError(ctx, http.StatusInternalServerError, err)
+		return hook, false
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if hook.Owner != payload.Username {
+		respondError(ctx, http.StatusUnauthorized, errWebhookNotOwned)
+		return hook, false
+	}
+
+	return hook, true
+}
@@ -0,0 +1,380 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	mockdb "simplebank/db/mock"
+	db "simplebank/db/sqlc"
+	"simplebank/logger"
+	"simplebank/utils"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func randomWebhook(owner string) db.Webhook {
+	return db.Webhook{
+		ID:        utils.RandomInt(1, 1000),
+		Owner:     owner,
+		Url:       "https://example.com/hooks/" + utils.RandomString(6),
+		Secret:    utils.RandomString(64),
+		CreatedAt: time.Now().Truncate(time.Second).UTC(),
+	}
+}
+
+func TestCreateWebhookAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	hook := randomWebhook(user.Username)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"url": hook.Url},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateWebhook(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateWebhookParams) (db.Webhook, error) {
+						require.Equal(t, user.Username, arg.Owner)
+						require.Equal(t, hook.Url, arg.Url)
+						// 每个 webhook 使用随机生成的密钥
+						require.Len(t, arg.Secret, 64)
+						return hook, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp createWebhookResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, hook.ID, rsp.ID)
+				require.Equal(t, hook.Url, rsp.URL)
+				require.Equal(t, hook.Secret, rsp.Secret)
+			},
+		},
+		{
+			name: "InvalidScheme",
+			body: gin.H{"url": "ftp://example.com/hooks"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhook(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "RelativeURL",
+			body: gin.H{"url": "/hooks"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhook(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "LoopbackAddress",
+			body: gin.H{"url": "http://127.0.0.1:8080/hooks"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhook(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "LinkLocalAddress",
+			body: gin.H{"url": "http://169.254.169.254/latest/meta-data"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhook(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"url": hook.Url},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateWebhook(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Webhook{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateWebhookAPIRequiresHTTPSInProduction(t *testing.T) {
+	user, _ := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().CreateWebhook(gomock.Any(), gomock.Any()).Times(0)
+
+	config := utils.Config{
+		Environment:         logger.EnvironmentProduction,
+		TokenSymmetricKey:   utils.RandomString(32),
+		AccessTokenDuartion: time.Minute,
+	}
+	server, err := NewServer(config, store)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(gin.H{"url": "http://example.com/hooks"})
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	requireBodyMatchErrorCode(t, recorder.Body, errCodeWebhookURLNotHTTPS)
+}
+
+func TestListWebhooksAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	hooks := []db.Webhook{randomWebhook(user.Username), randomWebhook(user.Username)}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListWebhooks(gomock.Any(), gomock.Eq(db.ListWebhooksParams{
+			Owner:  user.Username,
+			Limit:  5,
+			Offset: 5,
+		})).
+		Times(1).
+		Return(hooks, nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/webhooks?page_id=2&page_size=5", nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// 列表中不返回签名密钥
+	require.NotContains(t, recorder.Body.String(), "secret")
+
+	var gotHooks []webhookResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &gotHooks)
+	require.NoError(t, err)
+	require.Len(t, gotHooks, len(hooks))
+	for i, hook := range hooks {
+		require.Equal(t, newWebhookResponse(hook), gotHooks[i])
+	}
+}
+
+func TestDeleteWebhookAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	hook := randomWebhook(user.Username)
+
+	testCases := []struct {
+		name          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(hook.ID)).Times(1).Return(hook, nil)
+				store.EXPECT().DeleteWebhook(gomock.Any(), gomock.Eq(hook.ID)).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name:     "NotFound",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(hook.ID)).Times(1).Return(db.Webhook{}, sql.ErrNoRows)
+				store.EXPECT().DeleteWebhook(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "UnauthorizedUser",
+			username: user2.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(hook.ID)).Times(1).Return(hook, nil)
+				store.EXPECT().DeleteWebhook(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/webhooks/%d", hook.ID), nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListWebhookDeliveriesAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	hook := randomWebhook(user.Username)
+
+	deliveries := []db.WebhookDelivery{
+		{
+			ID:            3,
+			WebhookID:     hook.ID,
+			EventID:       9,
+			Status:        db.DeliveryStatusDead,
+			Attempts:      8,
+			NextAttemptAt: time.Now().Truncate(time.Second).UTC(),
+			LastError:     "unexpected status code: 500",
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "DeadLetters",
+			query: "page_id=1&page_size=5&status=dead",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(hook.ID)).Times(1).Return(hook, nil)
+				store.EXPECT().
+					ListWebhookDeliveries(gomock.Any(), gomock.Eq(db.ListWebhookDeliveriesParams{
+						WebhookID: hook.ID,
+						Status:    sql.NullString{String: string(db.DeliveryStatusDead), Valid: true},
+						Limit:     5,
+						Offset:    0,
+					})).
+					Times(1).
+					Return(deliveries, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var gotDeliveries []db.WebhookDelivery
+				err := json.Unmarshal(recorder.Body.Bytes(), &gotDeliveries)
+				require.NoError(t, err)
+				require.Equal(t, deliveries, gotDeliveries)
+			},
+		},
+		{
+			name:  "AllStatus",
+			query: "page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(hook.ID)).Times(1).Return(hook, nil)
+				store.EXPECT().
+					ListWebhookDeliveries(gomock.Any(), gomock.Eq(db.ListWebhookDeliveriesParams{
+						WebhookID: hook.ID,
+						Limit:     5,
+						Offset:    0,
+					})).
+					Times(1).
+					Return(deliveries, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidStatus",
+			query: "page_id=1&page_size=5&status=failed",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListWebhookDeliveries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/webhooks/%d/deliveries?%s", hook.ID, tc.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,16 @@
+CREATE TABLE "idempotency_keys" (
+  "username" varchar NOT NULL,
+  "idempotency_key" varchar NOT NULL,
+  "request_hash" varchar NOT NULL,
+  "response_code" int,
+  "response_body" bytea,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "expired_at" timestamptz NOT NULL,
+  PRIMARY KEY ("username", "idempotency_key")
+);
+
+COMMENT ON COLUMN "idempotency_keys"."request_hash" IS '请求内容摘要，同一个 key 只能用于相同的请求';
+
+COMMENT ON COLUMN "idempotency_keys"."response_code" IS '为空表示请求仍在处理中';
+
+ALTER TABLE "idempotency_keys" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
@@ -0,0 +1,9 @@
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "fx_quote_id";
+
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "exchange_rate";
+
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "to_amount";
+
+DROP TABLE IF EXISTS "fx_quotes";
+
+DROP TABLE IF EXISTS "exchange_rates";
@@ -0,0 +1,46 @@
+CREATE TABLE "exchange_rates" (
+  "base_currency" varchar NOT NULL,
+  "quote_currency" varchar NOT NULL,
+  "rate" bigint NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("base_currency", "quote_currency")
+);
+
+CREATE TABLE "fx_quotes" (
+  "id" uuid PRIMARY KEY DEFAULT (gen_random_uuid()),
+  "username" varchar NOT NULL,
+  "from_currency" varchar NOT NULL,
+  "to_currency" varchar NOT NULL,
+  "rate" bigint NOT NULL,
+  "used_at" timestamptz DEFAULT null,
+  "expired_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "fx_quotes" ("username");
+
+ALTER TABLE "exchange_rates" ADD CONSTRAINT "exchange_rate_check" CHECK ("rate" > 0);
+
+ALTER TABLE "fx_quotes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "transfers" ADD COLUMN "to_amount" bigint;
+
+ALTER TABLE "transfers" ADD COLUMN "exchange_rate" bigint NOT NULL DEFAULT 100000000;
+
+UPDATE "transfers" SET "to_amount" = "amount";
+
+ALTER TABLE "transfers" ALTER COLUMN "to_amount" SET NOT NULL;
+
+ALTER TABLE "transfers" ADD COLUMN "fx_quote_id" uuid;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("fx_quote_id") REFERENCES "fx_quotes" ("id");
+
+COMMENT ON COLUMN "exchange_rates"."rate" IS '1 单位 base_currency 可兑换的 quote_currency 数量 × 10^8';
+
+COMMENT ON COLUMN "fx_quotes"."rate" IS '锁定的汇率 × 10^8';
+
+COMMENT ON COLUMN "fx_quotes"."used_at" IS '报价只能使用一次';
+
+COMMENT ON COLUMN "transfers"."to_amount" IS '转入账户入账金额（转入账户币种）';
+
+COMMENT ON COLUMN "transfers"."exchange_rate" IS '汇率 × 10^8，同币种转账为 1';
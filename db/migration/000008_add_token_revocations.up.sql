@@ -0,0 +1,18 @@
+CREATE TABLE "revoked_tokens" (
+  "id" uuid PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "expired_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "revoked_tokens" ("expired_at");
+
+COMMENT ON COLUMN "revoked_tokens"."id" IS 'token payload ID';
+
+COMMENT ON COLUMN "revoked_tokens"."expired_at" IS 'token 过期后无需再记录';
+
+ALTER TABLE "revoked_tokens" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "users" ADD COLUMN "tokens_revoked_at" timestamptz DEFAULT null;
+
+COMMENT ON COLUMN "users"."tokens_revoked_at" IS '在此时间之前签发的 token 全部失效';
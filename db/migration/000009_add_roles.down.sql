@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS "audit_logs";
+
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "status";
+
+DROP TYPE IF EXISTS "account_status";
+
+ALTER TABLE IF EXISTS "users" DROP COLUMN IF EXISTS "role";
+
+DROP TYPE IF EXISTS "user_role";
@@ -0,0 +1,39 @@
+CREATE TYPE "user_role" AS ENUM (
+  'customer',
+  'support',
+  'admin',
+  'auditor'
+);
+
+ALTER TABLE "users" ADD COLUMN "role" user_role NOT NULL DEFAULT 'customer';
+
+CREATE TYPE "account_status" AS ENUM (
+  'active',
+  'frozen'
+);
+
+ALTER TABLE "accounts" ADD COLUMN "status" account_status NOT NULL DEFAULT 'active';
+
+COMMENT ON COLUMN "accounts"."status" IS '冻结的账户不能转入或转出';
+
+CREATE TABLE "audit_logs" (
+  "id" bigserial PRIMARY KEY,
+  "actor" varchar NOT NULL,
+  "actor_role" user_role NOT NULL,
+  "action" varchar NOT NULL,
+  "path" varchar NOT NULL,
+  "status_code" int NOT NULL,
+  "client_ip" varchar NOT NULL,
+  "details" jsonb NOT NULL DEFAULT '{}',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "audit_logs" ("actor");
+
+CREATE INDEX ON "audit_logs" ("created_at");
+
+COMMENT ON COLUMN "audit_logs"."action" IS '请求方法和路由，例如 POST /admin/accounts/:id/freeze';
+
+COMMENT ON COLUMN "audit_logs"."path" IS '实际请求的路径，包含操作对象的 ID';
+
+ALTER TABLE "audit_logs" ADD FOREIGN KEY ("actor") REFERENCES "users" ("username");
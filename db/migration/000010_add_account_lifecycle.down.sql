@@ -0,0 +1,25 @@
+DROP INDEX IF EXISTS "owner_currency_key";
+
+-- 已销户的账户回退为冻结状态
+UPDATE "accounts" SET "status" = 'frozen' WHERE "status" = 'closed';
+
+ALTER TABLE "accounts" ADD CONSTRAINT "owner_currency_key" UNIQUE ("owner", "currency");
+
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "closed_at";
+
+ALTER TABLE "accounts" ALTER COLUMN "status" DROP DEFAULT;
+
+ALTER TYPE "account_status" RENAME TO "account_status_new";
+
+CREATE TYPE "account_status" AS ENUM (
+  'active',
+  'frozen'
+);
+
+ALTER TABLE "accounts" ALTER COLUMN "status" TYPE account_status USING "status"::text::account_status;
+
+ALTER TABLE "accounts" ALTER COLUMN "status" SET DEFAULT 'active';
+
+DROP TYPE "account_status_new";
+
+COMMENT ON COLUMN "accounts"."status" IS '冻结的账户不能转入或转出';
@@ -0,0 +1,25 @@
+-- 新增的枚举值不能在同一个事务中使用，因此重建类型而不是 ADD VALUE
+ALTER TABLE "accounts" ALTER COLUMN "status" DROP DEFAULT;
+
+ALTER TYPE "account_status" RENAME TO "account_status_old";
+
+CREATE TYPE "account_status" AS ENUM (
+  'active',
+  'frozen',
+  'closed'
+);
+
+ALTER TABLE "accounts" ALTER COLUMN "status" TYPE account_status USING "status"::text::account_status;
+
+ALTER TABLE "accounts" ALTER COLUMN "status" SET DEFAULT 'active';
+
+DROP TYPE "account_status_old";
+
+ALTER TABLE "accounts" ADD COLUMN "closed_at" timestamptz;
+
+COMMENT ON COLUMN "accounts"."status" IS '只有 active 的账户可以转入或转出，closed 为终态';
+
+-- 销户后允许重新开立同币种的账户
+ALTER TABLE "accounts" DROP CONSTRAINT "owner_currency_key";
+
+CREATE UNIQUE INDEX "owner_currency_key" ON "accounts" ("owner", "currency") WHERE "status" <> 'closed';
@@ -0,0 +1,30 @@
+CREATE TYPE "adjustment_reason" AS ENUM (
+  'correction',
+  'fee',
+  'refund',
+  'chargeback',
+  'write_off'
+);
+
+CREATE TABLE "account_adjustments" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "entry_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "reason_code" adjustment_reason NOT NULL,
+  "note" varchar NOT NULL DEFAULT '',
+  "actor" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "account_adjustments" ("account_id");
+
+COMMENT ON COLUMN "account_adjustments"."amount" IS '调账金额，允许正负，与对应流水的金额相同';
+
+COMMENT ON COLUMN "account_adjustments"."actor" IS '执行调账的管理员';
+
+ALTER TABLE "account_adjustments" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "account_adjustments" ADD FOREIGN KEY ("entry_id") REFERENCES "entries" ("id");
+
+ALTER TABLE "account_adjustments" ADD FOREIGN KEY ("actor") REFERENCES "users" ("username");
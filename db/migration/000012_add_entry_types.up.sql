@@ -0,0 +1,38 @@
+CREATE TYPE "entry_type" AS ENUM (
+  'transfer_debit',
+  'transfer_credit',
+  'deposit',
+  'withdrawal',
+  'fee',
+  'interest',
+  'adjustment',
+  'reversal'
+);
+
+ALTER TABLE "entries" ADD COLUMN "transfer_id" bigint;
+
+ALTER TABLE "entries" ADD COLUMN "entry_type" entry_type;
+
+ALTER TABLE "entries" ADD COLUMN "memo" varchar NOT NULL DEFAULT '';
+
+-- 转账与流水在同一事务中创建，created_at 相同，据此关联已有的流水
+UPDATE "entries" e
+SET "transfer_id" = t."id", "entry_type" = 'transfer_debit'
+FROM "transfers" t
+WHERE e."account_id" = t."from_account_id" AND e."amount" = -t."amount" AND e."created_at" = t."created_at";
+
+UPDATE "entries" e
+SET "transfer_id" = t."id", "entry_type" = 'transfer_credit'
+FROM "transfers" t
+WHERE e."entry_type" IS NULL AND e."account_id" = t."to_account_id" AND e."amount" = t."to_amount" AND e."created_at" = t."created_at";
+
+-- 其余流水来自调账
+UPDATE "entries" SET "entry_type" = 'adjustment' WHERE "entry_type" IS NULL;
+
+ALTER TABLE "entries" ALTER COLUMN "entry_type" SET NOT NULL;
+
+CREATE INDEX ON "entries" ("transfer_id");
+
+COMMENT ON COLUMN "entries"."transfer_id" IS '产生该流水的转账，非转账流水为空';
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS "transfer_reversals";
+
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "reversed_to_amount";
+
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "reversed_amount";
+
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "reversal_status";
+
+DROP TYPE IF EXISTS "reversal_status";
@@ -0,0 +1,37 @@
+CREATE TYPE "reversal_status" AS ENUM (
+  'none',
+  'partial',
+  'full'
+);
+
+ALTER TABLE "transfers" ADD COLUMN "reversal_status" reversal_status NOT NULL DEFAULT 'none';
+
+ALTER TABLE "transfers" ADD COLUMN "reversed_amount" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "transfers" ADD COLUMN "reversed_to_amount" bigint NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN "transfers"."reversed_amount" IS '已退回转出账户的金额（转出账户币种）';
+
+COMMENT ON COLUMN "transfers"."reversed_to_amount" IS '已从转入账户扣回的金额（转入账户币种），不能超过 to_amount';
+
+CREATE TABLE "transfer_reversals" (
+  "id" bigserial PRIMARY KEY,
+  "transfer_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "to_amount" bigint NOT NULL,
+  "reason" varchar NOT NULL DEFAULT '',
+  "actor" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "transfer_reversals" ("transfer_id");
+
+COMMENT ON COLUMN "transfer_reversals"."amount" IS '退回转出账户的金额（转出账户币种）';
+
+COMMENT ON COLUMN "transfer_reversals"."to_amount" IS '从转入账户扣回的金额（转入账户币种）';
+
+COMMENT ON COLUMN "transfer_reversals"."actor" IS '发起冲正的收款人或管理员';
+
+ALTER TABLE "transfer_reversals" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "transfer_reversals" ADD FOREIGN KEY ("actor") REFERENCES "users" ("username");
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "scheduled_transfer_executions";
+
+DROP TABLE IF EXISTS "scheduled_transfers";
+
+DROP TYPE IF EXISTS "execution_status";
+
+DROP TYPE IF EXISTS "scheduled_transfer_status";
@@ -0,0 +1,63 @@
+CREATE TYPE "scheduled_transfer_status" AS ENUM (
+  'active',
+  'paused',
+  'completed',
+  'cancelled'
+);
+
+CREATE TYPE "execution_status" AS ENUM (
+  'succeeded',
+  'failed'
+);
+
+CREATE TABLE "scheduled_transfers" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "memo" varchar NOT NULL DEFAULT '',
+  "cron_expr" varchar NOT NULL DEFAULT '',
+  "next_run_at" timestamptz NOT NULL,
+  "status" scheduled_transfer_status NOT NULL DEFAULT 'active',
+  "failure_count" int NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "scheduled_transfers" ("owner");
+
+CREATE INDEX ON "scheduled_transfers" ("next_run_at") WHERE "status" = 'active';
+
+COMMENT ON COLUMN "scheduled_transfers"."amount" IS '每次转账的金额，必须为正数';
+
+COMMENT ON COLUMN "scheduled_transfers"."cron_expr" IS '标准 cron 表达式，为空时只在 next_run_at 执行一次';
+
+COMMENT ON COLUMN "scheduled_transfers"."next_run_at" IS '下一次执行的时间，失败重试时为重试时间';
+
+COMMENT ON COLUMN "scheduled_transfers"."failure_count" IS '连续因余额不足失败的次数，成功后清零';
+
+CREATE TABLE "scheduled_transfer_executions" (
+  "id" bigserial PRIMARY KEY,
+  "scheduled_transfer_id" bigint NOT NULL,
+  "transfer_id" bigint,
+  "status" execution_status NOT NULL,
+  "error" varchar NOT NULL DEFAULT '',
+  "scheduled_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "scheduled_transfer_executions" ("scheduled_transfer_id");
+
+COMMENT ON COLUMN "scheduled_transfer_executions"."transfer_id" IS '执行成功时生成的转账';
+
+COMMENT ON COLUMN "scheduled_transfer_executions"."scheduled_at" IS '本次执行对应的计划时间';
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfer_executions" ADD FOREIGN KEY ("scheduled_transfer_id") REFERENCES "scheduled_transfers" ("id");
+
+ALTER TABLE "scheduled_transfer_executions" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
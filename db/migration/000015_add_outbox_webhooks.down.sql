@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "webhook_deliveries";
+
+DROP TABLE IF EXISTS "webhooks";
+
+DROP TABLE IF EXISTS "outbox";
+
+DROP TYPE IF EXISTS "delivery_status";
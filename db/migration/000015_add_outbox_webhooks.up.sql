@@ -0,0 +1,60 @@
+CREATE TYPE "delivery_status" AS ENUM (
+  'pending',
+  'succeeded',
+  'dead'
+);
+
+CREATE TABLE "outbox" (
+  "id" bigserial PRIMARY KEY,
+  "event_type" varchar NOT NULL,
+  "username" varchar NOT NULL,
+  "payload" jsonb NOT NULL,
+  "published_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "outbox" ("id") WHERE "published_at" IS NULL;
+
+COMMENT ON COLUMN "outbox"."username" IS '事件相关的用户，事件投递到该用户注册的 webhook';
+
+COMMENT ON COLUMN "outbox"."published_at" IS '为空时尚未分发到 webhook';
+
+CREATE TABLE "webhooks" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "url" varchar NOT NULL,
+  "secret" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "webhooks" ("owner");
+
+COMMENT ON COLUMN "webhooks"."secret" IS '用于 HMAC-SHA256 签名的密钥';
+
+CREATE TABLE "webhook_deliveries" (
+  "id" bigserial PRIMARY KEY,
+  "webhook_id" bigint NOT NULL,
+  "event_id" bigint NOT NULL,
+  "status" delivery_status NOT NULL DEFAULT 'pending',
+  "attempts" int NOT NULL DEFAULT 0,
+  "next_attempt_at" timestamptz NOT NULL DEFAULT (now()),
+  "last_error" varchar NOT NULL DEFAULT '',
+  "delivered_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "webhook_deliveries" ("webhook_id");
+
+CREATE INDEX ON "webhook_deliveries" ("next_attempt_at") WHERE "status" = 'pending';
+
+COMMENT ON COLUMN "webhook_deliveries"."status" IS '重试次数用尽后为 dead，不再投递';
+
+COMMENT ON COLUMN "webhook_deliveries"."next_attempt_at" IS '下一次投递的时间，投递中时为租约到期时间';
+
+ALTER TABLE "outbox" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "webhooks" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "webhook_deliveries" ADD FOREIGN KEY ("webhook_id") REFERENCES "webhooks" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "webhook_deliveries" ADD FOREIGN KEY ("event_id") REFERENCES "outbox" ("id");
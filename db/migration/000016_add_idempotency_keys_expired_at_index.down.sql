@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "idempotency_keys_expired_at_idx";
@@ -0,0 +1,152 @@
+package migration
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/rs/zerolog/log"
+)
+
+// FS 包含所有迁移文件，文件名格式为 golang-migrate 的 {version}_{title}.{up|down}.sql
+//
+//go:embed *.sql
+var FS embed.FS
+
+// LatestVersion 返回迁移文件中最新的版本号，服务要求数据库至少迁移到这个版本
+func LatestVersion() (uint, error) {
+	files, err := fs.Glob(FS, "*.up.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, file := range files {
+		prefix, _, _ := strings.Cut(file, "_")
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid migration file name %q: %w", file, err)
+		}
+		if uint(version) > latest {
+			latest = uint(version)
+		}
+	}
+	return latest, nil
+}
+
+// Migrator 使用嵌入的迁移文件迁移数据库，与 migrate 命令行工具共用 schema_migrations 表
+type Migrator struct {
+	migrate *migrate.Migrate
+}
+
+// NewMigrator 连接 dbSource 指向的数据库，使用完需要调用 Close
+func NewMigrator(dbSource string) (*Migrator, error) {
+	source, err := iofs.New(FS, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", source, dbSource)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create migrator: %w", err)
+	}
+	m.Log = migrateLogger{}
+	return &Migrator{migrate: m}, nil
+}
+
+// Up 向上迁移 steps 个版本，steps 为 0 时迁移到最新版本，已经是最新版本时不报错
+func (migrator *Migrator) Up(steps int) error {
+	var err error
+	if steps > 0 {
+		err = migrator.migrate.Steps(steps)
+	} else {
+		err = migrator.migrate.Up()
+	}
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// Down 向下迁移 steps 个版本
+func (migrator *Migrator) Down(steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("invalid number of steps: %d", steps)
+	}
+
+	err := migrator.migrate.Steps(-steps)
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// DownAll 回滚所有版本，会删除所有数据
+func (migrator *Migrator) DownAll() error {
+	err := migrator.migrate.Down()
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// Version 返回数据库当前的迁移版本，没有执行过迁移时返回 0
+// dirty 为 true 表示上一次迁移执行失败，需要人工处理
+func (migrator *Migrator) Version() (version uint, dirty bool, err error) {
+	version, dirty, err = migrator.migrate.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return
+}
+
+// Check 检查数据库是否已经迁移到代码要求的版本，版本更新时也可以使用（滚动发布时旧实例仍在运行）
+func (migrator *Migrator) Check() error {
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+	return CheckVersion(version, dirty)
+}
+
+// Close 关闭迁移使用的数据库连接
+func (migrator *Migrator) Close() error {
+	sourceErr, dbErr := migrator.migrate.Close()
+	if sourceErr != nil {
+		return sourceErr
+	}
+	return dbErr
+}
+
+// CheckVersion 检查 version 是否不低于迁移文件中最新的版本
+func CheckVersion(version uint, dirty bool) error {
+	if dirty {
+		return fmt.Errorf("migration %d failed and must be fixed manually", version)
+	}
+
+	latest, err := LatestVersion()
+	if err != nil {
+		return err
+	}
+	if version < latest {
+		return fmt.Errorf("database schema version %d is behind the required version %d", version, latest)
+	}
+	return nil
+}
+
+// migrateLogger 把迁移过程输出到日志
+type migrateLogger struct{}
+
+func (migrateLogger) Printf(format string, v ...interface{}) {
+	log.Info().Msg(strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (migrateLogger) Verbose() bool {
+	return false
+}
@@ -0,0 +1,36 @@
+package migration
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestVersion(t *testing.T) {
+	version, err := LatestVersion()
+	require.NoError(t, err)
+
+	// 每个版本都有 up 和 down 两个文件
+	files, err := fs.Glob(FS, "*.sql")
+	require.NoError(t, err)
+	require.Len(t, files, int(version)*2)
+}
+
+func TestCheckVersion(t *testing.T) {
+	latest, err := LatestVersion()
+	require.NoError(t, err)
+
+	require.NoError(t, CheckVersion(latest, false))
+	// 滚动发布时数据库可能已经迁移到新版本
+	require.NoError(t, CheckVersion(latest+1, false))
+	require.Error(t, CheckVersion(latest-1, false))
+	require.Error(t, CheckVersion(0, false))
+	require.Error(t, CheckVersion(latest, true))
+}
+
+func TestNewMigratorInvalidSource(t *testing.T) {
+	migrator, err := NewMigrator("unknown://localhost/simple_bank")
+	require.Error(t, err)
+	require.Nil(t, migrator)
+}
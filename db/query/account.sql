@@ -11,6 +11,11 @@ INSERT INTO accounts (
 SELECT * FROM accounts
 WHERE id = $1 LIMIT 1;
 
+-- name: GetAccountForUpdate :one
+SELECT * FROM accounts
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
 -- name: ListAccounts :many
 SELECT * FROM accounts
 WHERE owner = $1
@@ -18,10 +23,17 @@ ORDER BY id
 LIMIT $2
 OFFSET $3;
 
--- name: UpdateAccount :one
+-- name: UpdateAccountOverdraftLimit :one
+UPDATE accounts
+SET overdraft_limit = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateAccountStatus :one
 UPDATE accounts
-SET balance = $3
-WHERE id = $1 AND owner = $2
+SET status = $2,
+  closed_at = $3
+WHERE id = $1
 RETURNING *;
 
 -- name: AddAccountBalance :one
@@ -30,5 +42,6 @@ SET balance = balance + sqlc.arg(amount)
 WHERE id = sqlc.arg(id)
 RETURNING *;
 
--- name: DeleteAccount :exec
-DELETE FROM accounts WHERE id = $1 AND owner = $2;
\ No newline at end of file
+-- name: NotifyAccountActivity :exec
+-- 通知在 account_activity 频道上监听的连接，事务提交后才会送达
+SELECT pg_notify('account_activity', sqlc.arg(account_id)::bigint::text);
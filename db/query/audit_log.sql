@@ -0,0 +1,20 @@
+-- name: CreateAuditLog :one
+INSERT INTO audit_logs (
+  actor,
+  actor_role,
+  action,
+  path,
+  status_code,
+  client_ip,
+  details
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: ListAuditLogs :many
+-- actor 为空时返回全部记录
+SELECT * FROM audit_logs
+WHERE sqlc.narg(actor)::varchar IS NULL OR actor = sqlc.narg(actor)
+ORDER BY id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
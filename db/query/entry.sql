@@ -1,9 +1,12 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
   account_id,
-  amount
+  amount,
+  transfer_id,
+  entry_type,
+  memo
 ) VALUES (
-  $1, $2
+  $1, $2, $3, $4, $5
 ) RETURNING *;
 
 -- name: GetEntry :one
@@ -15,4 +18,42 @@ SELECT * FROM entries
 WHERE account_id = $1
 ORDER BY id
 LIMIT $2
-OFFSET $3;
\ No newline at end of file
+OFFSET $3;
+
+-- name: ListAccountEntries :many
+-- 按 (created_at, id) 倒序做游标分页，可选的过滤条件为空时不生效
+SELECT * FROM entries
+WHERE
+  account_id = sqlc.arg(account_id) AND
+  (sqlc.narg(start_time)::timestamptz IS NULL OR created_at >= sqlc.narg(start_time)) AND
+  (sqlc.narg(end_time)::timestamptz IS NULL OR created_at < sqlc.narg(end_time)) AND
+  (sqlc.narg(min_amount)::bigint IS NULL OR abs(amount) >= sqlc.narg(min_amount)) AND
+  (sqlc.narg(max_amount)::bigint IS NULL OR abs(amount) <= sqlc.narg(max_amount)) AND
+  (
+    sqlc.narg(direction)::varchar IS NULL OR
+    (sqlc.narg(direction) = 'incoming' AND amount > 0) OR
+    (sqlc.narg(direction) = 'outgoing' AND amount < 0)
+  ) AND
+  (sqlc.narg(entry_type)::entry_type IS NULL OR entry_type = sqlc.narg(entry_type)) AND
+  (
+    sqlc.narg(cursor_created_at)::timestamptz IS NULL OR
+    (created_at, id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::bigint)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListTransferEntries :many
+SELECT * FROM entries
+WHERE transfer_id = $1
+ORDER BY id;
+
+-- name: ListAccountEntriesAfter :many
+-- 按 id 顺序返回 after_id 之后的流水，用于推送新流水和断线续传
+SELECT * FROM entries
+WHERE account_id = sqlc.arg(account_id) AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg('limit');
+
+-- name: GetLastAccountEntryID :one
+SELECT COALESCE(MAX(id), 0)::bigint AS last_entry_id FROM entries
+WHERE account_id = $1;
@@ -0,0 +1,16 @@
+-- name: UpsertExchangeRate :one
+INSERT INTO exchange_rates (
+  base_currency,
+  quote_currency,
+  rate
+) VALUES (
+  $1, $2, $3
+) ON CONFLICT (base_currency, quote_currency) DO UPDATE
+SET
+  rate = EXCLUDED.rate,
+  updated_at = now()
+RETURNING *;
+
+-- name: GetExchangeRate :one
+SELECT * FROM exchange_rates
+WHERE base_currency = $1 AND quote_currency = $2 LIMIT 1;
@@ -0,0 +1,21 @@
+-- name: CreateFxQuote :one
+INSERT INTO fx_quotes (
+  username,
+  from_currency,
+  to_currency,
+  rate,
+  expired_at
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetFxQuote :one
+SELECT * FROM fx_quotes
+WHERE id = $1 LIMIT 1;
+
+-- name: UseFxQuote :one
+-- 报价只能使用一次，已使用或已过期时返回 sql.ErrNoRows
+UPDATE fx_quotes
+SET used_at = now()
+WHERE id = $1 AND used_at IS NULL AND expired_at > now()
+RETURNING *;
@@ -0,0 +1,39 @@
+-- name: CreateIdempotencyKey :one
+-- 已存在且未过期的 key 不会被覆盖，此时返回 sql.ErrNoRows
+INSERT INTO idempotency_keys (
+  username,
+  idempotency_key,
+  request_hash,
+  expired_at
+) VALUES (
+  $1, $2, $3, $4
+) ON CONFLICT (username, idempotency_key) DO UPDATE
+SET
+  request_hash = EXCLUDED.request_hash,
+  response_code = NULL,
+  response_body = NULL,
+  created_at = now(),
+  expired_at = EXCLUDED.expired_at
+WHERE idempotency_keys.expired_at < now()
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE username = $1 AND idempotency_key = $2 LIMIT 1;
+
+-- name: UpdateIdempotencyKeyResponse :one
+UPDATE idempotency_keys
+SET
+  response_code = $3,
+  response_body = $4
+WHERE username = $1 AND idempotency_key = $2
+RETURNING *;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE username = $1 AND idempotency_key = $2;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+-- 过期的 key 不会再用于重放，由后台任务定期清理
+DELETE FROM idempotency_keys
+WHERE expired_at < now();
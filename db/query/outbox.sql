@@ -0,0 +1,25 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox (
+  event_type,
+  username,
+  payload
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: GetOutboxEvent :one
+SELECT * FROM outbox
+WHERE id = $1 LIMIT 1;
+
+-- name: ListUnpublishedOutboxEvents :many
+-- 跳过其他 dispatcher 正在分发的事件
+SELECT * FROM outbox
+WHERE published_at IS NULL
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxEventPublished :exec
+UPDATE outbox
+SET published_at = now()
+WHERE id = $1;
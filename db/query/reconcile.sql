@@ -0,0 +1,35 @@
+-- name: ListAccountBalances :many
+-- 按 id 分批返回账户余额与流水合计，after_id 为上一批最后一个账户的 id
+SELECT
+  a.id,
+  a.balance,
+  COALESCE(SUM(e.amount), 0)::bigint AS entries_total
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+WHERE a.id > sqlc.arg(after_id)
+GROUP BY a.id
+ORDER BY a.id
+LIMIT sqlc.arg('limit');
+
+-- name: ListTransferEntryCounts :many
+-- 按 id 分批返回每笔转账对应的扣账、入账流水数量
+SELECT
+  t.id,
+  t.from_account_id,
+  t.to_account_id,
+  t.amount,
+  t.to_amount,
+  (
+    SELECT count(*) FROM entries e
+    WHERE e.transfer_id = t.id AND e.entry_type = 'transfer_debit' AND
+      e.account_id = t.from_account_id AND e.amount = -t.amount
+  ) AS debit_entries,
+  (
+    SELECT count(*) FROM entries e
+    WHERE e.transfer_id = t.id AND e.entry_type = 'transfer_credit' AND
+      e.account_id = t.to_account_id AND e.amount = t.to_amount
+  ) AS credit_entries
+FROM transfers t
+WHERE t.id > sqlc.arg(after_id)
+ORDER BY t.id
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,17 @@
+-- name: CreateRevokedToken :exec
+INSERT INTO revoked_tokens (
+  id,
+  username,
+  expired_at
+) VALUES (
+  $1, $2, $3
+) ON CONFLICT (id) DO NOTHING;
+
+-- name: ListRevokedTokens :many
+-- 已过期的 token 无论是否吊销都无法通过验证
+SELECT * FROM revoked_tokens
+WHERE expired_at > now();
+
+-- name: DeleteExpiredRevokedTokens :exec
+DELETE FROM revoked_tokens
+WHERE expired_at <= now();
@@ -0,0 +1,65 @@
+-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+  owner,
+  from_account_id,
+  to_account_id,
+  amount,
+  memo,
+  cron_expr,
+  next_run_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: GetScheduledTransfer :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1 LIMIT 1;
+
+-- name: GetScheduledTransferForUpdate :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListScheduledTransfers :many
+SELECT * FROM scheduled_transfers
+WHERE owner = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: UpdateScheduledTransfer :one
+UPDATE scheduled_transfers
+SET amount = $2,
+  memo = $3,
+  cron_expr = $4,
+  next_run_at = $5,
+  status = $6,
+  failure_count = $7
+WHERE id = $1
+RETURNING *;
+
+-- name: GetDueScheduledTransferForUpdate :one
+-- 跳过其他 worker 已锁定的记录，多个实例可以同时执行
+SELECT * FROM scheduled_transfers
+WHERE status = 'active' AND next_run_at <= sqlc.arg(now)
+ORDER BY next_run_at
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: CreateScheduledTransferExecution :one
+INSERT INTO scheduled_transfer_executions (
+  scheduled_transfer_id,
+  transfer_id,
+  status,
+  error,
+  scheduled_at
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: ListScheduledTransferExecutions :many
+SELECT * FROM scheduled_transfer_executions
+WHERE scheduled_transfer_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
@@ -2,9 +2,12 @@
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  to_amount,
+  exchange_rate,
+  fx_quote_id
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4, $5, $6
 ) RETURNING *;
 
 -- name: GetTransfer :one
@@ -18,4 +21,58 @@ WHERE
   to_account_id = $2
 ORDER BY id
 LIMIT $3
-OFFSET $4;
\ No newline at end of file
+OFFSET $4;
+
+-- name: ListAccountTransfers :many
+-- 按 (created_at, id) 倒序做游标分页，可选的过滤条件为空时不生效
+-- 转出和转入分别沿 (from_account_id, created_at, id) 和 (to_account_id, created_at, id) 索引各取 limit 条，合并后再取前 limit 条
+-- 金额过滤使用本账户币种的金额：转出为 amount，转入为 to_amount
+(
+  SELECT o.* FROM transfers o
+  WHERE
+    o.from_account_id = sqlc.arg(account_id) AND
+    (sqlc.narg(direction)::varchar IS NULL OR sqlc.narg(direction) = 'outgoing') AND
+    (sqlc.narg(start_time)::timestamptz IS NULL OR o.created_at >= sqlc.narg(start_time)) AND
+    (sqlc.narg(end_time)::timestamptz IS NULL OR o.created_at < sqlc.narg(end_time)) AND
+    (sqlc.narg(min_amount)::bigint IS NULL OR o.amount >= sqlc.narg(min_amount)) AND
+    (sqlc.narg(max_amount)::bigint IS NULL OR o.amount <= sqlc.narg(max_amount)) AND
+    (
+      sqlc.narg(cursor_created_at)::timestamptz IS NULL OR
+      (o.created_at, o.id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::bigint)
+    )
+  ORDER BY o.created_at DESC, o.id DESC
+  LIMIT sqlc.arg('limit')
+)
+UNION ALL
+(
+  SELECT i.* FROM transfers i
+  WHERE
+    i.to_account_id = sqlc.arg(account_id) AND
+    (sqlc.narg(direction)::varchar IS NULL OR sqlc.narg(direction) = 'incoming') AND
+    (sqlc.narg(start_time)::timestamptz IS NULL OR i.created_at >= sqlc.narg(start_time)) AND
+    (sqlc.narg(end_time)::timestamptz IS NULL OR i.created_at < sqlc.narg(end_time)) AND
+    (sqlc.narg(min_amount)::bigint IS NULL OR i.to_amount >= sqlc.narg(min_amount)) AND
+    (sqlc.narg(max_amount)::bigint IS NULL OR i.to_amount <= sqlc.narg(max_amount)) AND
+    (
+      sqlc.narg(cursor_created_at)::timestamptz IS NULL OR
+      (i.created_at, i.id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::bigint)
+    )
+  ORDER BY i.created_at DESC, i.id DESC
+  LIMIT sqlc.arg('limit')
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetTransferForUpdate :one
+SELECT * FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: UpdateTransferReversal :one
+UPDATE transfers
+SET
+  reversed_amount = $2,
+  reversed_to_amount = $3,
+  reversal_status = $4
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,15 @@
+-- name: CreateTransferReversal :one
+INSERT INTO transfer_reversals (
+  transfer_id,
+  amount,
+  to_amount,
+  reason,
+  actor
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: ListTransferReversals :many
+SELECT * FROM transfer_reversals
+WHERE transfer_id = $1
+ORDER BY id;
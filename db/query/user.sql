@@ -13,4 +13,21 @@ SELECT * FROM users
 WHERE username = $1 LIMIT 1;
 
 -- name: DeleteUser :exec
-DELETE FROM users WHERE username = $1;
\ No newline at end of file
+DELETE FROM users WHERE username = $1;
+
+-- name: RevokeUserTokens :one
+UPDATE users
+SET tokens_revoked_at = $2
+WHERE username = $1
+RETURNING *;
+
+-- name: ListUserTokenRevocations :many
+-- 只返回在 $1 之后吊销的用户，更早的吊销不会影响未过期的 token
+SELECT username, tokens_revoked_at FROM users
+WHERE tokens_revoked_at > $1;
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET role = $2
+WHERE username = $1
+RETURNING *;
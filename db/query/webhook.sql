@@ -0,0 +1,65 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (
+  owner,
+  url,
+  secret
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks
+WHERE id = $1 LIMIT 1;
+
+-- name: ListWebhooks :many
+SELECT * FROM webhooks
+WHERE owner = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks
+WHERE id = $1;
+
+-- name: CreateWebhookDeliveries :execrows
+-- 为用户注册的每个 webhook 创建一条待投递记录
+INSERT INTO webhook_deliveries (
+  webhook_id,
+  event_id
+)
+SELECT id, sqlc.arg(event_id)::bigint FROM webhooks
+WHERE owner = sqlc.arg(owner);
+
+-- name: ClaimWebhookDeliveries :many
+-- 领取到期的投递并延后 next_attempt_at 作为租约，投递失败或进程退出时租约到期后会被重新领取
+UPDATE webhook_deliveries
+SET next_attempt_at = sqlc.arg(lease_until)
+WHERE id IN (
+  SELECT d.id FROM webhook_deliveries d
+  WHERE d.status = 'pending' AND d.next_attempt_at <= sqlc.arg(now)
+  ORDER BY d.next_attempt_at
+  LIMIT sqlc.arg('limit')
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: UpdateWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status = $2,
+  attempts = $3,
+  next_attempt_at = $4,
+  last_error = $5,
+  delivered_at = $6
+WHERE id = $1
+RETURNING *;
+
+-- name: ListWebhookDeliveries :many
+-- status 为空时返回全部记录，dead 为死信
+SELECT * FROM webhook_deliveries
+WHERE
+  webhook_id = sqlc.arg(webhook_id) AND
+  (sqlc.narg(status)::varchar IS NULL OR status::varchar = sqlc.narg(status))
+ORDER BY id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
@@ -7,13 +7,14 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
 const addAccountBalance = `-- name: AddAccountBalance :one
 UPDATE accounts
 SET balance = balance + $1
 WHERE id = $2
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, overdraft_limit, status, closed_at
 `
 
 type AddAccountBalanceParams struct {
@@ -30,6 +31,9 @@ func (q *Queries) AddAccountBalance(ctx context.Context, arg AddAccountBalancePa
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Status,
+		&i.ClosedAt,
 	)
 	return i, err
 }
@@ -41,7 +45,7 @@ INSERT INTO accounts (
   currency
 ) VALUES (
   $1, $2, $3
-) RETURNING id, owner, balance, currency, created_at
+) RETURNING id, owner, balance, currency, created_at, overdraft_limit, status, closed_at
 `
 
 type CreateAccountParams struct {
@@ -59,31 +63,42 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Status,
+		&i.ClosedAt,
 	)
 	return i, err
 }
 
-const deleteAccount = `-- name: DeleteAccount :exec
-DELETE FROM accounts WHERE id = $1 AND owner = $2
+const getAccount = `-- name: GetAccount :one
+SELECT id, owner, balance, currency, created_at, overdraft_limit, status, closed_at FROM accounts
+WHERE id = $1 LIMIT 1
 `
 
-type DeleteAccountParams struct {
-	ID    int64  `json:"id"`
-	Owner string `json:"owner"`
-}
-
-func (q *Queries) DeleteAccount(ctx context.Context, arg DeleteAccountParams) error {
-	_, err := q.db.ExecContext(ctx, deleteAccount, arg.ID, arg.Owner)
-	return err
+func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
+	row := q.db.QueryRowContext(ctx, getAccount, id)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Status,
+		&i.ClosedAt,
+	)
+	return i, err
 }
 
-const getAccount = `-- name: GetAccount :one
-SELECT id, owner, balance, currency, created_at FROM accounts
+const getAccountForUpdate = `-- name: GetAccountForUpdate :one
+SELECT id, owner, balance, currency, created_at, overdraft_limit, status, closed_at FROM accounts
 WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
 `
 
-func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
-	row := q.db.QueryRowContext(ctx, getAccount, id)
+func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, error) {
+	row := q.db.QueryRowContext(ctx, getAccountForUpdate, id)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -91,12 +106,15 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Status,
+		&i.ClosedAt,
 	)
 	return i, err
 }
 
 const listAccounts = `-- name: ListAccounts :many
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, overdraft_limit, status, closed_at FROM accounts
 WHERE owner = $1
 ORDER BY id
 LIMIT $2
@@ -124,6 +142,9 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 			&i.Balance,
 			&i.Currency,
 			&i.CreatedAt,
+			&i.OverdraftLimit,
+			&i.Status,
+			&i.ClosedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -138,21 +159,60 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 	return items, nil
 }
 
-const updateAccount = `-- name: UpdateAccount :one
+const notifyAccountActivity = `-- name: NotifyAccountActivity :exec
+SELECT pg_notify('account_activity', $1::bigint::text)
+`
+
+// 通知在 account_activity 频道上监听的连接，事务提交后才会送达
+func (q *Queries) NotifyAccountActivity(ctx context.Context, accountID int64) error {
+	_, err := q.db.ExecContext(ctx, notifyAccountActivity, accountID)
+	return err
+}
+
+const updateAccountOverdraftLimit = `-- name: UpdateAccountOverdraftLimit :one
+UPDATE accounts
+SET overdraft_limit = $2
+WHERE id = $1
+RETURNING id, owner, balance, currency, created_at, overdraft_limit, status, closed_at
+`
+
+type UpdateAccountOverdraftLimitParams struct {
+	ID             int64 `json:"id"`
+	OverdraftLimit int64 `json:"overdraft_limit"`
+}
+
+func (q *Queries) UpdateAccountOverdraftLimit(ctx context.Context, arg UpdateAccountOverdraftLimitParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, updateAccountOverdraftLimit, arg.ID, arg.OverdraftLimit)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Status,
+		&i.ClosedAt,
+	)
+	return i, err
+}
+
+const updateAccountStatus = `-- name: UpdateAccountStatus :one
 UPDATE accounts
-SET balance = $3
-WHERE id = $1 AND owner = $2
-RETURNING id, owner, balance, currency, created_at
+SET status = $2,
+  closed_at = $3
+WHERE id = $1
+RETURNING id, owner, balance, currency, created_at, overdraft_limit, status, closed_at
 `
 
-type UpdateAccountParams struct {
-	ID      int64  `json:"id"`
-	Owner   string `json:"owner"`
-	Balance int64  `json:"balance"`
+type UpdateAccountStatusParams struct {
+	ID       int64         `json:"id"`
+	Status   AccountStatus `json:"status"`
+	ClosedAt sql.NullTime  `json:"closed_at"`
 }
 
-func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
-	row := q.db.QueryRowContext(ctx, updateAccount, arg.ID, arg.Owner, arg.Balance)
+func (q *Queries) UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, updateAccountStatus, arg.ID, arg.Status, arg.ClosedAt)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -160,6 +220,9 @@ func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Status,
+		&i.ClosedAt,
 	)
 	return i, err
 }
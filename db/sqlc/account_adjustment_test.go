@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAccountAdjustments(t *testing.T) {
+	store := NewStore(testDb)
+
+	admin := CreateRandomUser(t)
+	account := createFundedAccount(t, 10)
+
+	var results []AdjustAccountTxResult
+	for i := 0; i < 3; i++ {
+		result, err := store.AdjustAccountTx(context.Background(), AdjustAccountTxParams{
+			AccountID:  account.ID,
+			Amount:     -1,
+			ReasonCode: AdjustmentReasonFee,
+			Actor:      admin.Username,
+		})
+		require.NoError(t, err)
+		results = append(results, result)
+	}
+
+	adjustments, err := testQueries.ListAccountAdjustments(context.Background(), ListAccountAdjustmentsParams{
+		AccountID: account.ID,
+		Limit:     5,
+		Offset:    0,
+	})
+	require.NoError(t, err)
+	require.Len(t, adjustments, 3)
+
+	// 按时间倒序返回
+	for i, adjustment := range adjustments {
+		require.Equal(t, results[len(results)-1-i].Adjustment.ID, adjustment.ID)
+		require.Equal(t, account.ID, adjustment.AccountID)
+		require.Equal(t, admin.Username, adjustment.Actor)
+	}
+}
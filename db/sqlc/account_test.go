@@ -30,6 +30,8 @@ func CreateRandomAccount(t *testing.T) Account {
 	require.Equal(t, arg.Owner, account.Owner)
 	require.Equal(t, arg.Balance, account.Balance)
 	require.Equal(t, arg.Currency, account.Currency)
+	require.Equal(t, AccountStatusActive, account.Status)
+	require.False(t, account.ClosedAt.Valid)
 	// 返回结果非零值
 	require.NotZero(t, account.ID)
 	require.NotZero(t, account.CreatedAt)
@@ -55,38 +57,44 @@ func TestGetAccount(t *testing.T) {
 	require.WithinDuration(t, account1.CreatedAt, account2.CreatedAt, time.Second)
 }
 
-func TestUpdateAccount(t *testing.T) {
+func TestUpdateAccountOverdraftLimit(t *testing.T) {
 	account1 := CreateRandomAccount(t)
+	require.Zero(t, account1.OverdraftLimit)
 
-	arg := UpdateAccountParams{
-		ID:      account1.ID,
-		Owner:   account1.Owner,
-		Balance: utils.RandomMoney(),
+	arg := UpdateAccountOverdraftLimitParams{
+		ID:             account1.ID,
+		OverdraftLimit: utils.RandomMoney(),
 	}
 
-	account2, err := testQueries.UpdateAccount(context.Background(), arg)
+	account2, err := testQueries.UpdateAccountOverdraftLimit(context.Background(), arg)
 	require.NoError(t, err)
-	require.NotEmpty(t, account2)
 	require.Equal(t, account1.ID, account2.ID)
-	require.Equal(t, account1.Owner, account2.Owner)
-	require.Equal(t, account1.Currency, account2.Currency)
-	require.Equal(t, arg.Balance, account2.Balance)
-	require.Equal(t, account1.CreatedAt, account2.CreatedAt)
+	require.Equal(t, account1.Balance, account2.Balance)
+	require.Equal(t, arg.OverdraftLimit, account2.OverdraftLimit)
 }
 
-func TestDeleteAccount(t *testing.T) {
+func TestUpdateAccountStatus(t *testing.T) {
 	account1 := CreateRandomAccount(t)
-	arg := DeleteAccountParams{
-		ID:    account1.ID,
-		Owner: account1.Owner,
+
+	arg := UpdateAccountStatusParams{
+		ID:       account1.ID,
+		Status:   AccountStatusClosed,
+		ClosedAt: sql.NullTime{Time: time.Now(), Valid: true},
 	}
-	err := testQueries.DeleteAccount(context.Background(), arg)
-	require.NoError(t, err)
 
-	account2, err := testQueries.GetAccount(context.Background(), account1.ID)
-	require.Error(t, err)
+	account2, err := testQueries.UpdateAccountStatus(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, account1.ID, account2.ID)
+	require.Equal(t, account1.Balance, account2.Balance)
+	require.Equal(t, AccountStatusClosed, account2.Status)
+	require.True(t, account2.ClosedAt.Valid)
+	require.WithinDuration(t, arg.ClosedAt.Time, account2.ClosedAt.Time, time.Second)
+
+	_, err = testQueries.UpdateAccountStatus(context.Background(), UpdateAccountStatusParams{
+		ID:     account1.ID + 1000000,
+		Status: AccountStatusFrozen,
+	})
 	require.EqualError(t, err, sql.ErrNoRows.Error())
-	require.Empty(t, account2)
 }
 
 func TestListAccounts(t *testing.T) {
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func CreateRandomAuditLog(t *testing.T, actor User) AuditLog {
+	arg := CreateAuditLogParams{
+		Actor:      actor.Username,
+		ActorRole:  UserRoleAdmin,
+		Action:     "POST /admin/accounts/:id/freeze",
+		Path:       "/admin/accounts/1/freeze",
+		StatusCode: http.StatusOK,
+		ClientIp:   "127.0.0.1",
+		Details:    json.RawMessage(`{"params":{"id":"1"}}`),
+	}
+
+	auditLog, err := testQueries.CreateAuditLog(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, auditLog.ID)
+	require.Equal(t, arg.Actor, auditLog.Actor)
+	require.Equal(t, arg.ActorRole, auditLog.ActorRole)
+	require.Equal(t, arg.Action, auditLog.Action)
+	require.Equal(t, arg.Path, auditLog.Path)
+	require.Equal(t, arg.StatusCode, auditLog.StatusCode)
+	require.Equal(t, arg.ClientIp, auditLog.ClientIp)
+	require.JSONEq(t, string(arg.Details), string(auditLog.Details))
+	require.NotZero(t, auditLog.CreatedAt)
+
+	return auditLog
+}
+
+func TestCreateAuditLog(t *testing.T) {
+	CreateRandomAuditLog(t, CreateRandomUser(t))
+}
+
+func TestListAuditLogs(t *testing.T) {
+	actor := CreateRandomUser(t)
+	for i := 0; i < 3; i++ {
+		CreateRandomAuditLog(t, actor)
+	}
+	CreateRandomAuditLog(t, CreateRandomUser(t))
+
+	auditLogs, err := testQueries.ListAuditLogs(context.Background(), ListAuditLogsParams{
+		Actor:  sql.NullString{String: actor.Username, Valid: true},
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, auditLogs, 3)
+
+	// 按时间倒序返回
+	for i, auditLog := range auditLogs {
+		require.Equal(t, actor.Username, auditLog.Actor)
+		if i > 0 {
+			require.Greater(t, auditLogs[i-1].ID, auditLog.ID)
+		}
+	}
+
+	auditLogs, err = testQueries.ListAuditLogs(context.Background(), ListAuditLogsParams{
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, auditLogs)
+}
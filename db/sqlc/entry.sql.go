@@ -7,36 +7,52 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
 const createEntry = `-- name: CreateEntry :one
 INSERT INTO entries (
   account_id,
-  amount
+  amount,
+  transfer_id,
+  entry_type,
+  memo
 ) VALUES (
-  $1, $2
-) RETURNING id, account_id, amount, created_at
+  $1, $2, $3, $4, $5
+) RETURNING id, account_id, amount, created_at, transfer_id, entry_type, memo
 `
 
 type CreateEntryParams struct {
-	AccountID int64 `json:"account_id"`
-	Amount    int64 `json:"amount"`
+	AccountID  int64         `json:"account_id"`
+	Amount     int64         `json:"amount"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	EntryType  EntryType     `json:"entry_type"`
+	Memo       string        `json:"memo"`
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createEntry,
+		arg.AccountID,
+		arg.Amount,
+		arg.TransferID,
+		arg.EntryType,
+		arg.Memo,
+	)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.TransferID,
+		&i.EntryType,
+		&i.Memo,
 	)
 	return i, err
 }
 
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, transfer_id, entry_type, memo FROM entries
 WHERE id = $1 LIMIT 1
 `
 
@@ -48,12 +64,150 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.TransferID,
+		&i.EntryType,
+		&i.Memo,
 	)
 	return i, err
 }
 
+const getLastAccountEntryID = `-- name: GetLastAccountEntryID :one
+SELECT COALESCE(MAX(id), 0)::bigint AS last_entry_id FROM entries
+WHERE account_id = $1
+`
+
+func (q *Queries) GetLastAccountEntryID(ctx context.Context, accountID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getLastAccountEntryID, accountID)
+	var last_entry_id int64
+	err := row.Scan(&last_entry_id)
+	return last_entry_id, err
+}
+
+const listAccountEntries = `-- name: ListAccountEntries :many
+SELECT id, account_id, amount, created_at, transfer_id, entry_type, memo FROM entries
+WHERE
+  account_id = $1 AND
+  ($2::timestamptz IS NULL OR created_at >= $2) AND
+  ($3::timestamptz IS NULL OR created_at < $3) AND
+  ($4::bigint IS NULL OR abs(amount) >= $4) AND
+  ($5::bigint IS NULL OR abs(amount) <= $5) AND
+  (
+    $6::varchar IS NULL OR
+    ($6 = 'incoming' AND amount > 0) OR
+    ($6 = 'outgoing' AND amount < 0)
+  ) AND
+  ($7::entry_type IS NULL OR entry_type = $7) AND
+  (
+    $8::timestamptz IS NULL OR
+    (created_at, id) < ($8, $9::bigint)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT $10
+`
+
+type ListAccountEntriesParams struct {
+	AccountID       int64          `json:"account_id"`
+	StartTime       sql.NullTime   `json:"start_time"`
+	EndTime         sql.NullTime   `json:"end_time"`
+	MinAmount       sql.NullInt64  `json:"min_amount"`
+	MaxAmount       sql.NullInt64  `json:"max_amount"`
+	Direction       sql.NullString `json:"direction"`
+	EntryType       NullEntryType  `json:"entry_type"`
+	CursorCreatedAt sql.NullTime   `json:"cursor_created_at"`
+	CursorID        sql.NullInt64  `json:"cursor_id"`
+	Limit           int32          `json:"limit"`
+}
+
+// 按 (created_at, id) 倒序做游标分页，可选的过滤条件为空时不生效
+func (q *Queries) ListAccountEntries(ctx context.Context, arg ListAccountEntriesParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountEntries,
+		arg.AccountID,
+		arg.StartTime,
+		arg.EndTime,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Direction,
+		arg.EntryType,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.TransferID,
+			&i.EntryType,
+			&i.Memo,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountEntriesAfter = `-- name: ListAccountEntriesAfter :many
+SELECT id, account_id, amount, created_at, transfer_id, entry_type, memo FROM entries
+WHERE account_id = $1 AND id > $2
+ORDER BY id
+LIMIT $3
+`
+
+type ListAccountEntriesAfterParams struct {
+	AccountID int64 `json:"account_id"`
+	AfterID   int64 `json:"after_id"`
+	Limit     int32 `json:"limit"`
+}
+
+// 按 id 顺序返回 after_id 之后的流水，用于推送新流水和断线续传
+func (q *Queries) ListAccountEntriesAfter(ctx context.Context, arg ListAccountEntriesAfterParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountEntriesAfter, arg.AccountID, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.TransferID,
+			&i.EntryType,
+			&i.Memo,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, transfer_id, entry_type, memo FROM entries
 WHERE account_id = $1
 ORDER BY id
 LIMIT $2
@@ -80,6 +234,46 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.TransferID,
+			&i.EntryType,
+			&i.Memo,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransferEntries = `-- name: ListTransferEntries :many
+SELECT id, account_id, amount, created_at, transfer_id, entry_type, memo FROM entries
+WHERE transfer_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListTransferEntries(ctx context.Context, transferID sql.NullInt64) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listTransferEntries, transferID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.TransferID,
+			&i.EntryType,
+			&i.Memo,
 		); err != nil {
 			return nil, err
 		}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"simplebank/utils"
 	"testing"
 
@@ -12,6 +13,8 @@ func CreateRandomEntry(t *testing.T, account Account) Entry {
 	arg := CreateEntryParams{
 		AccountID: account.ID,
 		Amount:    utils.RandomMoney(),
+		EntryType: EntryTypeDeposit,
+		Memo:      utils.RandomString(10),
 	}
 
 	entry, err := testQueries.CreateEntry(context.Background(), arg)
@@ -20,6 +23,9 @@ func CreateRandomEntry(t *testing.T, account Account) Entry {
 
 	require.Equal(t, account.ID, entry.AccountID)
 	require.Equal(t, arg.Amount, entry.Amount)
+	require.Equal(t, arg.EntryType, entry.EntryType)
+	require.Equal(t, arg.Memo, entry.Memo)
+	require.False(t, entry.TransferID.Valid)
 	require.NotZero(t, entry.ID)
 	require.NotZero(t, entry.CreatedAt)
 
@@ -64,3 +70,126 @@ func TestListEntries(t *testing.T) {
 		require.Equal(t, arg.AccountID, entry.AccountID)
 	}
 }
+
+func TestListAccountEntries(t *testing.T) {
+	account := CreateRandomAccount(t)
+	for i := 0; i < 10; i++ {
+		CreateRandomEntry(t, account)
+	}
+
+	// 按 (created_at, id) 倒序翻页，两页之间不能有重复或遗漏
+	arg := ListAccountEntriesParams{
+		AccountID: account.ID,
+		Limit:     5,
+	}
+	page1, err := testQueries.ListAccountEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, page1, 5)
+
+	last := page1[len(page1)-1]
+	arg.CursorCreatedAt = sql.NullTime{Time: last.CreatedAt, Valid: true}
+	arg.CursorID = sql.NullInt64{Int64: last.ID, Valid: true}
+	page2, err := testQueries.ListAccountEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, page2, 5)
+
+	entries := append(page1, page2...)
+	for i := 1; i < len(entries); i++ {
+		require.Equal(t, account.ID, entries[i].AccountID)
+		require.False(t, entries[i].CreatedAt.After(entries[i-1].CreatedAt))
+		require.NotEqual(t, entries[i].ID, entries[i-1].ID)
+	}
+}
+
+func TestListAccountEntriesFilters(t *testing.T) {
+	account := CreateRandomAccount(t)
+	for _, amount := range []int64{-50, -5, 5, 50} {
+		entryType := EntryTypeDeposit
+		if amount < 0 {
+			entryType = EntryTypeWithdrawal
+		}
+		_, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    amount,
+			EntryType: entryType,
+		})
+		require.NoError(t, err)
+	}
+
+	entries, err := testQueries.ListAccountEntries(context.Background(), ListAccountEntriesParams{
+		AccountID: account.ID,
+		Direction: sql.NullString{String: "outgoing", Valid: true},
+		MinAmount: sql.NullInt64{Int64: 10, Valid: true},
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, int64(-50), entries[0].Amount)
+
+	entries, err = testQueries.ListAccountEntries(context.Background(), ListAccountEntriesParams{
+		AccountID: account.ID,
+		Direction: sql.NullString{String: "incoming", Valid: true},
+		MaxAmount: sql.NullInt64{Int64: 10, Valid: true},
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, int64(5), entries[0].Amount)
+}
+
+func TestListAccountEntriesEntryType(t *testing.T) {
+	account := CreateRandomAccount(t)
+	for _, entryType := range []EntryType{EntryTypeDeposit, EntryTypeFee, EntryTypeFee} {
+		_, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    utils.RandomMoney(),
+			EntryType: entryType,
+		})
+		require.NoError(t, err)
+	}
+
+	entries, err := testQueries.ListAccountEntries(context.Background(), ListAccountEntriesParams{
+		AccountID: account.ID,
+		EntryType: NullEntryType{EntryType: EntryTypeFee, Valid: true},
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		require.Equal(t, EntryTypeFee, entry.EntryType)
+	}
+}
+
+func TestListAccountEntriesAfter(t *testing.T) {
+	account := CreateRandomAccount(t)
+
+	lastID, err := testQueries.GetLastAccountEntryID(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Zero(t, lastID)
+
+	entries := make([]Entry, 5)
+	for i := range entries {
+		entries[i] = CreateRandomEntry(t, account)
+	}
+
+	lastID, err = testQueries.GetLastAccountEntryID(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, entries[4].ID, lastID)
+
+	// 按 id 顺序返回之后的流水
+	gotEntries, err := testQueries.ListAccountEntriesAfter(context.Background(), ListAccountEntriesAfterParams{
+		AccountID: account.ID,
+		AfterID:   entries[1].ID,
+		Limit:     2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, entries[2:4], gotEntries)
+
+	gotEntries, err = testQueries.ListAccountEntriesAfter(context.Background(), ListAccountEntriesAfterParams{
+		AccountID: account.ID,
+		AfterID:   lastID,
+		Limit:     2,
+	})
+	require.NoError(t, err)
+	require.Empty(t, gotEntries)
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"database/sql/driver"
+)
+
+// NullEntryType 可为空的流水类型，用于按流水类型筛选的参数
+// sqlc v1.14 不会为枚举生成可为空的类型，sqlc.yaml 中通过 overrides 映射到这个类型
+// 查询中仍然按 entry_type 比较，可以使用 entry_type 上的索引
+type NullEntryType struct {
+	EntryType EntryType
+	Valid     bool // Valid is true if EntryType is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullEntryType) Scan(value interface{}) error {
+	if value == nil {
+		ns.EntryType, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.EntryType.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullEntryType) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.EntryType), nil
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"simplebank/utils"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsertExchangeRate(t *testing.T) {
+	arg := UpsertExchangeRateParams{
+		BaseCurrency:  utils.USD,
+		QuoteCurrency: utils.EUR,
+		Rate:          utils.RandomInt(1, utils.ExchangeRateScale),
+	}
+
+	rate1, err := testQueries.UpsertExchangeRate(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.BaseCurrency, rate1.BaseCurrency)
+	require.Equal(t, arg.QuoteCurrency, rate1.QuoteCurrency)
+	require.Equal(t, arg.Rate, rate1.Rate)
+	require.NotZero(t, rate1.UpdatedAt)
+
+	// 同一币种对再次写入时更新汇率
+	arg.Rate++
+	rate2, err := testQueries.UpsertExchangeRate(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Rate, rate2.Rate)
+
+	rate3, err := testQueries.GetExchangeRate(context.Background(), GetExchangeRateParams{
+		BaseCurrency:  arg.BaseCurrency,
+		QuoteCurrency: arg.QuoteCurrency,
+	})
+	require.NoError(t, err)
+	require.Equal(t, rate2, rate3)
+}
+
+func TestGetExchangeRateNotFound(t *testing.T) {
+	_, err := testQueries.GetExchangeRate(context.Background(), GetExchangeRateParams{
+		BaseCurrency:  utils.USD,
+		QuoteCurrency: utils.USD,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
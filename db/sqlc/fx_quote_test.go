@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"simplebank/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func CreateRandomFxQuote(t *testing.T, user User, fromCurrency, toCurrency string, expiredAt time.Time) FxQuote {
+	arg := CreateFxQuoteParams{
+		Username:     user.Username,
+		FromCurrency: fromCurrency,
+		ToCurrency:   toCurrency,
+		Rate:         utils.RandomInt(1, 10*utils.ExchangeRateScale),
+		ExpiredAt:    expiredAt,
+	}
+
+	quote, err := testQueries.CreateFxQuote(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, quote)
+
+	require.NotZero(t, quote.ID)
+	require.Equal(t, arg.Username, quote.Username)
+	require.Equal(t, arg.FromCurrency, quote.FromCurrency)
+	require.Equal(t, arg.ToCurrency, quote.ToCurrency)
+	require.Equal(t, arg.Rate, quote.Rate)
+	require.WithinDuration(t, arg.ExpiredAt, quote.ExpiredAt, time.Second)
+	require.False(t, quote.UsedAt.Valid)
+	require.NotZero(t, quote.CreatedAt)
+
+	return quote
+}
+
+func TestCreateFxQuote(t *testing.T) {
+	user := CreateRandomUser(t)
+	CreateRandomFxQuote(t, user, utils.USD, utils.RMB, time.Now().Add(time.Minute))
+}
+
+func TestGetFxQuote(t *testing.T) {
+	user := CreateRandomUser(t)
+	quote1 := CreateRandomFxQuote(t, user, utils.USD, utils.RMB, time.Now().Add(time.Minute))
+
+	quote2, err := testQueries.GetFxQuote(context.Background(), quote1.ID)
+	require.NoError(t, err)
+	require.Equal(t, quote1, quote2)
+}
+
+func TestUseFxQuote(t *testing.T) {
+	user := CreateRandomUser(t)
+	quote1 := CreateRandomFxQuote(t, user, utils.USD, utils.RMB, time.Now().Add(time.Minute))
+
+	quote2, err := testQueries.UseFxQuote(context.Background(), quote1.ID)
+	require.NoError(t, err)
+	require.True(t, quote2.UsedAt.Valid)
+	require.WithinDuration(t, time.Now(), quote2.UsedAt.Time, time.Second)
+
+	// 报价只能使用一次
+	_, err = testQueries.UseFxQuote(context.Background(), quote1.ID)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestUseFxQuoteExpired(t *testing.T) {
+	user := CreateRandomUser(t)
+	quote := CreateRandomFxQuote(t, user, utils.USD, utils.RMB, time.Now().Add(-time.Second))
+
+	_, err := testQueries.UseFxQuote(context.Background(), quote.ID)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"simplebank/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func CreateRandomIdempotencyKey(t *testing.T, user User, expiredAt time.Time) IdempotencyKey {
+	arg := CreateIdempotencyKeyParams{
+		Username:       user.Username,
+		IdempotencyKey: utils.RandomString(16),
+		RequestHash:    utils.RandomString(64),
+		ExpiredAt:      expiredAt,
+	}
+
+	key, err := testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, key)
+
+	require.Equal(t, arg.Username, key.Username)
+	require.Equal(t, arg.IdempotencyKey, key.IdempotencyKey)
+	require.Equal(t, arg.RequestHash, key.RequestHash)
+	require.WithinDuration(t, arg.ExpiredAt, key.ExpiredAt, time.Second)
+	// 新创建的 key 处于处理中
+	require.False(t, key.ResponseCode.Valid)
+	require.Empty(t, key.ResponseBody)
+	require.NotZero(t, key.CreatedAt)
+
+	return key
+}
+
+func TestCreateIdempotencyKey(t *testing.T) {
+	user := CreateRandomUser(t)
+	CreateRandomIdempotencyKey(t, user, time.Now().Add(time.Hour))
+}
+
+func TestCreateIdempotencyKeyConflict(t *testing.T) {
+	user := CreateRandomUser(t)
+	key1 := CreateRandomIdempotencyKey(t, user, time.Now().Add(time.Hour))
+
+	// 未过期的 key 不能被重复创建
+	_, err := testQueries.CreateIdempotencyKey(context.Background(), CreateIdempotencyKeyParams{
+		Username:       user.Username,
+		IdempotencyKey: key1.IdempotencyKey,
+		RequestHash:    utils.RandomString(64),
+		ExpiredAt:      time.Now().Add(time.Hour),
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	// 不同用户之间的 key 互不影响
+	user2 := CreateRandomUser(t)
+	key2, err := testQueries.CreateIdempotencyKey(context.Background(), CreateIdempotencyKeyParams{
+		Username:       user2.Username,
+		IdempotencyKey: key1.IdempotencyKey,
+		RequestHash:    utils.RandomString(64),
+		ExpiredAt:      time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, user2.Username, key2.Username)
+}
+
+func TestCreateIdempotencyKeyExpired(t *testing.T) {
+	user := CreateRandomUser(t)
+	key1 := CreateRandomIdempotencyKey(t, user, time.Now().Add(-time.Minute))
+
+	// 过期的 key 可以重新使用
+	arg := CreateIdempotencyKeyParams{
+		Username:       user.Username,
+		IdempotencyKey: key1.IdempotencyKey,
+		RequestHash:    utils.RandomString(64),
+		ExpiredAt:      time.Now().Add(time.Hour),
+	}
+	key2, err := testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.RequestHash, key2.RequestHash)
+	require.False(t, key2.ResponseCode.Valid)
+}
+
+func TestUpdateIdempotencyKeyResponse(t *testing.T) {
+	user := CreateRandomUser(t)
+	key1 := CreateRandomIdempotencyKey(t, user, time.Now().Add(time.Hour))
+
+	arg := UpdateIdempotencyKeyResponseParams{
+		Username:       key1.Username,
+		IdempotencyKey: key1.IdempotencyKey,
+		ResponseCode:   sql.NullInt32{Int32: 200, Valid: true},
+		ResponseBody:   []byte(`{"id":1}`),
+	}
+	key2, err := testQueries.UpdateIdempotencyKeyResponse(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ResponseCode, key2.ResponseCode)
+	require.Equal(t, arg.ResponseBody, key2.ResponseBody)
+
+	key3, err := testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username:       key1.Username,
+		IdempotencyKey: key1.IdempotencyKey,
+	})
+	require.NoError(t, err)
+	require.Equal(t, key2, key3)
+}
+
+func TestDeleteIdempotencyKey(t *testing.T) {
+	user := CreateRandomUser(t)
+	key1 := CreateRandomIdempotencyKey(t, user, time.Now().Add(time.Hour))
+
+	err := testQueries.DeleteIdempotencyKey(context.Background(), DeleteIdempotencyKeyParams{
+		Username:       key1.Username,
+		IdempotencyKey: key1.IdempotencyKey,
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username:       key1.Username,
+		IdempotencyKey: key1.IdempotencyKey,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestDeleteExpiredIdempotencyKeys(t *testing.T) {
+	user := CreateRandomUser(t)
+	expired := CreateRandomIdempotencyKey(t, user, time.Now().Add(-time.Minute))
+	active := CreateRandomIdempotencyKey(t, user, time.Now().Add(time.Hour))
+
+	count, err := testQueries.DeleteExpiredIdempotencyKeys(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, count, int64(1))
+
+	_, err = testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username:       user.Username,
+		IdempotencyKey: expired.IdempotencyKey,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	_, err = testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username:       user.Username,
+		IdempotencyKey: active.IdempotencyKey,
+	})
+	require.NoError(t, err)
+}
@@ -6,9 +6,181 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"time"
+
+	"github.com/google/uuid"
+)
+
+type AccountStatus string
+
+const (
+	AccountStatusActive AccountStatus = "active"
+	AccountStatusFrozen AccountStatus = "frozen"
+	AccountStatusClosed AccountStatus = "closed"
+)
+
+func (e *AccountStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = AccountStatus(s)
+	case string:
+		*e = AccountStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for AccountStatus: %T", src)
+	}
+	return nil
+}
+
+type AdjustmentReason string
+
+const (
+	AdjustmentReasonCorrection AdjustmentReason = "correction"
+	AdjustmentReasonFee        AdjustmentReason = "fee"
+	AdjustmentReasonRefund     AdjustmentReason = "refund"
+	AdjustmentReasonChargeback AdjustmentReason = "chargeback"
+	AdjustmentReasonWriteOff   AdjustmentReason = "write_off"
+)
+
+func (e *AdjustmentReason) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = AdjustmentReason(s)
+	case string:
+		*e = AdjustmentReason(s)
+	default:
+		return fmt.Errorf("unsupported scan type for AdjustmentReason: %T", src)
+	}
+	return nil
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending   DeliveryStatus = "pending"
+	DeliveryStatusSucceeded DeliveryStatus = "succeeded"
+	DeliveryStatusDead      DeliveryStatus = "dead"
+)
+
+func (e *DeliveryStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = DeliveryStatus(s)
+	case string:
+		*e = DeliveryStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for DeliveryStatus: %T", src)
+	}
+	return nil
+}
+
+type EntryType string
+
+const (
+	EntryTypeTransferDebit  EntryType = "transfer_debit"
+	EntryTypeTransferCredit EntryType = "transfer_credit"
+	EntryTypeDeposit        EntryType = "deposit"
+	EntryTypeWithdrawal     EntryType = "withdrawal"
+	EntryTypeFee            EntryType = "fee"
+	EntryTypeInterest       EntryType = "interest"
+	EntryTypeAdjustment     EntryType = "adjustment"
+	EntryTypeReversal       EntryType = "reversal"
+)
+
+func (e *EntryType) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = EntryType(s)
+	case string:
+		*e = EntryType(s)
+	default:
+		return fmt.Errorf("unsupported scan type for EntryType: %T", src)
+	}
+	return nil
+}
+
+type ExecutionStatus string
+
+const (
+	ExecutionStatusSucceeded ExecutionStatus = "succeeded"
+	ExecutionStatusFailed    ExecutionStatus = "failed"
+)
+
+func (e *ExecutionStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ExecutionStatus(s)
+	case string:
+		*e = ExecutionStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ExecutionStatus: %T", src)
+	}
+	return nil
+}
+
+type ReversalStatus string
+
+const (
+	ReversalStatusNone    ReversalStatus = "none"
+	ReversalStatusPartial ReversalStatus = "partial"
+	ReversalStatusFull    ReversalStatus = "full"
+)
+
+func (e *ReversalStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ReversalStatus(s)
+	case string:
+		*e = ReversalStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ReversalStatus: %T", src)
+	}
+	return nil
+}
+
+type ScheduledTransferStatus string
+
+const (
+	ScheduledTransferStatusActive    ScheduledTransferStatus = "active"
+	ScheduledTransferStatusPaused    ScheduledTransferStatus = "paused"
+	ScheduledTransferStatusCompleted ScheduledTransferStatus = "completed"
+	ScheduledTransferStatusCancelled ScheduledTransferStatus = "cancelled"
+)
+
+func (e *ScheduledTransferStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ScheduledTransferStatus(s)
+	case string:
+		*e = ScheduledTransferStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ScheduledTransferStatus: %T", src)
+	}
+	return nil
+}
+
+type UserRole string
+
+const (
+	UserRoleCustomer UserRole = "customer"
+	UserRoleSupport  UserRole = "support"
+	UserRoleAdmin    UserRole = "admin"
+	UserRoleAuditor  UserRole = "auditor"
 )
 
+func (e *UserRole) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = UserRole(s)
+	case string:
+		*e = UserRole(s)
+	default:
+		return fmt.Errorf("unsupported scan type for UserRole: %T", src)
+	}
+	return nil
+}
+
 type Account struct {
 	ID    int64  `json:"id"`
 	Owner string `json:"owner"`
@@ -17,6 +189,38 @@ type Account struct {
 	// 币种
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// 透支额度，余额最低可为 -overdraft_limit
+	OverdraftLimit int64 `json:"overdraft_limit"`
+	// 只有 active 的账户可以转入或转出，closed 为终态
+	Status   AccountStatus `json:"status"`
+	ClosedAt sql.NullTime  `json:"closed_at"`
+}
+
+type AccountAdjustment struct {
+	ID        int64 `json:"id"`
+	AccountID int64 `json:"account_id"`
+	EntryID   int64 `json:"entry_id"`
+	// 调账金额，允许正负，与对应流水的金额相同
+	Amount     int64            `json:"amount"`
+	ReasonCode AdjustmentReason `json:"reason_code"`
+	Note       string           `json:"note"`
+	// 执行调账的管理员
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AuditLog struct {
+	ID        int64    `json:"id"`
+	Actor     string   `json:"actor"`
+	ActorRole UserRole `json:"actor_role"`
+	// 请求方法和路由，例如 POST /admin/accounts/:id/freeze
+	Action string `json:"action"`
+	// 实际请求的路径，包含操作对象的 ID
+	Path       string          `json:"path"`
+	StatusCode int32           `json:"status_code"`
+	ClientIp   string          `json:"client_ip"`
+	Details    json.RawMessage `json:"details"`
+	CreatedAt  time.Time       `json:"created_at"`
 }
 
 type Entry struct {
@@ -25,6 +229,106 @@ type Entry struct {
 	// 变更金额，允许正负
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// 产生该流水的转账，非转账流水为空
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	EntryType  EntryType     `json:"entry_type"`
+	Memo       string        `json:"memo"`
+}
+
+type ExchangeRate struct {
+	BaseCurrency  string `json:"base_currency"`
+	QuoteCurrency string `json:"quote_currency"`
+	// 1 单位 base_currency 可兑换的 quote_currency 数量 × 10^8
+	Rate      int64     `json:"rate"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type FxQuote struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	FromCurrency string    `json:"from_currency"`
+	ToCurrency   string    `json:"to_currency"`
+	// 锁定的汇率 × 10^8
+	Rate int64 `json:"rate"`
+	// 报价只能使用一次
+	UsedAt    sql.NullTime `json:"used_at"`
+	ExpiredAt time.Time    `json:"expired_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type IdempotencyKey struct {
+	Username       string `json:"username"`
+	IdempotencyKey string `json:"idempotency_key"`
+	// 请求内容摘要，同一个 key 只能用于相同的请求
+	RequestHash string `json:"request_hash"`
+	// 为空表示请求仍在处理中
+	ResponseCode sql.NullInt32 `json:"response_code"`
+	ResponseBody []byte        `json:"response_body"`
+	CreatedAt    time.Time     `json:"created_at"`
+	ExpiredAt    time.Time     `json:"expired_at"`
+}
+
+type Outbox struct {
+	ID        int64  `json:"id"`
+	EventType string `json:"event_type"`
+	// 事件相关的用户，事件投递到该用户注册的 webhook
+	Username string          `json:"username"`
+	Payload  json.RawMessage `json:"payload"`
+	// 为空时尚未分发到 webhook
+	PublishedAt sql.NullTime `json:"published_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+type RevokedToken struct {
+	// token payload ID
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	// token 过期后无需再记录
+	ExpiredAt time.Time `json:"expired_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64  `json:"id"`
+	Owner         string `json:"owner"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	// 每次转账的金额，必须为正数
+	Amount int64  `json:"amount"`
+	Memo   string `json:"memo"`
+	// 标准 cron 表达式，为空时只在 next_run_at 执行一次
+	CronExpr string `json:"cron_expr"`
+	// 下一次执行的时间，失败重试时为重试时间
+	NextRunAt time.Time               `json:"next_run_at"`
+	Status    ScheduledTransferStatus `json:"status"`
+	// 连续因余额不足失败的次数，成功后清零
+	FailureCount int32     `json:"failure_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type ScheduledTransferExecution struct {
+	ID                  int64 `json:"id"`
+	ScheduledTransferID int64 `json:"scheduled_transfer_id"`
+	// 执行成功时生成的转账
+	TransferID sql.NullInt64   `json:"transfer_id"`
+	Status     ExecutionStatus `json:"status"`
+	Error      string          `json:"error"`
+	// 本次执行对应的计划时间
+	ScheduledAt time.Time `json:"scheduled_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Session struct {
+	// 与 refresh token 的 payload ID 相同
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	RefreshToken string    `json:"refresh_token"`
+	UserAgent    string    `json:"user_agent"`
+	ClientIp     string    `json:"client_ip"`
+	// 被封禁的会话不能再刷新 access token
+	IsBlocked bool      `json:"is_blocked"`
+	ExpiredAt time.Time `json:"expired_at"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Transfer struct {
@@ -34,6 +338,29 @@ type Transfer struct {
 	// 转账金额，必须为正
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// 转入账户入账金额（转入账户币种）
+	ToAmount int64 `json:"to_amount"`
+	// 汇率 × 10^8，同币种转账为 1
+	ExchangeRate   int64          `json:"exchange_rate"`
+	FxQuoteID      uuid.NullUUID  `json:"fx_quote_id"`
+	ReversalStatus ReversalStatus `json:"reversal_status"`
+	// 已退回转出账户的金额（转出账户币种）
+	ReversedAmount int64 `json:"reversed_amount"`
+	// 已从转入账户扣回的金额（转入账户币种），不能超过 to_amount
+	ReversedToAmount int64 `json:"reversed_to_amount"`
+}
+
+type TransferReversal struct {
+	ID         int64 `json:"id"`
+	TransferID int64 `json:"transfer_id"`
+	// 退回转出账户的金额（转出账户币种）
+	Amount int64 `json:"amount"`
+	// 从转入账户扣回的金额（转入账户币种）
+	ToAmount int64  `json:"to_amount"`
+	Reason   string `json:"reason"`
+	// 发起冲正的收款人或管理员
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type User struct {
@@ -43,4 +370,30 @@ type User struct {
 	Email             string       `json:"email"`
 	PasswordChangedAt sql.NullTime `json:"password_changed_at"`
 	CreatedAt         time.Time    `json:"created_at"`
+	// 在此时间之前签发的 token 全部失效
+	TokensRevokedAt sql.NullTime `json:"tokens_revoked_at"`
+	Role            UserRole     `json:"role"`
+}
+
+type Webhook struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	Url   string `json:"url"`
+	// 用于 HMAC-SHA256 签名的密钥
+	Secret    string    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID        int64 `json:"id"`
+	WebhookID int64 `json:"webhook_id"`
+	EventID   int64 `json:"event_id"`
+	// 重试次数用尽后为 dead，不再投递
+	Status   DeliveryStatus `json:"status"`
+	Attempts int32          `json:"attempts"`
+	// 下一次投递的时间，投递中时为租约到期时间
+	NextAttemptAt time.Time    `json:"next_attempt_at"`
+	LastError     string       `json:"last_error"`
+	DeliveredAt   sql.NullTime `json:"delivered_at"`
+	CreatedAt     time.Time    `json:"created_at"`
 }
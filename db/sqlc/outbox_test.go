@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func CreateRandomOutboxEvent(t *testing.T, user User) Outbox {
+	arg := CreateOutboxEventParams{
+		EventType: EventAccountCreated,
+		Username:  user.Username,
+		Payload:   json.RawMessage(`{"id":1}`),
+	}
+
+	event, err := testQueries.CreateOutboxEvent(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, event)
+
+	require.Equal(t, arg.EventType, event.EventType)
+	require.Equal(t, arg.Username, event.Username)
+	require.JSONEq(t, string(arg.Payload), string(event.Payload))
+	// 新写入的事件尚未分发
+	require.False(t, event.PublishedAt.Valid)
+	require.NotZero(t, event.CreatedAt)
+
+	return event
+}
+
+func TestCreateOutboxEvent(t *testing.T) {
+	CreateRandomOutboxEvent(t, CreateRandomUser(t))
+}
+
+func TestMarkOutboxEventPublished(t *testing.T) {
+	event1 := CreateRandomOutboxEvent(t, CreateRandomUser(t))
+
+	err := testQueries.MarkOutboxEventPublished(context.Background(), event1.ID)
+	require.NoError(t, err)
+
+	event2, err := testQueries.GetOutboxEvent(context.Background(), event1.ID)
+	require.NoError(t, err)
+	require.True(t, event2.PublishedAt.Valid)
+	require.Equal(t, event1.Payload, event2.Payload)
+}
@@ -6,24 +6,105 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type Querier interface {
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	// 只能封禁自己的会话，会话不存在或不属于该用户时返回 sql.ErrNoRows
+	BlockSession(ctx context.Context, arg BlockSessionParams) (Session, error)
+	BlockUserSessions(ctx context.Context, username string) error
+	// 领取到期的投递并延后 next_attempt_at 作为租约，投递失败或进程退出时租约到期后会被重新领取
+	ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]WebhookDelivery, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAccountAdjustment(ctx context.Context, arg CreateAccountAdjustmentParams) (AccountAdjustment, error)
+	CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateFxQuote(ctx context.Context, arg CreateFxQuoteParams) (FxQuote, error)
+	// 已存在且未过期的 key 不会被覆盖，此时返回 sql.ErrNoRows
+	CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (IdempotencyKey, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (Outbox, error)
+	CreateRevokedToken(ctx context.Context, arg CreateRevokedTokenParams) error
+	CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error)
+	CreateScheduledTransferExecution(ctx context.Context, arg CreateScheduledTransferExecutionParams) (ScheduledTransferExecution, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
+	CreateTransferReversal(ctx context.Context, arg CreateTransferReversalParams) (TransferReversal, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
-	DeleteAccount(ctx context.Context, arg DeleteAccountParams) error
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	// 为用户注册的每个 webhook 创建一条待投递记录
+	CreateWebhookDeliveries(ctx context.Context, arg CreateWebhookDeliveriesParams) (int64, error)
+	// 过期的 key 不会再用于重放，由后台任务定期清理
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error)
+	DeleteExpiredRevokedTokens(ctx context.Context) error
+	DeleteIdempotencyKey(ctx context.Context, arg DeleteIdempotencyKeyParams) error
 	DeleteUser(ctx context.Context, username string) error
+	DeleteWebhook(ctx context.Context, id int64) error
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	// 跳过其他 worker 已锁定的记录，多个实例可以同时执行
+	GetDueScheduledTransferForUpdate(ctx context.Context, now time.Time) (ScheduledTransfer, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetExchangeRate(ctx context.Context, arg GetExchangeRateParams) (ExchangeRate, error)
+	GetFxQuote(ctx context.Context, id uuid.UUID) (FxQuote, error)
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	GetLastAccountEntryID(ctx context.Context, accountID int64) (int64, error)
+	GetOutboxEvent(ctx context.Context, id int64) (Outbox, error)
+	GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error)
+	GetScheduledTransferForUpdate(ctx context.Context, id int64) (ScheduledTransfer, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetWebhook(ctx context.Context, id int64) (Webhook, error)
+	ListAccountAdjustments(ctx context.Context, arg ListAccountAdjustmentsParams) ([]AccountAdjustment, error)
+	// 按 id 分批返回账户余额与流水合计，after_id 为上一批最后一个账户的 id
+	ListAccountBalances(ctx context.Context, arg ListAccountBalancesParams) ([]ListAccountBalancesRow, error)
+	// 按 (created_at, id) 倒序做游标分页，可选的过滤条件为空时不生效
+	ListAccountEntries(ctx context.Context, arg ListAccountEntriesParams) ([]Entry, error)
+	// 按 id 顺序返回 after_id 之后的流水，用于推送新流水和断线续传
+	ListAccountEntriesAfter(ctx context.Context, arg ListAccountEntriesAfterParams) ([]Entry, error)
+	// 按 (created_at, id) 倒序做游标分页，可选的过滤条件为空时不生效
+	// 转出和转入分别沿 (from_account_id, created_at, id) 和 (to_account_id, created_at, id) 索引各取 limit 条，合并后再取前 limit 条
+	// 金额过滤使用本账户币种的金额：转出为 amount，转入为 to_amount
+	ListAccountTransfers(ctx context.Context, arg ListAccountTransfersParams) ([]Transfer, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	// actor 为空时返回全部记录
+	ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([]AuditLog, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	// 已过期的 token 无论是否吊销都无法通过验证
+	ListRevokedTokens(ctx context.Context) ([]RevokedToken, error)
+	ListScheduledTransferExecutions(ctx context.Context, arg ListScheduledTransferExecutionsParams) ([]ScheduledTransferExecution, error)
+	ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ScheduledTransfer, error)
+	ListTransferEntries(ctx context.Context, transferID sql.NullInt64) ([]Entry, error)
+	// 按 id 分批返回每笔转账对应的扣账、入账流水数量
+	ListTransferEntryCounts(ctx context.Context, arg ListTransferEntryCountsParams) ([]ListTransferEntryCountsRow, error)
+	ListTransferReversals(ctx context.Context, transferID int64) ([]TransferReversal, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
-	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	// 跳过其他 dispatcher 正在分发的事件
+	ListUnpublishedOutboxEvents(ctx context.Context, limit int32) ([]Outbox, error)
+	// 只返回在 $1 之后吊销的用户，更早的吊销不会影响未过期的 token
+	ListUserTokenRevocations(ctx context.Context, tokensRevokedAt sql.NullTime) ([]ListUserTokenRevocationsRow, error)
+	// status 为空时返回全部记录，dead 为死信
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhooks(ctx context.Context, arg ListWebhooksParams) ([]Webhook, error)
+	MarkOutboxEventPublished(ctx context.Context, id int64) error
+	// 通知在 account_activity 频道上监听的连接，事务提交后才会送达
+	NotifyAccountActivity(ctx context.Context, accountID int64) error
+	RevokeUserTokens(ctx context.Context, arg RevokeUserTokensParams) (User, error)
+	UpdateAccountOverdraftLimit(ctx context.Context, arg UpdateAccountOverdraftLimitParams) (Account, error)
+	UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error)
+	UpdateIdempotencyKeyResponse(ctx context.Context, arg UpdateIdempotencyKeyResponseParams) (IdempotencyKey, error)
+	UpdateScheduledTransfer(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error)
+	UpdateTransferReversal(ctx context.Context, arg UpdateTransferReversalParams) (Transfer, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	UpdateWebhookDelivery(ctx context.Context, arg UpdateWebhookDeliveryParams) (WebhookDelivery, error)
+	UpsertExchangeRate(ctx context.Context, arg UpsertExchangeRateParams) (ExchangeRate, error)
+	// 报价只能使用一次，已使用或已过期时返回 sql.ErrNoRows
+	UseFxQuote(ctx context.Context, id uuid.UUID) (FxQuote, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"simplebank/utils"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAccountBalances(t *testing.T) {
+	store := NewStore(testDb)
+
+	user := CreateRandomUser(t)
+	account1 := createCurrencyAccount(t, user, utils.RMB, 0)
+	account2 := createCurrencyAccount(t, user, utils.USD, 0)
+
+	_, err := store.AdjustAccountTx(context.Background(), AdjustAccountTxParams{
+		AccountID:  account1.ID,
+		Amount:     100,
+		ReasonCode: AdjustmentReasonCorrection,
+		Actor:      user.Username,
+	})
+	require.NoError(t, err)
+
+	// 绕过流水直接修改余额，余额与流水合计不一致
+	_, err = testQueries.AddAccountBalance(context.Background(), AddAccountBalanceParams{
+		ID:     account2.ID,
+		Amount: 10,
+	})
+	require.NoError(t, err)
+
+	rows, err := testQueries.ListAccountBalances(context.Background(), ListAccountBalancesParams{
+		AfterID: account1.ID - 1,
+		Limit:   2,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, ListAccountBalancesRow{ID: account1.ID, Balance: 100, EntriesTotal: 100}, rows[0])
+	require.Equal(t, ListAccountBalancesRow{ID: account2.ID, Balance: 10, EntriesTotal: 0}, rows[1])
+}
+
+func TestListTransferEntryCounts(t *testing.T) {
+	store := NewStore(testDb)
+
+	account1 := createFundedAccount(t, 10)
+	account2 := createFundedAccount(t, 10)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	rows, err := testQueries.ListTransferEntryCounts(context.Background(), ListTransferEntryCountsParams{
+		AfterID: result.Transfer.ID - 1,
+		Limit:   1,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, result.Transfer.ID, rows[0].ID)
+	require.Equal(t, int64(1), rows[0].DebitEntries)
+	require.Equal(t, int64(1), rows[0].CreditEntries)
+}
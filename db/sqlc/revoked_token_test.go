@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevokedTokens(t *testing.T) {
+	user := CreateRandomUser(t)
+
+	arg := CreateRevokedTokenParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		ExpiredAt: time.Now().Add(time.Minute),
+	}
+	err := testQueries.CreateRevokedToken(context.Background(), arg)
+	require.NoError(t, err)
+
+	// 重复吊销同一个 token 不会报错
+	err = testQueries.CreateRevokedToken(context.Background(), arg)
+	require.NoError(t, err)
+
+	expired := CreateRevokedTokenParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		ExpiredAt: time.Now().Add(-time.Minute),
+	}
+	err = testQueries.CreateRevokedToken(context.Background(), expired)
+	require.NoError(t, err)
+
+	// 只返回未过期的 token
+	tokens, err := testQueries.ListRevokedTokens(context.Background())
+	require.NoError(t, err)
+
+	ids := make(map[uuid.UUID]bool)
+	for _, token := range tokens {
+		ids[token.ID] = true
+	}
+	require.True(t, ids[arg.ID])
+	require.False(t, ids[expired.ID])
+
+	err = testQueries.DeleteExpiredRevokedTokens(context.Background())
+	require.NoError(t, err)
+
+	tokens, err = testQueries.ListRevokedTokens(context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, tokens)
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func CreateRandomScheduledTransfer(t *testing.T, fromAccount, toAccount Account, nextRunAt time.Time) ScheduledTransfer {
+	arg := CreateScheduledTransferParams{
+		Owner:         fromAccount.Owner,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        10,
+		Memo:          "rent",
+		CronExpr:      "@monthly",
+		NextRunAt:     nextRunAt,
+	}
+
+	scheduled, err := testQueries.CreateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, scheduled.ID)
+	require.Equal(t, arg.Owner, scheduled.Owner)
+	require.Equal(t, arg.FromAccountID, scheduled.FromAccountID)
+	require.Equal(t, arg.ToAccountID, scheduled.ToAccountID)
+	require.Equal(t, arg.Amount, scheduled.Amount)
+	require.Equal(t, arg.Memo, scheduled.Memo)
+	require.Equal(t, arg.CronExpr, scheduled.CronExpr)
+	require.WithinDuration(t, arg.NextRunAt, scheduled.NextRunAt, time.Second)
+	require.Equal(t, ScheduledTransferStatusActive, scheduled.Status)
+	require.Zero(t, scheduled.FailureCount)
+	require.NotZero(t, scheduled.CreatedAt)
+
+	return scheduled
+}
+
+func TestCreateScheduledTransfer(t *testing.T) {
+	CreateRandomScheduledTransfer(t, CreateRandomAccount(t), CreateRandomAccount(t), time.Now().Add(time.Hour))
+}
+
+func TestGetScheduledTransfer(t *testing.T) {
+	scheduled1 := CreateRandomScheduledTransfer(t, CreateRandomAccount(t), CreateRandomAccount(t), time.Now().Add(time.Hour))
+
+	scheduled2, err := testQueries.GetScheduledTransfer(context.Background(), scheduled1.ID)
+	require.NoError(t, err)
+	require.Equal(t, scheduled1, scheduled2)
+}
+
+func TestListScheduledTransfers(t *testing.T) {
+	account := CreateRandomAccount(t)
+	for i := 0; i < 3; i++ {
+		CreateRandomScheduledTransfer(t, account, CreateRandomAccount(t), time.Now().Add(time.Hour))
+	}
+
+	scheduledTransfers, err := testQueries.ListScheduledTransfers(context.Background(), ListScheduledTransfersParams{
+		Owner:  account.Owner,
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, scheduledTransfers, 3)
+	for _, scheduled := range scheduledTransfers {
+		require.Equal(t, account.Owner, scheduled.Owner)
+	}
+}
+
+func TestScheduledTransferExecutions(t *testing.T) {
+	scheduled := CreateRandomScheduledTransfer(t, CreateRandomAccount(t), CreateRandomAccount(t), time.Now())
+
+	arg := CreateScheduledTransferExecutionParams{
+		ScheduledTransferID: scheduled.ID,
+		TransferID:          sql.NullInt64{},
+		Status:              ExecutionStatusFailed,
+		Error:               "insufficient funds",
+		ScheduledAt:         scheduled.NextRunAt,
+	}
+	execution, err := testQueries.CreateScheduledTransferExecution(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ScheduledTransferID, execution.ScheduledTransferID)
+	require.False(t, execution.TransferID.Valid)
+	require.Equal(t, arg.Status, execution.Status)
+	require.Equal(t, arg.Error, execution.Error)
+	require.WithinDuration(t, arg.ScheduledAt, execution.ScheduledAt, time.Second)
+
+	executions, err := testQueries.ListScheduledTransferExecutions(context.Background(), ListScheduledTransferExecutionsParams{
+		ScheduledTransferID: scheduled.ID,
+		Limit:               5,
+		Offset:              0,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []ScheduledTransferExecution{execution}, executions)
+}
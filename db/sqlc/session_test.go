@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"simplebank/utils"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func CreateRandomSession(t *testing.T, user User) Session {
+	arg := CreateSessionParams{
+		ID:           uuid.New(),
+		Username:     user.Username,
+		RefreshToken: utils.RandomString(32),
+		UserAgent:    "Mozilla/5.0",
+		ClientIp:     "127.0.0.1",
+		IsBlocked:    false,
+		ExpiredAt:    time.Now().Add(time.Hour),
+	}
+
+	session, err := testQueries.CreateSession(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, session)
+
+	require.Equal(t, arg.ID, session.ID)
+	require.Equal(t, arg.Username, session.Username)
+	require.Equal(t, arg.RefreshToken, session.RefreshToken)
+	require.Equal(t, arg.UserAgent, session.UserAgent)
+	require.Equal(t, arg.ClientIp, session.ClientIp)
+	require.False(t, session.IsBlocked)
+	require.WithinDuration(t, arg.ExpiredAt, session.ExpiredAt, time.Second)
+	require.NotZero(t, session.CreatedAt)
+
+	return session
+}
+
+func TestCreateSession(t *testing.T) {
+	user := CreateRandomUser(t)
+	CreateRandomSession(t, user)
+}
+
+func TestGetSession(t *testing.T) {
+	user := CreateRandomUser(t)
+	session1 := CreateRandomSession(t, user)
+
+	session2, err := testQueries.GetSession(context.Background(), session1.ID)
+	require.NoError(t, err)
+	require.Equal(t, session1, session2)
+}
+
+func TestBlockSession(t *testing.T) {
+	user := CreateRandomUser(t)
+	session1 := CreateRandomSession(t, user)
+
+	// 其他用户不能封禁该会话
+	other := CreateRandomUser(t)
+	_, err := testQueries.BlockSession(context.Background(), BlockSessionParams{
+		ID:       session1.ID,
+		Username: other.Username,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	session2, err := testQueries.BlockSession(context.Background(), BlockSessionParams{
+		ID:       session1.ID,
+		Username: user.Username,
+	})
+	require.NoError(t, err)
+	require.True(t, session2.IsBlocked)
+	require.Equal(t, session1.RefreshToken, session2.RefreshToken)
+}
+
+func TestBlockUserSessions(t *testing.T) {
+	user := CreateRandomUser(t)
+	session1 := CreateRandomSession(t, user)
+	session2 := CreateRandomSession(t, user)
+
+	other := CreateRandomUser(t)
+	session3 := CreateRandomSession(t, other)
+
+	err := testQueries.BlockUserSessions(context.Background(), user.Username)
+	require.NoError(t, err)
+
+	for _, session := range []Session{session1, session2} {
+		got, err := testQueries.GetSession(context.Background(), session.ID)
+		require.NoError(t, err)
+		require.True(t, got.IsBlocked)
+	}
+
+	// 其他用户的会话不受影响
+	got, err := testQueries.GetSession(context.Background(), session3.ID)
+	require.NoError(t, err)
+	require.False(t, got.IsBlocked)
+}
@@ -3,59 +3,178 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"simplebank/logger"
+	"simplebank/metrics"
+	"simplebank/utils"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInsufficientFunds 转出账户余额（含透支额度）不足以完成转账
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrInvalidQuote 汇率报价不存在、已过期、已使用或与转账账户不匹配
+	ErrInvalidQuote = errors.New("invalid fx quote")
+	// ErrAccountNotActive 转出或转入账户已被冻结或已销户
+	ErrAccountNotActive = errors.New("account is not active")
+	// ErrInvalidStatusTransition 账户当前状态不允许变更为目标状态
+	ErrInvalidStatusTransition = errors.New("invalid account status transition")
+	// ErrNonZeroBalance 销户时账户余额不为零
+	ErrNonZeroBalance = errors.New("account balance is not zero")
+	// ErrInvalidReversal 冲正金额超过转账剩余可冲正的金额，或转账已全部冲正
+	ErrInvalidReversal = errors.New("invalid reversal amount")
+	// ErrScheduledTransferFinished 定时转账已完成或已取消，不能再修改
+	ErrScheduledTransferFinished = errors.New("scheduled transfer is completed or cancelled")
+	// ErrScheduledTransferInternal 定时转账因内部错误执行失败，记录在执行记录中，原始错误只写入日志
+	ErrScheduledTransferInternal = errors.New("internal error")
+)
+
+// outbox 中的事件类型
+const (
+	EventUserCreated      = "user.created"
+	EventAccountCreated   = "account.created"
+	EventTransferCreated  = "transfer.created"
+	EventTransferReversed = "transfer.reversed"
 )
 
 // Store 提供了所有数据库转账相关方法
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	FXTransferTx(ctx context.Context, arg FXTransferTxParams) (TransferTxResult, error)
+	UpdateAccountStatusTx(ctx context.Context, arg UpdateAccountStatusTxParams) (Account, error)
+	AdjustAccountTx(ctx context.Context, arg AdjustAccountTxParams) (AdjustAccountTxResult, error)
+	ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error)
+	UpdateScheduledTransferTx(ctx context.Context, arg UpdateScheduledTransferTxParams) (ScheduledTransfer, error)
+	ExecuteScheduledTransferTx(ctx context.Context, arg ExecuteScheduledTransferTxParams) (ExecuteScheduledTransferTxResult, error)
+	CreateUserTx(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error)
+	PublishOutboxTx(ctx context.Context, limit int32) (int, error)
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (version int64, dirty bool, err error)
 }
 
 // SQLStore 提供了所有操作 SQL 转账的相关方法
 type SQLStore struct {
-	*Queries         // 组合 sqlc 生成的单个数据库操作
-	db       *sql.DB // 用于执行事务
+	*Queries          // 组合 sqlc 生成的单个数据库操作
+	db       *sql.DB  // 用于执行事务
+	txConfig TxConfig // 事务的隔离级别和重试策略
 }
 
 // NewStore creates a new Store
 func NewStore(db *sql.DB) Store {
+	return NewStoreWithTxConfig(db, DefaultTxConfig)
+}
+
+// NewStoreWithTxConfig 使用指定的事务配置创建 Store，MaxAttempts 小于 1 时不重试
+func NewStoreWithTxConfig(db *sql.DB, txConfig TxConfig) Store {
 	return &SQLStore{
-		db:      db,
-		Queries: New(db),
+		db:       db,
+		Queries:  New(newTracedDBTX(db, nil)),
+		txConfig: txConfig,
 	}
 }
 
-// execTx 使用事务执行一个数据库操作的方法
+// Ping 检查数据库连接是否可用
+func (stroe *SQLStore) Ping(ctx context.Context) error {
+	return stroe.db.PingContext(ctx)
+}
+
+// SchemaVersion 返回 golang-migrate 在 schema_migrations 中记录的迁移版本
+// dirty 为 true 表示上一次迁移执行失败，需要人工处理
+func (stroe *SQLStore) SchemaVersion(ctx context.Context) (version int64, dirty bool, err error) {
+	err = stroe.db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	return
+}
+
+// execTx 使用数据库默认的隔离级别（READ COMMITTED）执行事务
 func (stroe *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
-	// 开始事务，使用默认的读隔离
-	tx, err := stroe.db.BeginTx(ctx, nil)
+	return stroe.execTxWithIsolation(ctx, sql.LevelDefault, fn)
+}
+
+// transferIsolation 返回资金变动事务的隔离级别，调用方没有指定时使用 TxConfig.TransferIsolation
+func (stroe *SQLStore) transferIsolation(isolation sql.IsolationLevel) sql.IsolationLevel {
+	if isolation != sql.LevelDefault {
+		return isolation
+	}
+	return stroe.txConfig.TransferIsolation
+}
+
+// execTxWithIsolation 使用指定的隔离级别执行事务，序列化失败或死锁时重新执行整个事务
+// 重试时 fn 会被再次调用，fn 中不能有事务以外的副作用，写入的结果变量会被覆盖
+func (stroe *SQLStore) execTxWithIsolation(ctx context.Context, isolation sql.IsolationLevel, fn func(*Queries) error) error {
+	return retryTx(ctx, stroe.txConfig.MaxAttempts, stroe.txConfig.RetryBackoff, func(attempt int) error {
+		return stroe.runTx(ctx, isolation, attempt, fn)
+	})
+}
+
+// runTx 执行一次事务，fn 返回错误时回退
+func (stroe *SQLStore) runTx(ctx context.Context, isolation sql.IsolationLevel, attempt int, fn func(*Queries) error) (err error) {
+	ctx, span := startTxSpan(ctx, isolation, attempt)
+	defer func() {
+		recordSpanError(span, err)
+		span.End()
+	}()
+
+	tx, err := stroe.db.BeginTx(ctx, &sql.TxOptions{Isolation: isolation})
 	if err != nil {
 		return err
 	}
 
 	// 执行数据库操作
-	q := New(tx)
+	q := New(newTracedDBTX(tx, span))
 	err = fn(q)
 	if err != nil {
 		// 数据库转账操作失败，事务回退
 		// 如果事务回退也报错，将两个报错合并返回
 		if rbErr := tx.Rollback(); rbErr != nil {
-			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
+			metrics.Transactions.WithLabelValues(metrics.TxOutcomeRollbackFailed).Inc()
+			logger.Ctx(ctx).Error().Err(rbErr).AnErr("tx_error", err).Msg("cannot roll back transaction")
+			return fmt.Errorf("tx err: %w, rb err: %v", err, rbErr)
 		}
 
+		// 余额不足等业务错误也会回退，只在 debug 级别记录
+		metrics.Transactions.WithLabelValues(metrics.TxOutcomeRolledBack).Inc()
+		logger.Ctx(ctx).Debug().Err(err).Msg("transaction rolled back")
 		return err
 	}
 
 	// 事务操作成功，提交事务
-	return tx.Commit()
+	// SERIALIZABLE 下提交时也可能发生序列化失败，由 retryTx 重试并记录日志
+	if err = commitTx(ctx, tx); err != nil {
+		metrics.Transactions.WithLabelValues(metrics.TxOutcomeCommitFailed).Inc()
+		if !isRetryableTxError(err) {
+			logger.Ctx(ctx).Error().Err(err).Msg("cannot commit transaction")
+		}
+		return err
+	}
+	metrics.Transactions.WithLabelValues(metrics.TxOutcomeCommitted).Inc()
+	return nil
 }
 
 // 转账所需参数
 type TransferTxParams struct {
-	FromAccountId int64 `json:"from_account_id"`
-	ToAccountId   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountId int64  `json:"from_account_id"`
+	ToAccountId   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Memo          string `json:"memo"`
+	// Isolation 事务的隔离级别，为 sql.LevelDefault 时使用 TxConfig.TransferIsolation
+	Isolation sql.IsolationLevel `json:"isolation"`
+}
+
+// 跨币种转账所需参数，Amount 为转出账户币种的金额
+type FXTransferTxParams struct {
+	FromAccountId int64     `json:"from_account_id"`
+	ToAccountId   int64     `json:"to_account_id"`
+	Amount        int64     `json:"amount"`
+	QuoteID       uuid.UUID `json:"quote_id"`
+	Memo          string    `json:"memo"`
+	// Isolation 事务的隔离级别，为 sql.LevelDefault 时使用 TxConfig.TransferIsolation
+	Isolation sql.IsolationLevel `json:"isolation"`
 }
 
 // 转账操作所有创建的数据库数据
@@ -68,54 +187,826 @@ type TransferTxResult struct {
 }
 
 // 使用事务执行转账操作
-// 包含创建转账记录、扣账记录、入账记录、账户扣账、账户入账
+// 包含余额检查、创建转账记录、扣账记录、入账记录、账户扣账、账户入账
 func (Store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
+	var currency string
 
-	err := Store.execTx(ctx, func(q *Queries) error {
-		var err error
+	err := Store.execTxWithIsolation(ctx, Store.transferIsolation(arg.Isolation), func(q *Queries) error {
+		// 锁定账户后再检查余额，避免并发转账时超额扣款
+		fromAccount, toAccount, err := lockAccounts(ctx, q, arg.FromAccountId, arg.ToAccountId)
+		if err != nil {
+			return err
+		}
+		currency = fromAccount.Currency
 
-		// 创建转账记录
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+		result, err = transfer(ctx, q, fromAccount, toAccount, CreateTransferParams{
 			FromAccountID: arg.FromAccountId,
 			ToAccountID:   arg.ToAccountId,
 			Amount:        arg.Amount,
+			ToAmount:      arg.Amount,
+			ExchangeRate:  utils.ExchangeRateScale,
+		}, arg.Memo)
+		return err
+	})
+
+	observeTransfer(currency, arg.Amount, err)
+	return result, err
+}
+
+// 使用事务执行跨币种转账
+// 按报价锁定的汇率，转出账户以转出币种扣账，转入账户以转入币种入账
+func (Store *SQLStore) FXTransferTx(ctx context.Context, arg FXTransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+	var currency string
+
+	err := Store.execTxWithIsolation(ctx, Store.transferIsolation(arg.Isolation), func(q *Queries) error {
+		fromAccount, toAccount, err := lockAccounts(ctx, q, arg.FromAccountId, arg.ToAccountId)
+		if err != nil {
+			return err
+		}
+		currency = fromAccount.Currency
+
+		// 转账失败时事务回退，报价可以继续使用
+		quote, err := q.UseFxQuote(ctx, arg.QuoteID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("%w: quote [%s] not found, expired or already used", ErrInvalidQuote, arg.QuoteID)
+			}
+			return err
+		}
+
+		if quote.Username != fromAccount.Owner {
+			return fmt.Errorf("%w: quote [%s] doesn't belong to the owner of account [%d]", ErrInvalidQuote, quote.ID, fromAccount.ID)
+		}
+
+		if quote.FromCurrency != fromAccount.Currency || quote.ToCurrency != toAccount.Currency {
+			return fmt.Errorf("%w: quote [%s] is for %s/%s, accounts are %s/%s",
+				ErrInvalidQuote, quote.ID, quote.FromCurrency, quote.ToCurrency, fromAccount.Currency, toAccount.Currency)
+		}
+
+		toAmount := utils.ConvertAmount(arg.Amount, quote.Rate)
+		if toAmount <= 0 {
+			return fmt.Errorf("%w: amount %d is too small to convert", ErrInvalidQuote, arg.Amount)
+		}
+
+		result, err = transfer(ctx, q, fromAccount, toAccount, CreateTransferParams{
+			FromAccountID: arg.FromAccountId,
+			ToAccountID:   arg.ToAccountId,
+			Amount:        arg.Amount,
+			ToAmount:      toAmount,
+			ExchangeRate:  quote.Rate,
+			FxQuoteID:     uuid.NullUUID{UUID: quote.ID, Valid: true},
+		}, arg.Memo)
+		return err
+	})
+
+	observeTransfer(currency, arg.Amount, err)
+	return result, err
+}
+
+// observeTransfer 记录转账结果和成功转账的金额，账户不存在时币种未知
+func observeTransfer(currency string, amount int64, err error) {
+	if len(currency) == 0 {
+		currency = "unknown"
+	}
+
+	metrics.Transfers.WithLabelValues(currency, transferOutcome(err)).Inc()
+	if err == nil {
+		metrics.TransferAmount.WithLabelValues(currency).Add(float64(amount))
+	}
+}
+
+func transferOutcome(err error) string {
+	switch {
+	case err == nil:
+		return metrics.TransferOutcomeSuccess
+	case errors.Is(err, ErrInsufficientFunds):
+		return metrics.TransferOutcomeInsufficientFunds
+	case errors.Is(err, ErrAccountNotActive):
+		return metrics.TransferOutcomeAccountNotActive
+	case errors.Is(err, ErrInvalidQuote):
+		return metrics.TransferOutcomeInvalidQuote
+	case errors.Is(err, sql.ErrNoRows):
+		return metrics.TransferOutcomeAccountNotFound
+	default:
+		return metrics.TransferOutcomeError
+	}
+}
+
+// 账户状态变更所需参数
+type UpdateAccountStatusTxParams struct {
+	AccountID int64         `json:"account_id"`
+	Status    AccountStatus `json:"status"`
+}
+
+// 允许的账户状态变更，closed 为终态
+var accountStatusTransitions = map[AccountStatus][]AccountStatus{
+	AccountStatusActive: {AccountStatusFrozen, AccountStatusClosed},
+	AccountStatusFrozen: {AccountStatusActive},
+}
+
+// 使用事务变更账户状态
+// 锁定账户后检查状态变更是否允许，销户时要求余额为零
+func (Store *SQLStore) UpdateAccountStatusTx(ctx context.Context, arg UpdateAccountStatusTxParams) (Account, error) {
+	var result Account
+
+	err := Store.execTx(ctx, func(q *Queries) error {
+		// 与转账使用相同的行锁，避免销户时有转账正在进行
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if !canTransitAccountStatus(account.Status, arg.Status) {
+			return fmt.Errorf("%w: account [%d] cannot change from %s to %s", ErrInvalidStatusTransition, account.ID, account.Status, arg.Status)
+		}
+
+		var closedAt sql.NullTime
+		if arg.Status == AccountStatusClosed {
+			if account.Balance != 0 {
+				return fmt.Errorf("%w: account [%d] balance %d", ErrNonZeroBalance, account.ID, account.Balance)
+			}
+			closedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		}
+
+		result, err = q.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+			ID:       account.ID,
+			Status:   arg.Status,
+			ClosedAt: closedAt,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+func canTransitAccountStatus(from, to AccountStatus) bool {
+	for _, status := range accountStatusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// 调账所需参数，Amount 为正时入账，为负时扣账
+type AdjustAccountTxParams struct {
+	AccountID  int64            `json:"account_id"`
+	Amount     int64            `json:"amount"`
+	ReasonCode AdjustmentReason `json:"reason_code"`
+	Note       string           `json:"note"`
+	Actor      string           `json:"actor"`
+	// Isolation 事务的隔离级别，为 sql.LevelDefault 时使用 TxConfig.TransferIsolation
+	Isolation sql.IsolationLevel `json:"isolation"`
+}
+
+// 调账操作所有创建的数据库数据
+type AdjustAccountTxResult struct {
+	Adjustment AccountAdjustment `json:"adjustment"`
+	Account    Account           `json:"account"`
+	Entry      Entry             `json:"entry"`
+}
+
+// 使用事务执行调账
+// 与转账一样通过流水变更余额，保证 entries 的合计始终等于账户余额
+func (Store *SQLStore) AdjustAccountTx(ctx context.Context, arg AdjustAccountTxParams) (AdjustAccountTxResult, error) {
+	var result AdjustAccountTxResult
+
+	err := Store.execTxWithIsolation(ctx, Store.transferIsolation(arg.Isolation), func(q *Queries) error {
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		// 冻结的账户允许调账，已销户的账户余额必须保持为零
+		if account.Status == AccountStatusClosed {
+			return fmt.Errorf("%w: account [%d] is closed", ErrAccountNotActive, account.ID)
+		}
+
+		if account.Balance+account.OverdraftLimit+arg.Amount < 0 {
+			return fmt.Errorf("%w: account [%d] balance %d, overdraft limit %d, amount %d",
+				ErrInsufficientFunds, account.ID, account.Balance, account.OverdraftLimit, arg.Amount)
+		}
+
+		result.Entry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    arg.Amount,
+			EntryType: EntryTypeAdjustment,
+			Memo:      arg.Note,
 		})
 		if err != nil {
 			return err
 		}
 
-		// 扣账记录
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountId,
-			Amount:    -arg.Amount,
+		result.Adjustment, err = q.CreateAccountAdjustment(ctx, CreateAccountAdjustmentParams{
+			AccountID:  account.ID,
+			EntryID:    result.Entry.ID,
+			Amount:     arg.Amount,
+			ReasonCode: arg.ReasonCode,
+			Note:       arg.Note,
+			Actor:      arg.Actor,
 		})
 		if err != nil {
 			return err
 		}
 
-		// 入账记录
+		result.Account, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
+			ID:     account.ID,
+			Amount: arg.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		return q.NotifyAccountActivity(ctx, account.ID)
+	})
+
+	return result, err
+}
+
+// 冲正所需参数
+// ToAmount 为从转入账户扣回的金额（转入账户币种），为 0 时冲正剩余的全部金额
+type ReverseTransferTxParams struct {
+	TransferID int64  `json:"transfer_id"`
+	ToAmount   int64  `json:"to_amount"`
+	Reason     string `json:"reason"`
+	Actor      string `json:"actor"`
+	// Isolation 事务的隔离级别，为 sql.LevelDefault 时使用 TxConfig.TransferIsolation
+	Isolation sql.IsolationLevel `json:"isolation"`
+}
+
+// 冲正操作所有创建或更新的数据库数据
+type ReverseTransferTxResult struct {
+	Reversal    TransferReversal `json:"reversal"`
+	Transfer    Transfer         `json:"transfer"`
+	FromAccount Account          `json:"from_account"`
+	ToAccount   Account          `json:"to_account"`
+	FromEntry   Entry            `json:"from_entry"`
+	ToEntry     Entry            `json:"to_entry"`
+}
+
+// 使用事务冲正一笔转账
+// 从原转入账户扣回 ToAmount，按原转账的比例退回原转出账户，冲正流水关联到原转账
+func (Store *SQLStore) ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error) {
+	var result ReverseTransferTxResult
+
+	err := Store.execTxWithIsolation(ctx, Store.transferIsolation(arg.Isolation), func(q *Queries) error {
+		// 锁定转账记录，避免并发冲正的合计超过原金额
+		transfer, err := q.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			return err
+		}
+
+		remainingToAmount := transfer.ToAmount - transfer.ReversedToAmount
+		if remainingToAmount <= 0 {
+			return fmt.Errorf("%w: transfer [%d] has already been fully reversed", ErrInvalidReversal, transfer.ID)
+		}
+
+		toAmount := arg.ToAmount
+		if toAmount == 0 {
+			toAmount = remainingToAmount
+		}
+		if toAmount < 0 || toAmount > remainingToAmount {
+			return fmt.Errorf("%w: transfer [%d] can reverse at most %d, requested %d",
+				ErrInvalidReversal, transfer.ID, remainingToAmount, toAmount)
+		}
+
+		// 最后一次冲正退回剩余的全部金额，避免跨币种折算的舍入误差
+		amount := transfer.Amount - transfer.ReversedAmount
+		if toAmount < remainingToAmount {
+			amount = utils.ProrateAmount(toAmount, transfer.Amount, transfer.ToAmount)
+		}
+		if amount <= 0 {
+			return fmt.Errorf("%w: amount %d is too small to reverse", ErrInvalidReversal, toAmount)
+		}
+
+		fromAccount, toAccount, err := lockAccounts(ctx, q, transfer.FromAccountID, transfer.ToAccountID)
+		if err != nil {
+			return err
+		}
+
+		if err = checkAccountsActive(fromAccount, toAccount); err != nil {
+			return err
+		}
+
+		if toAccount.Balance+toAccount.OverdraftLimit < toAmount {
+			return fmt.Errorf("%w: account [%d] balance %d, overdraft limit %d, amount %d",
+				ErrInsufficientFunds, toAccount.ID, toAccount.Balance, toAccount.OverdraftLimit, toAmount)
+		}
+
+		result.Reversal, err = q.CreateTransferReversal(ctx, CreateTransferReversalParams{
+			TransferID: transfer.ID,
+			Amount:     amount,
+			ToAmount:   toAmount,
+			Reason:     arg.Reason,
+			Actor:      arg.Actor,
+		})
+		if err != nil {
+			return err
+		}
+
+		// 冲正方向与原转账相反
 		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountId,
-			Amount:    arg.Amount,
+			AccountID:  toAccount.ID,
+			Amount:     -toAmount,
+			TransferID: sql.NullInt64{Int64: transfer.ID, Valid: true},
+			EntryType:  EntryTypeReversal,
+			Memo:       arg.Reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID:  fromAccount.ID,
+			Amount:     amount,
+			TransferID: sql.NullInt64{Int64: transfer.ID, Valid: true},
+			EntryType:  EntryTypeReversal,
+			Memo:       arg.Reason,
 		})
 		if err != nil {
 			return err
 		}
 
-		// 规避死锁问题，让 id 值更小的账户先执行
-		if arg.FromAccountId < arg.ToAccountId {
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountId, -arg.Amount, arg.ToAccountId, arg.Amount)
+		if fromAccount.ID < toAccount.ID {
+			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, fromAccount.ID, amount, toAccount.ID, -toAmount)
 		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountId, arg.Amount, arg.FromAccountId, -arg.Amount)
+			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, toAccount.ID, -toAmount, fromAccount.ID, amount)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = notifyAccounts(ctx, q, fromAccount.ID, toAccount.ID); err != nil {
+			return err
+		}
+
+		status := ReversalStatusPartial
+		if toAmount == remainingToAmount {
+			status = ReversalStatusFull
+		}
+
+		result.Transfer, err = q.UpdateTransferReversal(ctx, UpdateTransferReversalParams{
+			ID:               transfer.ID,
+			ReversedAmount:   transfer.ReversedAmount + amount,
+			ReversedToAmount: transfer.ReversedToAmount + toAmount,
+			ReversalStatus:   status,
+		})
+		if err != nil {
+			return err
+		}
+
+		return writeOutboxEvent(ctx, q, EventTransferReversed, result.Reversal, fromAccount.Owner, toAccount.Owner)
+	})
+
+	return result, err
+}
+
+// 修改定时转账所需参数，Valid 为 false 或 Status 为空的字段保持不变
+type UpdateScheduledTransferTxParams struct {
+	ID        int64                   `json:"id"`
+	Amount    sql.NullInt64           `json:"amount"`
+	Memo      sql.NullString          `json:"memo"`
+	CronExpr  sql.NullString          `json:"cron_expr"`
+	NextRunAt sql.NullTime            `json:"next_run_at"`
+	Status    ScheduledTransferStatus `json:"status"`
+}
+
+// 使用事务修改定时转账
+// 与 worker 使用相同的行锁，避免修改覆盖正在进行的执行结果
+func (Store *SQLStore) UpdateScheduledTransferTx(ctx context.Context, arg UpdateScheduledTransferTxParams) (ScheduledTransfer, error) {
+	var result ScheduledTransfer
+
+	err := Store.execTx(ctx, func(q *Queries) error {
+		scheduled, err := q.GetScheduledTransferForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		if scheduled.Status == ScheduledTransferStatusCompleted || scheduled.Status == ScheduledTransferStatusCancelled {
+			return fmt.Errorf("%w: scheduled transfer [%d] status is %s", ErrScheduledTransferFinished, scheduled.ID, scheduled.Status)
+		}
+
+		update := UpdateScheduledTransferParams{
+			ID:           scheduled.ID,
+			Amount:       scheduled.Amount,
+			Memo:         scheduled.Memo,
+			CronExpr:     scheduled.CronExpr,
+			NextRunAt:    scheduled.NextRunAt,
+			Status:       scheduled.Status,
+			FailureCount: scheduled.FailureCount,
+		}
+		if arg.Amount.Valid {
+			update.Amount = arg.Amount.Int64
+		}
+		if arg.Memo.Valid {
+			update.Memo = arg.Memo.String
 		}
 
+		now := time.Now()
+		if arg.CronExpr.Valid {
+			update.CronExpr = arg.CronExpr.String
+			if len(update.CronExpr) > 0 {
+				update.NextRunAt, err = utils.NextRunTime(update.CronExpr, now)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if arg.NextRunAt.Valid {
+			update.NextRunAt = arg.NextRunAt.Time
+		}
+
+		// 恢复暂停的定时转账时清零失败次数，错过的执行不再补偿
+		if scheduled.Status == ScheduledTransferStatusPaused && arg.Status == ScheduledTransferStatusActive {
+			update.FailureCount = 0
+			if update.NextRunAt.Before(now) {
+				update.NextRunAt = now
+				if len(update.CronExpr) > 0 {
+					update.NextRunAt, err = utils.NextRunTime(update.CronExpr, now)
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if len(arg.Status) > 0 {
+			update.Status = arg.Status
+		}
+
+		result, err = q.UpdateScheduledTransfer(ctx, update)
 		return err
 	})
 
 	return result, err
 }
 
+// 执行定时转账所需参数
+// 连续余额不足时每隔 RetryInterval 重试，失败次数达到 MaxFailures 后暂停
+type ExecuteScheduledTransferTxParams struct {
+	Now           time.Time     `json:"now"`
+	MaxFailures   int32         `json:"max_failures"`
+	RetryInterval time.Duration `json:"retry_interval"`
+	// Isolation 事务的隔离级别，为 sql.LevelDefault 时使用 TxConfig.TransferIsolation
+	Isolation sql.IsolationLevel `json:"isolation"`
+}
+
+// 定时转账的执行结果，执行失败时 Transfer 为空
+type ExecuteScheduledTransferTxResult struct {
+	ScheduledTransfer ScheduledTransfer          `json:"scheduled_transfer"`
+	Execution         ScheduledTransferExecution `json:"execution"`
+	Transfer          TransferTxResult           `json:"transfer"`
+}
+
+// 使用事务执行一笔到期的定时转账
+// 跳过其他 worker 已锁定的记录，没有到期的记录时返回 sql.ErrNoRows
+// 转账、执行记录和下一次执行时间在同一个事务中提交
+// 其他错误导致事务回退时，在单独的事务中记录失败，避免这条记录一直排在最前面阻塞其他定时转账
+func (Store *SQLStore) ExecuteScheduledTransferTx(ctx context.Context, arg ExecuteScheduledTransferTxParams) (ExecuteScheduledTransferTxResult, error) {
+	var result ExecuteScheduledTransferTxResult
+	var claimedID int64
+
+	err := Store.execTxWithIsolation(ctx, Store.transferIsolation(arg.Isolation), func(q *Queries) error {
+		claimedID = 0
+		scheduled, err := q.GetDueScheduledTransferForUpdate(ctx, arg.Now)
+		if err != nil {
+			return err
+		}
+		claimedID = scheduled.ID
+
+		fromAccount, toAccount, err := lockAccounts(ctx, q, scheduled.FromAccountID, scheduled.ToAccountID)
+		if err != nil {
+			return err
+		}
+
+		// transfer 在写入任何数据之前完成余额和状态检查，业务失败时事务仍然可以继续使用
+		transferResult, err := transfer(ctx, q, fromAccount, toAccount, CreateTransferParams{
+			FromAccountID: scheduled.FromAccountID,
+			ToAccountID:   scheduled.ToAccountID,
+			Amount:        scheduled.Amount,
+			ToAmount:      scheduled.Amount,
+			ExchangeRate:  utils.ExchangeRateScale,
+		}, scheduled.Memo)
+
+		execution := CreateScheduledTransferExecutionParams{
+			ScheduledTransferID: scheduled.ID,
+			ScheduledAt:         scheduled.NextRunAt,
+		}
+		update := newScheduledTransferUpdate(scheduled)
+
+		switch {
+		case err == nil:
+			result.Transfer = transferResult
+			execution.Status = ExecutionStatusSucceeded
+			execution.TransferID = sql.NullInt64{Int64: transferResult.Transfer.ID, Valid: true}
+			update.FailureCount = 0
+
+			// 一次性转账执行后完成，周期转账从当前时间计算下一次执行，错过的执行不再补偿
+			if len(scheduled.CronExpr) == 0 {
+				update.Status = ScheduledTransferStatusCompleted
+			} else {
+				update.NextRunAt, err = utils.NextRunTime(scheduled.CronExpr, arg.Now)
+				if err != nil {
+					return err
+				}
+			}
+		case errors.Is(err, ErrInsufficientFunds):
+			execution.Status = ExecutionStatusFailed
+			execution.Error = err.Error()
+			retryOrPauseScheduledTransfer(&update, arg)
+		case errors.Is(err, ErrAccountNotActive):
+			// 账户被冻结或销户时重试没有意义，直接暂停
+			execution.Status = ExecutionStatusFailed
+			execution.Error = err.Error()
+			update.Status = ScheduledTransferStatusPaused
+		default:
+			return err
+		}
+
+		result.Execution, err = q.CreateScheduledTransferExecution(ctx, execution)
+		if err != nil {
+			return err
+		}
+
+		result.ScheduledTransfer, err = q.UpdateScheduledTransfer(ctx, update)
+		return err
+	})
+
+	// 事务回退后记录没有任何变化，下一轮仍会先领取到它；ctx 被取消时无法记录，留给下一轮处理
+	if err != nil && claimedID != 0 && ctx.Err() == nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("scheduled_transfer_id", claimedID).Msg("cannot execute scheduled transfer")
+
+		result, recordErr := Store.recordScheduledTransferFailure(ctx, claimedID, arg)
+		if recordErr != nil {
+			return result, fmt.Errorf("%w, record failure err: %v", err, recordErr)
+		}
+		return result, nil
+	}
+
+	return result, err
+}
+
+// recordScheduledTransferFailure 在单独的事务中记录一次内部错误导致的执行失败，与余额不足一样推迟或暂停
+// 执行记录对用户可见，不包含原始错误信息
+func (Store *SQLStore) recordScheduledTransferFailure(ctx context.Context, id int64, arg ExecuteScheduledTransferTxParams) (ExecuteScheduledTransferTxResult, error) {
+	var result ExecuteScheduledTransferTxResult
+
+	err := Store.execTxWithIsolation(ctx, Store.transferIsolation(arg.Isolation), func(q *Queries) error {
+		scheduled, err := q.GetScheduledTransferForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		result.ScheduledTransfer = scheduled
+		// 期间可能已被取消、暂停或由其他实例执行
+		if scheduled.Status != ScheduledTransferStatusActive || scheduled.NextRunAt.After(arg.Now) {
+			return nil
+		}
+
+		result.Execution, err = q.CreateScheduledTransferExecution(ctx, CreateScheduledTransferExecutionParams{
+			ScheduledTransferID: scheduled.ID,
+			ScheduledAt:         scheduled.NextRunAt,
+			Status:              ExecutionStatusFailed,
+			Error:               ErrScheduledTransferInternal.Error(),
+		})
+		if err != nil {
+			return err
+		}
+
+		update := newScheduledTransferUpdate(scheduled)
+		retryOrPauseScheduledTransfer(&update, arg)
+		result.ScheduledTransfer, err = q.UpdateScheduledTransfer(ctx, update)
+		return err
+	})
+
+	return result, err
+}
+
+// newScheduledTransferUpdate 返回保持定时转账不变的更新参数
+func newScheduledTransferUpdate(scheduled ScheduledTransfer) UpdateScheduledTransferParams {
+	return UpdateScheduledTransferParams{
+		ID:           scheduled.ID,
+		Amount:       scheduled.Amount,
+		Memo:         scheduled.Memo,
+		CronExpr:     scheduled.CronExpr,
+		NextRunAt:    scheduled.NextRunAt,
+		Status:       scheduled.Status,
+		FailureCount: scheduled.FailureCount,
+	}
+}
+
+// retryOrPauseScheduledTransfer 失败次数加一，达到 MaxFailures 后暂停，否则在 RetryInterval 之后重试
+func retryOrPauseScheduledTransfer(update *UpdateScheduledTransferParams, arg ExecuteScheduledTransferTxParams) {
+	update.FailureCount++
+	if update.FailureCount >= arg.MaxFailures {
+		update.Status = ScheduledTransferStatusPaused
+	} else {
+		update.NextRunAt = arg.Now.Add(arg.RetryInterval)
+	}
+}
+
+// 事务中创建用户，并写入 user.created 事件
+func (Store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserParams) (User, error) {
+	var result User
+
+	err := Store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = q.CreateUser(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		// 事件中不包含密码
+		return writeOutboxEvent(ctx, q, EventUserCreated, map[string]interface{}{
+			"username":   result.Username,
+			"full_name":  result.FullName,
+			"email":      result.Email,
+			"created_at": result.CreatedAt,
+		}, result.Username)
+	})
+
+	return result, err
+}
+
+// 事务中创建账户，并写入 account.created 事件
+func (Store *SQLStore) CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	var result Account
+
+	err := Store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = q.CreateAccount(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		return writeOutboxEvent(ctx, q, EventAccountCreated, result, result.Owner)
+	})
+
+	return result, err
+}
+
+// 使用事务把未分发的 outbox 事件展开为每个 webhook 的投递记录，返回分发的事件数量
+// 事件与投递记录在同一个事务中提交，多个 dispatcher 不会重复分发
+func (Store *SQLStore) PublishOutboxTx(ctx context.Context, limit int32) (int, error) {
+	var count int
+
+	err := Store.execTx(ctx, func(q *Queries) error {
+		events, err := q.ListUnpublishedOutboxEvents(ctx, limit)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			_, err = q.CreateWebhookDeliveries(ctx, CreateWebhookDeliveriesParams{
+				EventID: event.ID,
+				Owner:   event.Username,
+			})
+			if err != nil {
+				return err
+			}
+
+			if err = q.MarkOutboxEventPublished(ctx, event.ID); err != nil {
+				return err
+			}
+		}
+
+		count = len(events)
+		return nil
+	})
+
+	return count, err
+}
+
+// 在当前事务中为每个相关用户写入一条 outbox 事件，重复的用户只写一次
+func writeOutboxEvent(ctx context.Context, q *Queries, eventType string, data interface{}, usernames ...string) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(usernames))
+	for _, username := range usernames {
+		if seen[username] {
+			continue
+		}
+		seen[username] = true
+
+		_, err = q.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+			EventType: eventType,
+			Username:  username,
+			Payload:   payload,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 在已锁定账户的事务中完成转账
+// 转出账户扣除 arg.Amount，转入账户增加 arg.ToAmount，两条流水都关联到转账记录
+func transfer(ctx context.Context, q *Queries, fromAccount, toAccount Account, arg CreateTransferParams, memo string) (result TransferTxResult, err error) {
+	if err = checkAccountsActive(fromAccount, toAccount); err != nil {
+		return
+	}
+
+	if fromAccount.Balance+fromAccount.OverdraftLimit < arg.Amount {
+		err = fmt.Errorf("%w: account [%d] balance %d, overdraft limit %d, amount %d",
+			ErrInsufficientFunds, fromAccount.ID, fromAccount.Balance, fromAccount.OverdraftLimit, arg.Amount)
+		return
+	}
+
+	// 创建转账记录
+	result.Transfer, err = q.CreateTransfer(ctx, arg)
+	if err != nil {
+		return
+	}
+
+	// 扣账记录
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  arg.FromAccountID,
+		Amount:     -arg.Amount,
+		TransferID: sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+		EntryType:  EntryTypeTransferDebit,
+		Memo:       memo,
+	})
+	if err != nil {
+		return
+	}
+
+	// 入账记录
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  arg.ToAccountID,
+		Amount:     arg.ToAmount,
+		TransferID: sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+		EntryType:  EntryTypeTransferCredit,
+		Memo:       memo,
+	})
+	if err != nil {
+		return
+	}
+
+	// 规避死锁问题，让 id 值更小的账户先执行
+	if arg.FromAccountID < arg.ToAccountID {
+		result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.ToAmount)
+	} else {
+		result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.ToAmount, arg.FromAccountID, -arg.Amount)
+	}
+	if err != nil {
+		return
+	}
+
+	if err = notifyAccounts(ctx, q, arg.FromAccountID, arg.ToAccountID); err != nil {
+		return
+	}
+
+	// 事件与转账在同一个事务中提交，转账成功时事件一定存在
+	err = writeOutboxEvent(ctx, q, EventTransferCreated, result.Transfer, fromAccount.Owner, toAccount.Owner)
+	return
+}
+
+// 通知订阅了账户动态的连接有新的流水，通知在事务提交后送达，回滚时不会发出
+func notifyAccounts(ctx context.Context, q *Queries, accountIDs ...int64) error {
+	for _, accountID := range accountIDs {
+		if err := q.NotifyAccountActivity(ctx, accountID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 只有 active 的账户可以转入或转出
+func checkAccountsActive(accounts ...Account) error {
+	for _, account := range accounts {
+		if account.Status != AccountStatusActive {
+			return fmt.Errorf("%w: account [%d] status is %s", ErrAccountNotActive, account.ID, account.Status)
+		}
+	}
+	return nil
+}
+
+// 锁定转账涉及的两个账户
+// 与 addMoney 相同，按 id 从小到大加锁以规避死锁
+func lockAccounts(ctx context.Context, q *Queries, fromAccountID, toAccountID int64) (fromAccount, toAccount Account, err error) {
+	if fromAccountID < toAccountID {
+		fromAccount, err = q.GetAccountForUpdate(ctx, fromAccountID)
+		if err != nil {
+			return
+		}
+		toAccount, err = q.GetAccountForUpdate(ctx, toAccountID)
+		return
+	}
+
+	toAccount, err = q.GetAccountForUpdate(ctx, toAccountID)
+	if err != nil {
+		return
+	}
+	fromAccount, err = q.GetAccountForUpdate(ctx, fromAccountID)
+	return
+}
+
 // 实现两个账户的余额操作（转账）
 func addMoney(ctx context.Context, q *Queries, accountID1, amount1, accountID2, amount2 int64) (account1, account2 Account, err error) {
 	account1, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
@@ -2,16 +2,36 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"simplebank/utils"
 	"testing"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 )
 
+// 创建一个余额至少为 minBalance 的随机账户，保证转账不会因余额不足失败
+func createFundedAccount(t *testing.T, minBalance int64) Account {
+	account := CreateRandomAccount(t)
+
+	account, err := testQueries.AddAccountBalance(context.Background(), AddAccountBalanceParams{
+		ID:     account.ID,
+		Amount: minBalance,
+	})
+	require.NoError(t, err)
+	require.True(t, account.Balance >= minBalance)
+
+	return account
+}
+
 func TestTransferTx(t *testing.T) {
 	store := NewStore(testDb)
 
-	account1 := CreateRandomAccount(t)
+	account1 := createFundedAccount(t, 50)
 	account2 := CreateRandomAccount(t)
 	fmt.Println(">> befor:", account1.Balance, account2.Balance)
 
@@ -29,6 +49,7 @@ func TestTransferTx(t *testing.T) {
 				FromAccountId: account1.ID,
 				ToAccountId:   account2.ID,
 				Amount:        amount,
+				Memo:          "rent",
 			})
 			errs <- err
 			results <- result
@@ -61,6 +82,9 @@ func TestTransferTx(t *testing.T) {
 		require.NotEmpty(t, fromEntry)
 		require.Equal(t, account1.ID, fromEntry.AccountID)
 		require.Equal(t, -amount, fromEntry.Amount)
+		require.Equal(t, EntryTypeTransferDebit, fromEntry.EntryType)
+		require.Equal(t, sql.NullInt64{Int64: transfer.ID, Valid: true}, fromEntry.TransferID)
+		require.Equal(t, "rent", fromEntry.Memo)
 		require.NotZero(t, fromEntry.ID)
 		require.NotZero(t, fromEntry.CreatedAt)
 
@@ -71,12 +95,19 @@ func TestTransferTx(t *testing.T) {
 		require.NotEmpty(t, toEntry)
 		require.Equal(t, account2.ID, toEntry.AccountID)
 		require.Equal(t, amount, toEntry.Amount)
+		require.Equal(t, EntryTypeTransferCredit, toEntry.EntryType)
+		require.Equal(t, sql.NullInt64{Int64: transfer.ID, Valid: true}, toEntry.TransferID)
+		require.Equal(t, "rent", toEntry.Memo)
 		require.NotZero(t, toEntry.ID)
 		require.NotZero(t, toEntry.CreatedAt)
 
 		_, err = store.GetEntry(context.Background(), toEntry.ID)
 		require.NoError(t, err)
 
+		transferEntries, err := store.ListTransferEntries(context.Background(), fromEntry.TransferID)
+		require.NoError(t, err)
+		require.Len(t, transferEntries, 2)
+
 		// check accounts
 		fromAccount := result.FromAccount
 		require.NotEmpty(t, fromAccount)
@@ -120,8 +151,8 @@ func TestTransferTx(t *testing.T) {
 func TestTransferTxDeadLock(t *testing.T) {
 	store := NewStore(testDb)
 
-	account1 := CreateRandomAccount(t)
-	account2 := CreateRandomAccount(t)
+	account1 := createFundedAccount(t, 50)
+	account2 := createFundedAccount(t, 50)
 
 	// 使用并发验证事务操作
 	n := 10
@@ -166,3 +197,826 @@ func TestTransferTxDeadLock(t *testing.T) {
 	require.Equal(t, account1.Balance, updatedAccount1.Balance)
 	require.Equal(t, account2.Balance, updatedAccount2.Balance)
 }
+
+// SERIALIZABLE 下并发互转会发生序列化失败，重试后所有转账都应该成功
+func TestTransferTxSerializable(t *testing.T) {
+	store := NewStoreWithTxConfig(testDb, TxConfig{
+		TransferIsolation: sql.LevelSerializable,
+		MaxAttempts:       10,
+		RetryBackoff:      5 * time.Millisecond,
+	})
+
+	account1 := createFundedAccount(t, 50)
+	account2 := createFundedAccount(t, 50)
+
+	n := 10
+	amount := int64(10)
+
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		fromAccountID := account1.ID
+		toAccountId := account2.ID
+
+		if i%2 == 1 {
+			fromAccountID = account2.ID
+			toAccountId = account1.ID
+		}
+
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountId: fromAccountID,
+				ToAccountId:   toAccountId,
+				Amount:        amount,
+			})
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		err := <-errs
+		require.NoError(t, err)
+	}
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+
+	updatedAccount2, err := testQueries.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+}
+
+// 单个转账可以指定比配置更严格的隔离级别
+func TestTransferTxIsolationOverride(t *testing.T) {
+	store := NewStore(testDb)
+
+	account1 := createFundedAccount(t, 50)
+	account2 := createFundedAccount(t, 50)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        10,
+		Isolation:     sql.LevelSerializable,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-10, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+10, result.ToAccount.Balance)
+
+	// 不支持的隔离级别无法开始事务
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        10,
+		Isolation:     sql.LevelSnapshot,
+	})
+	require.Error(t, err)
+}
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDb)
+
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        account1.Balance + 1,
+	})
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrInsufficientFunds))
+
+	// 转账失败，余额不应该变化
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	updatedAccount2, err := testQueries.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+}
+
+func TestTransferTxFrozenAccount(t *testing.T) {
+	store := NewStore(testDb)
+
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	_, err := store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account2.ID,
+		Status:    AccountStatusFrozen,
+	})
+	require.NoError(t, err)
+
+	// 冻结账户既不能转出也不能转入
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        1,
+	})
+	require.True(t, errors.Is(err, ErrAccountNotActive))
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account2.ID,
+		ToAccountId:   account1.ID,
+		Amount:        1,
+	})
+	require.True(t, errors.Is(err, ErrAccountNotActive))
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	updatedAccount2, err := testQueries.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+}
+
+func TestTransferTxOverdraft(t *testing.T) {
+	store := NewStore(testDb)
+
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	overdraftLimit := int64(100)
+	account1, err := testQueries.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             account1.ID,
+		OverdraftLimit: overdraftLimit,
+	})
+	require.NoError(t, err)
+
+	// 透支额度内可以转账
+	amount := account1.Balance + overdraftLimit
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        amount,
+	})
+	require.NoError(t, err)
+	require.Equal(t, -overdraftLimit, result.FromAccount.Balance)
+
+	// 超出透支额度后拒绝转账
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        1,
+	})
+	require.True(t, errors.Is(err, ErrInsufficientFunds))
+}
+
+// 并发转账时不应该超额扣款
+func TestTransferTxConcurrentInsufficientFunds(t *testing.T) {
+	store := NewStore(testDb)
+
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	n := 5
+	amount := account1.Balance/2 + 1
+
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+			})
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.True(t, errors.Is(err, ErrInsufficientFunds))
+	}
+	require.LessOrEqual(t, succeeded, 1)
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.True(t, updatedAccount1.Balance >= 0)
+}
+
+// 创建指定币种的账户
+func createCurrencyAccount(t *testing.T, user User, currency string, balance int64) Account {
+	account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  balance,
+		Currency: currency,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func TestFXTransferTx(t *testing.T) {
+	store := NewStore(testDb)
+
+	user1 := CreateRandomUser(t)
+	user2 := CreateRandomUser(t)
+	account1 := createCurrencyAccount(t, user1, utils.USD, 1000)
+	account2 := createCurrencyAccount(t, user2, utils.RMB, 0)
+	quote := CreateRandomFxQuote(t, user1, utils.USD, utils.RMB, time.Now().Add(time.Minute))
+
+	amount := int64(100)
+	toAmount := utils.ConvertAmount(amount, quote.Rate)
+
+	result, err := store.FXTransferTx(context.Background(), FXTransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        amount,
+		QuoteID:       quote.ID,
+	})
+	require.NoError(t, err)
+
+	transfer := result.Transfer
+	require.Equal(t, amount, transfer.Amount)
+	require.Equal(t, toAmount, transfer.ToAmount)
+	require.Equal(t, quote.Rate, transfer.ExchangeRate)
+	require.Equal(t, quote.ID, transfer.FxQuoteID.UUID)
+	require.True(t, transfer.FxQuoteID.Valid)
+
+	// 转出账户以转出币种扣账，转入账户以转入币种入账
+	require.Equal(t, -amount, result.FromEntry.Amount)
+	require.Equal(t, toAmount, result.ToEntry.Amount)
+	require.Equal(t, account1.Balance-amount, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+toAmount, result.ToAccount.Balance)
+
+	// 报价只能使用一次
+	_, err = store.FXTransferTx(context.Background(), FXTransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        amount,
+		QuoteID:       quote.ID,
+	})
+	require.True(t, errors.Is(err, ErrInvalidQuote))
+}
+
+func TestFXTransferTxInvalidQuote(t *testing.T) {
+	store := NewStore(testDb)
+
+	user1 := CreateRandomUser(t)
+	user2 := CreateRandomUser(t)
+	account1 := createCurrencyAccount(t, user1, utils.USD, 1000)
+	account2 := createCurrencyAccount(t, user2, utils.RMB, 0)
+
+	testCases := []struct {
+		name  string
+		quote FxQuote
+	}{
+		{
+			name:  "CurrencyMismatch",
+			quote: CreateRandomFxQuote(t, user1, utils.USD, utils.EUR, time.Now().Add(time.Minute)),
+		},
+		{
+			name:  "NotOwner",
+			quote: CreateRandomFxQuote(t, user2, utils.USD, utils.RMB, time.Now().Add(time.Minute)),
+		},
+		{
+			name:  "Expired",
+			quote: CreateRandomFxQuote(t, user1, utils.USD, utils.RMB, time.Now().Add(-time.Second)),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := store.FXTransferTx(context.Background(), FXTransferTxParams{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        100,
+				QuoteID:       tc.quote.ID,
+			})
+			require.True(t, errors.Is(err, ErrInvalidQuote))
+
+			// 事务回退，报价没有被使用
+			quote, err := testQueries.GetFxQuote(context.Background(), tc.quote.ID)
+			require.NoError(t, err)
+			require.False(t, quote.UsedAt.Valid)
+		})
+	}
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+}
+
+func TestUpdateAccountStatusTx(t *testing.T) {
+	store := NewStore(testDb)
+	account := createFundedAccount(t, 1)
+
+	testCases := []struct {
+		status AccountStatus
+		err    error
+	}{
+		{status: AccountStatusActive, err: ErrInvalidStatusTransition},
+		{status: AccountStatusFrozen},
+		{status: AccountStatusFrozen, err: ErrInvalidStatusTransition},
+		// 冻结的账户需要先解冻才能销户
+		{status: AccountStatusClosed, err: ErrInvalidStatusTransition},
+		{status: AccountStatusActive},
+		// 余额不为零不能销户
+		{status: AccountStatusClosed, err: ErrNonZeroBalance},
+	}
+
+	for _, tc := range testCases {
+		updatedAccount, err := store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+			AccountID: account.ID,
+			Status:    tc.status,
+		})
+		if tc.err != nil {
+			require.True(t, errors.Is(err, tc.err))
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, tc.status, updatedAccount.Status)
+		require.False(t, updatedAccount.ClosedAt.Valid)
+	}
+
+	_, err := store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID + 1000000,
+		Status:    AccountStatusFrozen,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestCloseAccount(t *testing.T) {
+	store := NewStore(testDb)
+
+	user := CreateRandomUser(t)
+	account1 := createCurrencyAccount(t, user, utils.RMB, 0)
+
+	account2, err := store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account1.ID,
+		Status:    AccountStatusClosed,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusClosed, account2.Status)
+	require.True(t, account2.ClosedAt.Valid)
+
+	// 销户是终态
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account1.ID,
+		Status:    AccountStatusActive,
+	})
+	require.True(t, errors.Is(err, ErrInvalidStatusTransition))
+
+	// 销户后可以重新开立同币种账户
+	account3 := createCurrencyAccount(t, user, utils.RMB, 0)
+	require.NotEqual(t, account1.ID, account3.ID)
+
+	// 已销户的账户不能再转入
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account3.ID,
+		ToAccountId:   account1.ID,
+		Amount:        0,
+	})
+	require.True(t, errors.Is(err, ErrAccountNotActive))
+}
+
+func TestAdjustAccountTx(t *testing.T) {
+	store := NewStore(testDb)
+
+	admin := CreateRandomUser(t)
+	account := createFundedAccount(t, 10)
+
+	arg := AdjustAccountTxParams{
+		AccountID:  account.ID,
+		Amount:     -10,
+		ReasonCode: AdjustmentReasonCorrection,
+		Note:       utils.RandomString(10),
+		Actor:      admin.Username,
+	}
+	result, err := store.AdjustAccountTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, account.ID, result.Entry.AccountID)
+	require.Equal(t, arg.Amount, result.Entry.Amount)
+	require.Equal(t, EntryTypeAdjustment, result.Entry.EntryType)
+	require.Equal(t, arg.Note, result.Entry.Memo)
+
+	require.Equal(t, account.ID, result.Adjustment.AccountID)
+	require.Equal(t, result.Entry.ID, result.Adjustment.EntryID)
+	require.Equal(t, arg.Amount, result.Adjustment.Amount)
+	require.Equal(t, arg.ReasonCode, result.Adjustment.ReasonCode)
+	require.Equal(t, arg.Note, result.Adjustment.Note)
+	require.Equal(t, arg.Actor, result.Adjustment.Actor)
+
+	require.Equal(t, account.Balance+arg.Amount, result.Account.Balance)
+
+	// 余额（含透支额度）不足时不能扣账
+	_, err = store.AdjustAccountTx(context.Background(), AdjustAccountTxParams{
+		AccountID:  account.ID,
+		Amount:     -(result.Account.Balance + 1),
+		ReasonCode: AdjustmentReasonWriteOff,
+		Actor:      admin.Username,
+	})
+	require.True(t, errors.Is(err, ErrInsufficientFunds))
+
+	// 已销户的账户不能调账
+	closedAccount := createCurrencyAccount(t, CreateRandomUser(t), utils.RMB, 0)
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: closedAccount.ID,
+		Status:    AccountStatusClosed,
+	})
+	require.NoError(t, err)
+
+	_, err = store.AdjustAccountTx(context.Background(), AdjustAccountTxParams{
+		AccountID:  closedAccount.ID,
+		Amount:     10,
+		ReasonCode: AdjustmentReasonRefund,
+		Actor:      admin.Username,
+	})
+	require.True(t, errors.Is(err, ErrAccountNotActive))
+}
+
+func TestReverseTransferTx(t *testing.T) {
+	store := NewStore(testDb)
+
+	user1 := CreateRandomUser(t)
+	user2 := CreateRandomUser(t)
+	account1 := createCurrencyAccount(t, user1, utils.RMB, 1000)
+	account2 := createCurrencyAccount(t, user2, utils.RMB, 0)
+
+	transferResult, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        100,
+	})
+	require.NoError(t, err)
+	transfer := transferResult.Transfer
+	require.Equal(t, ReversalStatusNone, transfer.ReversalStatus)
+
+	// 部分冲正
+	arg := ReverseTransferTxParams{
+		TransferID: transfer.ID,
+		ToAmount:   40,
+		Reason:     utils.RandomString(10),
+		Actor:      user2.Username,
+	}
+	result, err := store.ReverseTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, transfer.ID, result.Reversal.TransferID)
+	require.Equal(t, int64(40), result.Reversal.Amount)
+	require.Equal(t, int64(40), result.Reversal.ToAmount)
+	require.Equal(t, arg.Reason, result.Reversal.Reason)
+	require.Equal(t, arg.Actor, result.Reversal.Actor)
+
+	// 冲正流水的方向与原转账相反，并关联到原转账
+	for _, entry := range []Entry{result.FromEntry, result.ToEntry} {
+		require.Equal(t, EntryTypeReversal, entry.EntryType)
+		require.Equal(t, transfer.ID, entry.TransferID.Int64)
+		require.Equal(t, arg.Reason, entry.Memo)
+	}
+	require.Equal(t, account1.ID, result.FromEntry.AccountID)
+	require.Equal(t, int64(40), result.FromEntry.Amount)
+	require.Equal(t, account2.ID, result.ToEntry.AccountID)
+	require.Equal(t, int64(-40), result.ToEntry.Amount)
+
+	require.Equal(t, transferResult.FromAccount.Balance+40, result.FromAccount.Balance)
+	require.Equal(t, transferResult.ToAccount.Balance-40, result.ToAccount.Balance)
+
+	require.Equal(t, ReversalStatusPartial, result.Transfer.ReversalStatus)
+	require.Equal(t, int64(40), result.Transfer.ReversedAmount)
+	require.Equal(t, int64(40), result.Transfer.ReversedToAmount)
+
+	// 冲正金额不能超过剩余的金额
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.ID,
+		ToAmount:   61,
+		Actor:      user2.Username,
+	})
+	require.True(t, errors.Is(err, ErrInvalidReversal))
+
+	// 金额为 0 时冲正剩余的全部金额
+	result, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.ID,
+		Actor:      user2.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(60), result.Reversal.ToAmount)
+	require.Equal(t, ReversalStatusFull, result.Transfer.ReversalStatus)
+	require.Equal(t, transfer.Amount, result.Transfer.ReversedAmount)
+	require.Equal(t, transfer.ToAmount, result.Transfer.ReversedToAmount)
+	require.Equal(t, account1.Balance, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance, result.ToAccount.Balance)
+
+	// 已全部冲正的转账不能再次冲正
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.ID,
+		Actor:      user2.Username,
+	})
+	require.True(t, errors.Is(err, ErrInvalidReversal))
+
+	reversals, err := testQueries.ListTransferReversals(context.Background(), transfer.ID)
+	require.NoError(t, err)
+	require.Len(t, reversals, 2)
+
+	entries, err := testQueries.ListTransferEntries(context.Background(), sql.NullInt64{Int64: transfer.ID, Valid: true})
+	require.NoError(t, err)
+	require.Len(t, entries, 6)
+}
+
+func TestReverseTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDb)
+
+	user1 := CreateRandomUser(t)
+	user2 := CreateRandomUser(t)
+	account1 := createCurrencyAccount(t, user1, utils.RMB, 1000)
+	account2 := createCurrencyAccount(t, user2, utils.RMB, 0)
+
+	transferResult, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        100,
+	})
+	require.NoError(t, err)
+
+	// 收款人已经把钱转走，余额不足以冲正
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account2.ID,
+		ToAccountId:   account1.ID,
+		Amount:        100,
+	})
+	require.NoError(t, err)
+
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transferResult.Transfer.ID,
+		Actor:      user2.Username,
+	})
+	require.True(t, errors.Is(err, ErrInsufficientFunds))
+}
+
+// 暂停其他测试留下的到期定时转账，避免 worker 的测试互相影响
+func pauseDueScheduledTransfers(t *testing.T, now time.Time) {
+	_, err := testDb.Exec("UPDATE scheduled_transfers SET status = 'paused' WHERE status = 'active' AND next_run_at <= $1", now)
+	require.NoError(t, err)
+}
+
+func TestExecuteScheduledTransferTx(t *testing.T) {
+	store := NewStore(testDb)
+	now := time.Now()
+	pauseDueScheduledTransfers(t, now)
+
+	user1 := CreateRandomUser(t)
+	user2 := CreateRandomUser(t)
+	account1 := createCurrencyAccount(t, user1, utils.RMB, 15)
+	account2 := createCurrencyAccount(t, user2, utils.RMB, 0)
+	scheduled := CreateRandomScheduledTransfer(t, account1, account2, now.Add(-time.Minute))
+
+	arg := ExecuteScheduledTransferTxParams{
+		Now:           now,
+		MaxFailures:   2,
+		RetryInterval: time.Hour,
+	}
+
+	// 第一次执行成功，按 cron 表达式计算下一次执行时间
+	result, err := store.ExecuteScheduledTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, scheduled.ID, result.ScheduledTransfer.ID)
+	require.Equal(t, ExecutionStatusSucceeded, result.Execution.Status)
+	require.Equal(t, result.Transfer.Transfer.ID, result.Execution.TransferID.Int64)
+	require.Equal(t, scheduled.Amount, result.Transfer.Transfer.Amount)
+	require.Equal(t, scheduled.Memo, result.Transfer.FromEntry.Memo)
+	require.Equal(t, account1.Balance-scheduled.Amount, result.Transfer.FromAccount.Balance)
+
+	nextRunAt, err := utils.NextRunTime(scheduled.CronExpr, now)
+	require.NoError(t, err)
+	require.WithinDuration(t, nextRunAt, result.ScheduledTransfer.NextRunAt, time.Second)
+	require.Equal(t, ScheduledTransferStatusActive, result.ScheduledTransfer.Status)
+
+	// 没有到期的定时转账
+	_, err = store.ExecuteScheduledTransferTx(context.Background(), arg)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+
+	// 余额不足时按重试间隔重试，连续失败达到上限后暂停
+	arg.Now = nextRunAt.Add(time.Second)
+	pauseDueScheduledTransfers(t, arg.Now)
+	_, err = testQueries.UpdateScheduledTransfer(context.Background(), UpdateScheduledTransferParams{
+		ID:        scheduled.ID,
+		Amount:    scheduled.Amount,
+		Memo:      scheduled.Memo,
+		CronExpr:  scheduled.CronExpr,
+		NextRunAt: nextRunAt,
+		Status:    ScheduledTransferStatusActive,
+	})
+	require.NoError(t, err)
+
+	result, err = store.ExecuteScheduledTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, ExecutionStatusFailed, result.Execution.Status)
+	require.False(t, result.Execution.TransferID.Valid)
+	require.Contains(t, result.Execution.Error, ErrInsufficientFunds.Error())
+	require.Equal(t, int32(1), result.ScheduledTransfer.FailureCount)
+	require.Equal(t, ScheduledTransferStatusActive, result.ScheduledTransfer.Status)
+	require.WithinDuration(t, arg.Now.Add(arg.RetryInterval), result.ScheduledTransfer.NextRunAt, time.Second)
+
+	arg.Now = arg.Now.Add(arg.RetryInterval)
+	result, err = store.ExecuteScheduledTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, ExecutionStatusFailed, result.Execution.Status)
+	require.Equal(t, int32(2), result.ScheduledTransfer.FailureCount)
+	require.Equal(t, ScheduledTransferStatusPaused, result.ScheduledTransfer.Status)
+
+	executions, err := testQueries.ListScheduledTransferExecutions(context.Background(), ListScheduledTransferExecutionsParams{
+		ScheduledTransferID: scheduled.ID,
+		Limit:               10,
+	})
+	require.NoError(t, err)
+	require.Len(t, executions, 3)
+
+	// 失败的执行不会改变余额
+	account, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-scheduled.Amount, account.Balance)
+}
+
+func TestRecordScheduledTransferFailure(t *testing.T) {
+	store := NewStore(testDb).(*SQLStore)
+	now := time.Now()
+
+	user1 := CreateRandomUser(t)
+	user2 := CreateRandomUser(t)
+	account1 := createCurrencyAccount(t, user1, utils.RMB, 100)
+	account2 := createCurrencyAccount(t, user2, utils.RMB, 0)
+	scheduled := CreateRandomScheduledTransfer(t, account1, account2, now.Add(-time.Minute))
+
+	arg := ExecuteScheduledTransferTxParams{
+		Now:           now,
+		MaxFailures:   2,
+		RetryInterval: time.Hour,
+	}
+
+	// 记录失败后推迟到重试间隔之后，下一轮不会再领取到这条记录
+	result, err := store.recordScheduledTransferFailure(context.Background(), scheduled.ID, arg)
+	require.NoError(t, err)
+	require.Equal(t, ExecutionStatusFailed, result.Execution.Status)
+	require.Equal(t, ErrScheduledTransferInternal.Error(), result.Execution.Error)
+	require.False(t, result.Execution.TransferID.Valid)
+	require.Equal(t, int32(1), result.ScheduledTransfer.FailureCount)
+	require.Equal(t, ScheduledTransferStatusActive, result.ScheduledTransfer.Status)
+	require.WithinDuration(t, now.Add(arg.RetryInterval), result.ScheduledTransfer.NextRunAt, time.Second)
+
+	// 还没有到期，不重复记录
+	result, err = store.recordScheduledTransferFailure(context.Background(), scheduled.ID, arg)
+	require.NoError(t, err)
+	require.Zero(t, result.Execution.ID)
+	require.Equal(t, int32(1), result.ScheduledTransfer.FailureCount)
+
+	// 连续失败达到上限后暂停
+	arg.Now = arg.Now.Add(arg.RetryInterval + time.Second)
+	result, err = store.recordScheduledTransferFailure(context.Background(), scheduled.ID, arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), result.ScheduledTransfer.FailureCount)
+	require.Equal(t, ScheduledTransferStatusPaused, result.ScheduledTransfer.Status)
+
+	executions, err := testQueries.ListScheduledTransferExecutions(context.Background(), ListScheduledTransferExecutionsParams{
+		ScheduledTransferID: scheduled.ID,
+		Limit:               10,
+	})
+	require.NoError(t, err)
+	require.Len(t, executions, 2)
+
+	// 余额不变
+	account, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, account.Balance)
+}
+
+func TestUpdateScheduledTransferTx(t *testing.T) {
+	store := NewStore(testDb)
+
+	account1 := createFundedAccount(t, 10)
+	account2 := CreateRandomAccount(t)
+	scheduled := CreateRandomScheduledTransfer(t, account1, account2, time.Now().Add(time.Hour))
+
+	// 修改金额和周期，未指定执行时间时按新的周期计算
+	updated, err := store.UpdateScheduledTransferTx(context.Background(), UpdateScheduledTransferTxParams{
+		ID:       scheduled.ID,
+		Amount:   sql.NullInt64{Int64: 20, Valid: true},
+		CronExpr: sql.NullString{String: "0 9 * * *", Valid: true},
+		Status:   ScheduledTransferStatusPaused,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(20), updated.Amount)
+	require.Equal(t, scheduled.Memo, updated.Memo)
+	require.Equal(t, "0 9 * * *", updated.CronExpr)
+	require.Equal(t, ScheduledTransferStatusPaused, updated.Status)
+	require.Equal(t, 9, updated.NextRunAt.Local().Hour())
+
+	// 恢复后从当前时间重新计算
+	updated, err = store.UpdateScheduledTransferTx(context.Background(), UpdateScheduledTransferTxParams{
+		ID:     scheduled.ID,
+		Status: ScheduledTransferStatusActive,
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferStatusActive, updated.Status)
+	require.Zero(t, updated.FailureCount)
+	require.True(t, updated.NextRunAt.After(time.Now()))
+
+	// 取消后不能再修改
+	updated, err = store.UpdateScheduledTransferTx(context.Background(), UpdateScheduledTransferTxParams{
+		ID:     scheduled.ID,
+		Status: ScheduledTransferStatusCancelled,
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferStatusCancelled, updated.Status)
+
+	_, err = store.UpdateScheduledTransferTx(context.Background(), UpdateScheduledTransferTxParams{
+		ID:     scheduled.ID,
+		Status: ScheduledTransferStatusActive,
+	})
+	require.True(t, errors.Is(err, ErrScheduledTransferFinished))
+}
+
+func TestPublishOutboxTx(t *testing.T) {
+	store := NewStore(testDb)
+	user := CreateRandomUser(t)
+	hook := CreateRandomWebhook(t, user)
+
+	account, err := store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  0,
+		Currency: utils.RandomCurrency(),
+	})
+	require.NoError(t, err)
+
+	// 其他测试也会写入事件，分发到没有剩余事件为止
+	for {
+		n, err := store.PublishOutboxTx(context.Background(), 100)
+		require.NoError(t, err)
+		if n == 0 {
+			break
+		}
+	}
+
+	deliveries, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		WebhookID: hook.ID,
+		Limit:     5,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+
+	event, err := testQueries.GetOutboxEvent(context.Background(), deliveries[0].EventID)
+	require.NoError(t, err)
+	require.Equal(t, EventAccountCreated, event.EventType)
+	require.Equal(t, user.Username, event.Username)
+	require.True(t, event.PublishedAt.Valid)
+
+	var gotAccount Account
+	require.NoError(t, json.Unmarshal(event.Payload, &gotAccount))
+	require.Equal(t, account.ID, gotAccount.ID)
+}
+
+func TestTransferTxNotifiesAccountActivity(t *testing.T) {
+	config, err := utils.LoadConig("../../")
+	require.NoError(t, err)
+
+	listener := pq.NewListener(config.DBSource, time.Second, time.Minute, nil)
+	defer listener.Close()
+	// 数据库不可用时 Listen 会一直等待连接
+	listening := make(chan error, 1)
+	go func() {
+		listening <- listener.Listen("account_activity")
+	}()
+	select {
+	case err := <-listening:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("cannot listen for notifications: database is unreachable")
+	}
+
+	store := NewStore(testDb)
+	account1 := createFundedAccount(t, 10)
+	account2 := createCurrencyAccount(t, CreateRandomUser(t), account1.Currency, 0)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// 其他测试也会发出通知，只检查本次转账的两个账户
+	want := map[string]bool{
+		fmt.Sprint(account1.ID): true,
+		fmt.Sprint(account2.ID): true,
+	}
+	timeout := time.After(5 * time.Second)
+	for len(want) > 0 {
+		select {
+		case n := <-listener.Notify:
+			if n != nil {
+				delete(want, n.Extra)
+			}
+		case <-timeout:
+			t.Fatalf("missing notifications for accounts %v", want)
+		}
+	}
+}
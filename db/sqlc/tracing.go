@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"simplebank/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// sqlc 生成的查询以 "-- name: GetAccount :one" 开头
+var queryNamePattern = regexp.MustCompile(`^-- name: (\w+)`)
+
+// tracedDBTX 为每个查询创建一个 span，span 名称为 sqlc 中的查询名称
+type tracedDBTX struct {
+	db DBTX
+	// 事务的 span，事务中查询的 span 都是它的子 span
+	txSpan trace.Span
+}
+
+func newTracedDBTX(db DBTX, txSpan trace.Span) DBTX {
+	return &tracedDBTX{db: db, txSpan: txSpan}
+}
+
+func (t *tracedDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := t.startSpan(ctx, query)
+	defer span.End()
+
+	result, err := t.db.ExecContext(ctx, query, args...)
+	recordSpanError(span, err)
+	return result, err
+}
+
+func (t *tracedDBTX) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := t.startSpan(ctx, query)
+	defer span.End()
+
+	stmt, err := t.db.PrepareContext(ctx, query)
+	recordSpanError(span, err)
+	return stmt, err
+}
+
+func (t *tracedDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := t.startSpan(ctx, query)
+	defer span.End()
+
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	recordSpanError(span, err)
+	return rows, err
+}
+
+func (t *tracedDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := t.startSpan(ctx, query)
+	defer span.End()
+
+	row := t.db.QueryRowContext(ctx, query, args...)
+	recordSpanError(span, row.Err())
+	return row
+}
+
+// queryName 返回 sqlc 查询的名称，不是 sqlc 生成的查询返回 "query"
+func queryName(query string) string {
+	match := queryNamePattern.FindStringSubmatch(query)
+	if match == nil {
+		return "query"
+	}
+	return match[1]
+}
+
+// startSpan 创建查询的 span，事务中的查询以事务的 span 为父 span
+// execTx 中的操作使用调用方的 ctx，因此不能从 ctx 中取得事务的 span
+func (t *tracedDBTX) startSpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	if t.txSpan != nil {
+		ctx = trace.ContextWithSpan(ctx, t.txSpan)
+	}
+
+	name := queryName(query)
+	return tracing.Tracer().Start(ctx, "db."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationKey.String(name),
+			semconv.DBStatementKey.String(query),
+		),
+	)
+}
+
+// recordSpanError 记录查询错误，没有数据不算错误
+func recordSpanError(span trace.Span, err error) {
+	if err == nil || errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// startTxSpan 为一次事务执行创建 span，重试时每次执行都有单独的 span
+func startTxSpan(ctx context.Context, isolation sql.IsolationLevel, attempt int) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "db.execTx",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			attribute.String("db.transaction.isolation", isolation.String()),
+			attribute.Int("db.transaction.attempt", attempt),
+		),
+	)
+}
+
+// commitTx 提交事务，提交时间单独记录为一个 span
+func commitTx(ctx context.Context, tx *sql.Tx) error {
+	_, span := tracing.Tracer().Start(ctx, "db.Commit",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL),
+	)
+	defer span.End()
+
+	err := tx.Commit()
+	recordSpanError(span, err)
+	return err
+}
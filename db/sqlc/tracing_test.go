@@ -0,0 +1,13 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryName(t *testing.T) {
+	require.Equal(t, "GetAccount", queryName(getAccount))
+	require.Equal(t, "AddAccountBalance", queryName(addAccountBalance))
+	require.Equal(t, "query", queryName("SELECT 1"))
+}
@@ -7,26 +7,42 @@ package db
 
 import (
 	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
 )
 
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  to_amount,
+  exchange_rate,
+  fx_quote_id
 ) VALUES (
-  $1, $2, $3
-) RETURNING id, from_account_id, to_account_id, amount, created_at
+  $1, $2, $3, $4, $5, $6
+) RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, fx_quote_id, reversal_status, reversed_amount, reversed_to_amount
 `
 
 type CreateTransferParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64         `json:"from_account_id"`
+	ToAccountID   int64         `json:"to_account_id"`
+	Amount        int64         `json:"amount"`
+	ToAmount      int64         `json:"to_amount"`
+	ExchangeRate  int64         `json:"exchange_rate"`
+	FxQuoteID     uuid.NullUUID `json:"fx_quote_id"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ToAmount,
+		arg.ExchangeRate,
+		arg.FxQuoteID,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -34,12 +50,18 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.FxQuoteID,
+		&i.ReversalStatus,
+		&i.ReversedAmount,
+		&i.ReversedToAmount,
 	)
 	return i, err
 }
 
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, fx_quote_id, reversal_status, reversed_amount, reversed_to_amount FROM transfers
 WHERE id = $1 LIMIT 1
 `
 
@@ -52,12 +74,141 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.FxQuoteID,
+		&i.ReversalStatus,
+		&i.ReversedAmount,
+		&i.ReversedToAmount,
+	)
+	return i, err
+}
+
+const getTransferForUpdate = `-- name: GetTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, fx_quote_id, reversal_status, reversed_amount, reversed_to_amount FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, getTransferForUpdate, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.FxQuoteID,
+		&i.ReversalStatus,
+		&i.ReversedAmount,
+		&i.ReversedToAmount,
 	)
 	return i, err
 }
 
+const listAccountTransfers = `-- name: ListAccountTransfers :many
+(
+  SELECT o.id, o.from_account_id, o.to_account_id, o.amount, o.created_at, o.to_amount, o.exchange_rate, o.fx_quote_id, o.reversal_status, o.reversed_amount, o.reversed_to_amount FROM transfers o
+  WHERE
+    o.from_account_id = $2 AND
+    ($3::varchar IS NULL OR $3 = 'outgoing') AND
+    ($4::timestamptz IS NULL OR o.created_at >= $4) AND
+    ($5::timestamptz IS NULL OR o.created_at < $5) AND
+    ($6::bigint IS NULL OR o.amount >= $6) AND
+    ($7::bigint IS NULL OR o.amount <= $7) AND
+    (
+      $8::timestamptz IS NULL OR
+      (o.created_at, o.id) < ($8, $9::bigint)
+    )
+  ORDER BY o.created_at DESC, o.id DESC
+  LIMIT $1
+)
+UNION ALL
+(
+  SELECT i.id, i.from_account_id, i.to_account_id, i.amount, i.created_at, i.to_amount, i.exchange_rate, i.fx_quote_id, i.reversal_status, i.reversed_amount, i.reversed_to_amount FROM transfers i
+  WHERE
+    i.to_account_id = $2 AND
+    ($3::varchar IS NULL OR $3 = 'incoming') AND
+    ($4::timestamptz IS NULL OR i.created_at >= $4) AND
+    ($5::timestamptz IS NULL OR i.created_at < $5) AND
+    ($6::bigint IS NULL OR i.to_amount >= $6) AND
+    ($7::bigint IS NULL OR i.to_amount <= $7) AND
+    (
+      $8::timestamptz IS NULL OR
+      (i.created_at, i.id) < ($8, $9::bigint)
+    )
+  ORDER BY i.created_at DESC, i.id DESC
+  LIMIT $1
+)
+ORDER BY created_at DESC, id DESC
+LIMIT $1
+`
+
+type ListAccountTransfersParams struct {
+	Limit           int32          `json:"limit"`
+	AccountID       int64          `json:"account_id"`
+	Direction       sql.NullString `json:"direction"`
+	StartTime       sql.NullTime   `json:"start_time"`
+	EndTime         sql.NullTime   `json:"end_time"`
+	MinAmount       sql.NullInt64  `json:"min_amount"`
+	MaxAmount       sql.NullInt64  `json:"max_amount"`
+	CursorCreatedAt sql.NullTime   `json:"cursor_created_at"`
+	CursorID        sql.NullInt64  `json:"cursor_id"`
+}
+
+// 按 (created_at, id) 倒序做游标分页，可选的过滤条件为空时不生效
+// 转出和转入分别沿 (from_account_id, created_at, id) 和 (to_account_id, created_at, id) 索引各取 limit 条，合并后再取前 limit 条
+// 金额过滤使用本账户币种的金额：转出为 amount，转入为 to_amount
+func (q *Queries) ListAccountTransfers(ctx context.Context, arg ListAccountTransfersParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountTransfers,
+		arg.Limit,
+		arg.AccountID,
+		arg.Direction,
+		arg.StartTime,
+		arg.EndTime,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.FxQuoteID,
+			&i.ReversalStatus,
+			&i.ReversedAmount,
+			&i.ReversedToAmount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, fx_quote_id, reversal_status, reversed_amount, reversed_to_amount FROM transfers
 WHERE
   from_account_id = $1 OR
   to_account_id = $2
@@ -93,6 +244,12 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.FxQuoteID,
+			&i.ReversalStatus,
+			&i.ReversedAmount,
+			&i.ReversedToAmount,
 		); err != nil {
 			return nil, err
 		}
@@ -106,3 +263,44 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 	}
 	return items, nil
 }
+
+const updateTransferReversal = `-- name: UpdateTransferReversal :one
+UPDATE transfers
+SET
+  reversed_amount = $2,
+  reversed_to_amount = $3,
+  reversal_status = $4
+WHERE id = $1
+RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, fx_quote_id, reversal_status, reversed_amount, reversed_to_amount
+`
+
+type UpdateTransferReversalParams struct {
+	ID               int64          `json:"id"`
+	ReversedAmount   int64          `json:"reversed_amount"`
+	ReversedToAmount int64          `json:"reversed_to_amount"`
+	ReversalStatus   ReversalStatus `json:"reversal_status"`
+}
+
+func (q *Queries) UpdateTransferReversal(ctx context.Context, arg UpdateTransferReversalParams) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, updateTransferReversal,
+		arg.ID,
+		arg.ReversedAmount,
+		arg.ReversedToAmount,
+		arg.ReversalStatus,
+	)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.FxQuoteID,
+		&i.ReversalStatus,
+		&i.ReversedAmount,
+		&i.ReversedToAmount,
+	)
+	return i, err
+}
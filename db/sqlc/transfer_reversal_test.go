@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateTransferReversal(t *testing.T) {
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+	transfer := CreateRandomTransfer(t, account1, account2)
+
+	arg := CreateTransferReversalParams{
+		TransferID: transfer.ID,
+		Amount:     transfer.Amount,
+		ToAmount:   transfer.ToAmount,
+		Reason:     "duplicate payment",
+		Actor:      account2.Owner,
+	}
+	reversal, err := testQueries.CreateTransferReversal(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, reversal.ID)
+	require.Equal(t, arg.TransferID, reversal.TransferID)
+	require.Equal(t, arg.Amount, reversal.Amount)
+	require.Equal(t, arg.ToAmount, reversal.ToAmount)
+	require.Equal(t, arg.Reason, reversal.Reason)
+	require.Equal(t, arg.Actor, reversal.Actor)
+	require.NotZero(t, reversal.CreatedAt)
+
+	reversals, err := testQueries.ListTransferReversals(context.Background(), transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, []TransferReversal{reversal}, reversals)
+}
+
+func TestUpdateTransferReversal(t *testing.T) {
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+	transfer1 := CreateRandomTransfer(t, account1, account2)
+
+	arg := UpdateTransferReversalParams{
+		ID:               transfer1.ID,
+		ReversedAmount:   transfer1.Amount,
+		ReversedToAmount: transfer1.ToAmount,
+		ReversalStatus:   ReversalStatusFull,
+	}
+	transfer2, err := testQueries.UpdateTransferReversal(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, transfer1.ID, transfer2.ID)
+	require.Equal(t, arg.ReversedAmount, transfer2.ReversedAmount)
+	require.Equal(t, arg.ReversedToAmount, transfer2.ReversedToAmount)
+	require.Equal(t, ReversalStatusFull, transfer2.ReversalStatus)
+}
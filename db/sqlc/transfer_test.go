@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"simplebank/utils"
 	"testing"
 
@@ -9,10 +10,13 @@ import (
 )
 
 func CreateRandomTransfer(t *testing.T, account1, account2 Account) Transfer {
+	amount := utils.RandomMoney()
 	arg := CreateTransferParams{
 		FromAccountID: account1.ID,
 		ToAccountID:   account2.ID,
-		Amount:        utils.RandomMoney(),
+		Amount:        amount,
+		ToAmount:      amount,
+		ExchangeRate:  utils.ExchangeRateScale,
 	}
 	transfer, err := testQueries.CreateTransfer(context.Background(), arg)
 
@@ -22,6 +26,9 @@ func CreateRandomTransfer(t *testing.T, account1, account2 Account) Transfer {
 	require.Equal(t, arg.FromAccountID, transfer.FromAccountID)
 	require.Equal(t, arg.ToAccountID, transfer.ToAccountID)
 	require.Equal(t, arg.Amount, transfer.Amount)
+	require.Equal(t, arg.ToAmount, transfer.ToAmount)
+	require.Equal(t, arg.ExchangeRate, transfer.ExchangeRate)
+	require.False(t, transfer.FxQuoteID.Valid)
 	require.NotZero(t, transfer.ID)
 	require.NotZero(t, transfer.CreatedAt)
 
@@ -74,3 +81,98 @@ func TestListTransfers(t *testing.T) {
 		require.True(t, transfer.FromAccountID == account1.ID || transfer.ToAccountID == account1.ID)
 	}
 }
+
+func TestListAccountTransfers(t *testing.T) {
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+	for i := 0; i < 5; i++ {
+		CreateRandomTransfer(t, account1, account2)
+		CreateRandomTransfer(t, account2, account1)
+	}
+
+	arg := ListAccountTransfersParams{
+		AccountID: account1.ID,
+		Limit:     10,
+	}
+	transfers, err := testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, transfers, 10)
+
+	arg.Direction = sql.NullString{String: "outgoing", Valid: true}
+	transfers, err = testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, transfers, 5)
+	for _, transfer := range transfers {
+		require.Equal(t, account1.ID, transfer.FromAccountID)
+	}
+
+	arg.Direction = sql.NullString{String: "incoming", Valid: true}
+	arg.Limit = 3
+	page1, err := testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, page1, 3)
+
+	last := page1[len(page1)-1]
+	arg.CursorCreatedAt = sql.NullTime{Time: last.CreatedAt, Valid: true}
+	arg.CursorID = sql.NullInt64{Int64: last.ID, Valid: true}
+	page2, err := testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, page2, 2)
+	for _, transfer := range append(page1, page2...) {
+		require.Equal(t, account1.ID, transfer.ToAccountID)
+	}
+}
+
+func TestListAccountTransfersOrder(t *testing.T) {
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+	for i := 0; i < 3; i++ {
+		CreateRandomTransfer(t, account1, account2)
+		CreateRandomTransfer(t, account2, account1)
+	}
+
+	// 两个方向的结果合并后仍按 (created_at, id) 倒序排列
+	transfers, err := testQueries.ListAccountTransfers(context.Background(), ListAccountTransfersParams{
+		AccountID: account1.ID,
+		Limit:     4,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 4)
+	for i := 1; i < len(transfers); i++ {
+		prev, cur := transfers[i-1], transfers[i]
+		require.True(t, prev.CreatedAt.After(cur.CreatedAt) ||
+			(prev.CreatedAt.Equal(cur.CreatedAt) && prev.ID > cur.ID))
+	}
+}
+
+func TestListAccountTransfersAmountFilter(t *testing.T) {
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	// 跨币种转账：转出 10，转入账户入账 70
+	transfer, err := testQueries.CreateTransfer(context.Background(), CreateTransferParams{
+		FromAccountID: account2.ID,
+		ToAccountID:   account1.ID,
+		Amount:        10,
+		ToAmount:      70,
+		ExchangeRate:  7 * utils.ExchangeRateScale,
+	})
+	require.NoError(t, err)
+
+	// 转入方按入账金额过滤
+	arg := ListAccountTransfersParams{
+		AccountID: account1.ID,
+		MinAmount: sql.NullInt64{Int64: 50, Valid: true},
+		Limit:     10,
+	}
+	transfers, err := testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, transfer.ID, transfers[0].ID)
+
+	// 转出方按转出金额过滤
+	arg.AccountID = account2.ID
+	transfers, err = testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, transfers)
+}
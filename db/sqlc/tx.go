@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"simplebank/logger"
+	"simplebank/metrics"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TxConfig 事务的隔离级别和重试策略
+type TxConfig struct {
+	// TransferIsolation 资金变动事务（转账、跨币种转账、调账、冲正、定时转账）的隔离级别，单个事务可以通过参数中的 Isolation 指定
+	// 默认 READ COMMITTED 下转账通过 SELECT ... FOR NO KEY UPDATE 锁定账户，SERIALIZABLE 下还会检测其他读写冲突
+	TransferIsolation sql.IsolationLevel
+	// MaxAttempts 序列化失败或死锁时事务最多执行的次数，包括第一次
+	MaxAttempts int
+	// RetryBackoff 第一次重试前等待的时间，之后每次翻倍，最长 1 秒；实际等待时间在其一半到全部之间随机
+	RetryBackoff time.Duration
+}
+
+// DefaultTxConfig 默认的事务配置
+var DefaultTxConfig = TxConfig{
+	TransferIsolation: sql.LevelReadCommitted,
+	MaxAttempts:       3,
+	RetryBackoff:      10 * time.Millisecond,
+}
+
+// 可以通过配置选择的隔离级别
+var isolationLevels = []sql.IsolationLevel{
+	sql.LevelDefault,
+	sql.LevelReadCommitted,
+	sql.LevelRepeatableRead,
+	sql.LevelSerializable,
+}
+
+// ParseIsolationLevel 解析隔离级别的名称，例如 "read committed"、"REPEATABLE_READ"、"serializable"
+func ParseIsolationLevel(name string) (sql.IsolationLevel, error) {
+	name = strings.ReplaceAll(strings.TrimSpace(name), "_", " ")
+	if len(name) == 0 {
+		return sql.LevelDefault, nil
+	}
+
+	for _, level := range isolationLevels {
+		if strings.EqualFold(name, level.String()) {
+			return level, nil
+		}
+	}
+	return sql.LevelDefault, fmt.Errorf("unsupported isolation level: %q", name)
+}
+
+// retryTx 执行 run，序列化失败或死锁时等待一段随机时间后重试，最多执行 maxAttempts 次
+// 业务错误和其他数据库错误不重试；等待期间 ctx 被取消时返回最后一次的错误
+func retryTx(ctx context.Context, maxAttempts int, backoff time.Duration, run func(attempt int) error) error {
+	for attempt := 1; ; attempt++ {
+		err := run(attempt)
+		if err == nil || !isRetryableTxError(err) {
+			return err
+		}
+
+		if attempt >= maxAttempts {
+			logger.Ctx(ctx).Warn().Err(err).Int("attempts", attempt).Msg("transaction retries exhausted")
+			return err
+		}
+
+		delay := txRetryDelay(backoff, attempt)
+		metrics.TransactionRetries.Inc()
+		logger.Ctx(ctx).Debug().Err(err).Int("attempt", attempt).Dur("delay", delay).Msg("retry transaction")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// isRetryableTxError 事务因序列化失败（40001）或死锁（40P01）被数据库中止，重新执行可能成功
+func isRetryableTxError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+
+	switch pqErr.Code.Name() {
+	case "serialization_failure", "deadlock_detected":
+		return true
+	}
+	return false
+}
+
+// txRetryDelay 第 attempt 次执行失败后的等待时间，指数增长并加入随机抖动，避免冲突的事务同时重试
+func txRetryDelay(backoff time.Duration, attempt int) time.Duration {
+	if backoff <= 0 {
+		return 0
+	}
+
+	delay := backoff << (attempt - 1)
+	if delay <= 0 || delay > time.Second {
+		delay = time.Second
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,162 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIsolationLevel(t *testing.T) {
+	testCases := []struct {
+		name  string
+		level sql.IsolationLevel
+		ok    bool
+	}{
+		{"", sql.LevelDefault, true},
+		{"read committed", sql.LevelReadCommitted, true},
+		{"REPEATABLE_READ", sql.LevelRepeatableRead, true},
+		{" Serializable ", sql.LevelSerializable, true},
+		{"read uncommitted", sql.LevelDefault, false},
+		{"snapshot", sql.LevelDefault, false},
+	}
+
+	for _, tc := range testCases {
+		level, err := ParseIsolationLevel(tc.name)
+		if !tc.ok {
+			require.Error(t, err, tc.name)
+			continue
+		}
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.level, level, tc.name)
+	}
+}
+
+func TestTransferIsolation(t *testing.T) {
+	store := NewStoreWithTxConfig(nil, TxConfig{TransferIsolation: sql.LevelRepeatableRead}).(*SQLStore)
+
+	// 没有指定时使用配置的隔离级别
+	require.Equal(t, sql.LevelRepeatableRead, store.transferIsolation(sql.LevelDefault))
+	require.Equal(t, sql.LevelSerializable, store.transferIsolation(sql.LevelSerializable))
+	require.Equal(t, sql.LevelReadCommitted, store.transferIsolation(sql.LevelReadCommitted))
+}
+
+func TestIsRetryableTxError(t *testing.T) {
+	serializationFailure := &pq.Error{Code: "40001"}
+	deadlock := &pq.Error{Code: "40P01"}
+
+	require.True(t, isRetryableTxError(serializationFailure))
+	require.True(t, isRetryableTxError(deadlock))
+	require.True(t, isRetryableTxError(fmt.Errorf("tx err: %w, rb err: %v", deadlock, sql.ErrTxDone)))
+	require.False(t, isRetryableTxError(&pq.Error{Code: "23505"}))
+	require.False(t, isRetryableTxError(ErrInsufficientFunds))
+	require.False(t, isRetryableTxError(nil))
+}
+
+func TestTxRetryDelay(t *testing.T) {
+	backoff := 10 * time.Millisecond
+	for attempt := 1; attempt <= 5; attempt++ {
+		expected := backoff << (attempt - 1)
+		delay := txRetryDelay(backoff, attempt)
+		require.GreaterOrEqual(t, delay, expected/2)
+		require.LessOrEqual(t, delay, expected)
+	}
+
+	// 等待时间最长 1 秒
+	require.LessOrEqual(t, txRetryDelay(backoff, 30), time.Second)
+	require.LessOrEqual(t, txRetryDelay(backoff, 100), time.Second)
+	require.Zero(t, txRetryDelay(0, 3))
+}
+
+func TestRetryTx(t *testing.T) {
+	serializationFailure := &pq.Error{Code: "40001"}
+
+	testCases := []struct {
+		name        string
+		maxAttempts int
+		errs        []error
+		attempts    int
+		checkErr    func(t *testing.T, err error)
+	}{
+		{
+			name:        "OK",
+			maxAttempts: 3,
+			errs:        []error{nil},
+			attempts:    1,
+			checkErr: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:        "RetrySucceeded",
+			maxAttempts: 3,
+			errs:        []error{serializationFailure, &pq.Error{Code: "40P01"}, nil},
+			attempts:    3,
+			checkErr: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:        "RetriesExhausted",
+			maxAttempts: 3,
+			errs:        []error{serializationFailure, serializationFailure, serializationFailure, nil},
+			attempts:    3,
+			checkErr: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, serializationFailure)
+			},
+		},
+		{
+			name:        "NoRetry",
+			maxAttempts: 0,
+			errs:        []error{serializationFailure, nil},
+			attempts:    1,
+			checkErr: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, serializationFailure)
+			},
+		},
+		{
+			name:        "NotRetryable",
+			maxAttempts: 3,
+			errs:        []error{ErrInsufficientFunds, nil},
+			attempts:    1,
+			checkErr: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, ErrInsufficientFunds)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			err := retryTx(context.Background(), tc.maxAttempts, time.Millisecond, func(attempt int) error {
+				attempts++
+				require.Equal(t, attempts, attempt)
+				return tc.errs[attempt-1]
+			})
+			tc.checkErr(t, err)
+			require.Equal(t, tc.attempts, attempts)
+		})
+	}
+}
+
+func TestRetryTxContextCanceled(t *testing.T) {
+	serializationFailure := &pq.Error{Code: "40001"}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// ctx 已取消时不再等待重试
+	attempts := 0
+	err := retryTx(ctx, 3, time.Hour, func(attempt int) error {
+		attempts++
+		return serializationFailure
+	})
+	require.True(t, errors.Is(err, serializationFailure))
+	require.Equal(t, 1, attempts)
+}
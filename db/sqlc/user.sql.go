@@ -7,6 +7,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
 const createUser = `-- name: CreateUser :one
@@ -17,7 +18,7 @@ INSERT INTO users (
   email
 ) VALUES (
   $1, $2, $3, $4
-) RETURNING username, hashed_password, full_name, email, password_changed_at, created_at
+) RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, tokens_revoked_at, role
 `
 
 type CreateUserParams struct {
@@ -42,6 +43,8 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.TokensRevokedAt,
+		&i.Role,
 	)
 	return i, err
 }
@@ -56,7 +59,7 @@ func (q *Queries) DeleteUser(ctx context.Context, username string) error {
 }
 
 const getUser = `-- name: GetUser :one
-SELECT username, hashed_password, full_name, email, password_changed_at, created_at FROM users
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, tokens_revoked_at, role FROM users
 WHERE username = $1 LIMIT 1
 `
 
@@ -70,6 +73,98 @@ func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.TokensRevokedAt,
+		&i.Role,
+	)
+	return i, err
+}
+
+const listUserTokenRevocations = `-- name: ListUserTokenRevocations :many
+SELECT username, tokens_revoked_at FROM users
+WHERE tokens_revoked_at > $1
+`
+
+type ListUserTokenRevocationsRow struct {
+	Username        string       `json:"username"`
+	TokensRevokedAt sql.NullTime `json:"tokens_revoked_at"`
+}
+
+// 只返回在 $1 之后吊销的用户，更早的吊销不会影响未过期的 token
+func (q *Queries) ListUserTokenRevocations(ctx context.Context, tokensRevokedAt sql.NullTime) ([]ListUserTokenRevocationsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUserTokenRevocations, tokensRevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUserTokenRevocationsRow{}
+	for rows.Next() {
+		var i ListUserTokenRevocationsRow
+		if err := rows.Scan(&i.Username, &i.TokensRevokedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeUserTokens = `-- name: RevokeUserTokens :one
+UPDATE users
+SET tokens_revoked_at = $2
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, tokens_revoked_at, role
+`
+
+type RevokeUserTokensParams struct {
+	Username        string       `json:"username"`
+	TokensRevokedAt sql.NullTime `json:"tokens_revoked_at"`
+}
+
+func (q *Queries) RevokeUserTokens(ctx context.Context, arg RevokeUserTokensParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, revokeUserTokens, arg.Username, arg.TokensRevokedAt)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.TokensRevokedAt,
+		&i.Role,
+	)
+	return i, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users
+SET role = $2
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, tokens_revoked_at, role
+`
+
+type UpdateUserRoleParams struct {
+	Username string   `json:"username"`
+	Role     UserRole `json:"role"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRole, arg.Username, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.TokensRevokedAt,
+		&i.Role,
 	)
 	return i, err
 }
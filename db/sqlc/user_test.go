@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"simplebank/utils"
 	"testing"
 	"time"
@@ -33,6 +34,8 @@ func CreateRandomUser(t *testing.T) User {
 	require.Equal(t, arg.FullName, user.FullName)
 	require.Equal(t, arg.Email, user.Email)
 	require.Equal(t, arg.HashedPassword, user.HashedPassword)
+	// 新用户默认为普通客户
+	require.Equal(t, UserRoleCustomer, user.Role)
 	// 返回结果非零值
 	require.NotZero(t, user.CreatedAt)
 	require.Zero(t, user.PasswordChangedAt)
@@ -60,3 +63,44 @@ func TestGetuser(t *testing.T) {
 	require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second)
 	require.Equal(t, user1.PasswordChangedAt, user2.PasswordChangedAt)
 }
+
+func TestRevokeUserTokens(t *testing.T) {
+	user1 := CreateRandomUser(t)
+	require.False(t, user1.TokensRevokedAt.Valid)
+
+	revokedAt := time.Now()
+	user2, err := testQueries.RevokeUserTokens(context.Background(), RevokeUserTokensParams{
+		Username:        user1.Username,
+		TokensRevokedAt: sql.NullTime{Time: revokedAt, Valid: true},
+	})
+	require.NoError(t, err)
+	require.True(t, user2.TokensRevokedAt.Valid)
+	require.WithinDuration(t, revokedAt, user2.TokensRevokedAt.Time, time.Millisecond)
+
+	// 只返回指定时间之后吊销的用户
+	rows, err := testQueries.ListUserTokenRevocations(context.Background(), sql.NullTime{Time: revokedAt.Add(-time.Second), Valid: true})
+	require.NoError(t, err)
+	require.Contains(t, rows, ListUserTokenRevocationsRow{Username: user2.Username, TokensRevokedAt: user2.TokensRevokedAt})
+
+	rows, err = testQueries.ListUserTokenRevocations(context.Background(), sql.NullTime{Time: revokedAt.Add(time.Second), Valid: true})
+	require.NoError(t, err)
+	for _, row := range rows {
+		require.NotEqual(t, user2.Username, row.Username)
+	}
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	user1 := CreateRandomUser(t)
+
+	user2, err := testQueries.UpdateUserRole(context.Background(), UpdateUserRoleParams{
+		Username: user1.Username,
+		Role:     UserRoleAuditor,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user1.Username, user2.Username)
+	require.Equal(t, UserRoleAuditor, user2.Role)
+
+	user3, err := testQueries.GetUser(context.Background(), user1.Username)
+	require.NoError(t, err)
+	require.Equal(t, UserRoleAuditor, user3.Role)
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"simplebank/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func CreateRandomWebhook(t *testing.T, user User) Webhook {
+	arg := CreateWebhookParams{
+		Owner:  user.Username,
+		Url:    "https://example.com/" + utils.RandomString(8),
+		Secret: utils.RandomString(64),
+	}
+
+	hook, err := testQueries.CreateWebhook(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, hook)
+
+	require.Equal(t, arg.Owner, hook.Owner)
+	require.Equal(t, arg.Url, hook.Url)
+	require.Equal(t, arg.Secret, hook.Secret)
+	require.NotZero(t, hook.CreatedAt)
+
+	return hook
+}
+
+func TestListWebhooks(t *testing.T) {
+	user := CreateRandomUser(t)
+	for i := 0; i < 3; i++ {
+		CreateRandomWebhook(t, user)
+	}
+
+	hooks, err := testQueries.ListWebhooks(context.Background(), ListWebhooksParams{
+		Owner:  user.Username,
+		Limit:  2,
+		Offset: 1,
+	})
+	require.NoError(t, err)
+	require.Len(t, hooks, 2)
+	for _, hook := range hooks {
+		require.Equal(t, user.Username, hook.Owner)
+	}
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	hook := CreateRandomWebhook(t, CreateRandomUser(t))
+
+	err := testQueries.DeleteWebhook(context.Background(), hook.ID)
+	require.NoError(t, err)
+
+	_, err = testQueries.GetWebhook(context.Background(), hook.ID)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	user := CreateRandomUser(t)
+	hook1 := CreateRandomWebhook(t, user)
+	hook2 := CreateRandomWebhook(t, user)
+	event := CreateRandomOutboxEvent(t, user)
+
+	// 用户的每个 webhook 各一条投递记录
+	rows, err := testQueries.CreateWebhookDeliveries(context.Background(), CreateWebhookDeliveriesParams{
+		EventID: event.ID,
+		Owner:   user.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), rows)
+
+	deliveries, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		WebhookID: hook1.ID,
+		Limit:     5,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+
+	delivery := deliveries[0]
+	require.Equal(t, event.ID, delivery.EventID)
+	require.Equal(t, DeliveryStatusPending, delivery.Status)
+	require.Zero(t, delivery.Attempts)
+
+	// 投递失败超过最大次数后进入死信
+	updated, err := testQueries.UpdateWebhookDelivery(context.Background(), UpdateWebhookDeliveryParams{
+		ID:            delivery.ID,
+		Status:        DeliveryStatusDead,
+		Attempts:      8,
+		NextAttemptAt: time.Now(),
+		LastError:     "unexpected status code: 500",
+	})
+	require.NoError(t, err)
+	require.Equal(t, DeliveryStatusDead, updated.Status)
+	require.Equal(t, int32(8), updated.Attempts)
+
+	dead, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		WebhookID: hook1.ID,
+		Status:    sql.NullString{String: string(DeliveryStatusDead), Valid: true},
+		Limit:     5,
+	})
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	require.Equal(t, delivery.ID, dead[0].ID)
+
+	pending, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		WebhookID: hook2.ID,
+		Status:    sql.NullString{String: string(DeliveryStatusPending), Valid: true},
+		Limit:     5,
+	})
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+}
+
+func TestClaimWebhookDeliveries(t *testing.T) {
+	user := CreateRandomUser(t)
+	hook := CreateRandomWebhook(t, user)
+	event := CreateRandomOutboxEvent(t, user)
+
+	_, err := testQueries.CreateWebhookDeliveries(context.Background(), CreateWebhookDeliveriesParams{
+		EventID: event.ID,
+		Owner:   user.Username,
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	leaseUntil := now.Add(time.Minute)
+	claimed, err := testQueries.ClaimWebhookDeliveries(context.Background(), ClaimWebhookDeliveriesParams{
+		LeaseUntil: leaseUntil,
+		Now:        now,
+		Limit:      1000,
+	})
+	require.NoError(t, err)
+
+	var found bool
+	for _, delivery := range claimed {
+		if delivery.WebhookID == hook.ID {
+			found = true
+			require.WithinDuration(t, leaseUntil, delivery.NextAttemptAt, time.Second)
+		}
+	}
+	require.True(t, found)
+
+	// 租约到期前不会被再次领取
+	claimed, err = testQueries.ClaimWebhookDeliveries(context.Background(), ClaimWebhookDeliveriesParams{
+		LeaseUntil: leaseUntil,
+		Now:        now,
+		Limit:      1000,
+	})
+	require.NoError(t, err)
+	for _, delivery := range claimed {
+		require.NotEqual(t, hook.ID, delivery.WebhookID)
+	}
+}
@@ -0,0 +1,129 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	db "simplebank/db/sqlc"
+	"simplebank/pb"
+
+	"github.com/lib/pq"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (server *Server) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.CreateAccountResponse, error) {
+	if err := validateCurrency(req.GetCurrency()); err != nil {
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{fieldViolation("currency", err)})
+	}
+
+	payload := authPayload(ctx)
+	account, err := server.store.CreateAccountTx(ctx, db.CreateAccountParams{
+		Owner:    payload.Username,
+		Currency: req.GetCurrency(),
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "unique_violation":
+				return nil, status.Errorf(codes.AlreadyExists, "account already exists: %s", err)
+			case "foreign_key_violation":
+				return nil, status.Errorf(codes.FailedPrecondition, "user does not exist: %s", err)
+			}
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create account: %s", err)
+	}
+
+	return &pb.CreateAccountResponse{Account: convertAccount(account)}, nil
+}
+
+func (server *Server) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.GetAccountResponse, error) {
+	if err := validateID(req.GetId()); err != nil {
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{fieldViolation("id", err)})
+	}
+
+	account, err := server.validAccountOwner(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetAccountResponse{Account: convertAccount(account)}, nil
+}
+
+func (server *Server) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	var violations []*errdetails.BadRequest_FieldViolation
+	if req.GetPageId() < 1 {
+		violations = append(violations, fieldViolation("page_id", errors.New("must be a positive integer")))
+	}
+	if req.GetPageSize() < 5 || req.GetPageSize() > 10 {
+		violations = append(violations, fieldViolation("page_size", errors.New("must be between 5 and 10")))
+	}
+	if len(violations) > 0 {
+		return nil, invalidArgumentError(violations)
+	}
+
+	// 只能查看自己的账户
+	payload := authPayload(ctx)
+	accounts, err := server.store.ListAccounts(ctx, db.ListAccountsParams{
+		Owner:  payload.Username,
+		Limit:  req.GetPageSize(),
+		Offset: (req.GetPageId() - 1) * req.GetPageSize(),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list accounts: %s", err)
+	}
+
+	rsp := &pb.ListAccountsResponse{Accounts: make([]*pb.Account, 0, len(accounts))}
+	for _, account := range accounts {
+		rsp.Accounts = append(rsp.Accounts, convertAccount(account))
+	}
+	return rsp, nil
+}
+
+// CloseAccount 销户，账户记录和流水保留，余额必须为零
+func (server *Server) CloseAccount(ctx context.Context, req *pb.CloseAccountRequest) (*pb.CloseAccountResponse, error) {
+	if err := validateID(req.GetId()); err != nil {
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{fieldViolation("id", err)})
+	}
+
+	if _, err := server.validAccountOwner(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+
+	account, err := server.store.UpdateAccountStatusTx(ctx, db.UpdateAccountStatusTxParams{
+		AccountID: req.GetId(),
+		Status:    db.AccountStatusClosed,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, status.Errorf(codes.NotFound, "account not found")
+		case errors.Is(err, db.ErrInvalidStatusTransition):
+			return nil, businessError(errCodeInvalidStatusTransition, err)
+		case errors.Is(err, db.ErrNonZeroBalance):
+			return nil, businessError(errCodeNonZeroBalance, err)
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to close account: %s", err)
+		}
+	}
+
+	return &pb.CloseAccountResponse{Account: convertAccount(account)}, nil
+}
+
+// validAccountOwner 检查账户是否存在且属于当前登录用户
+func (server *Server) validAccountOwner(ctx context.Context, accountID int64) (db.Account, error) {
+	account, err := server.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return account, status.Errorf(codes.NotFound, "account not found")
+		}
+		return account, status.Errorf(codes.Internal, "failed to get account: %s", err)
+	}
+
+	if account.Owner != authPayload(ctx).Username {
+		return account, status.Errorf(codes.PermissionDenied, "account doesn't belong to the authenticated user")
+	}
+
+	return account, nil
+}
@@ -0,0 +1,153 @@
+package gapi
+
+import (
+	"database/sql"
+	"fmt"
+	mockdb "simplebank/db/mock"
+	db "simplebank/db/sqlc"
+	"simplebank/pb"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetAccountRPC(t *testing.T) {
+	user, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name       string
+		username   string
+		buildStubs func(store *mockdb.MockStore)
+		checkCode  codes.Code
+	}{
+		{
+			name:     "OK",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkCode: codes.OK,
+		},
+		{
+			name:     "NotFound",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkCode: codes.NotFound,
+		},
+		{
+			name:     "PermissionDenied",
+			username: user2.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkCode: codes.PermissionDenied,
+		},
+		{
+			name:     "InternalError",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrConnDone)
+			},
+			checkCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil)
+			client := pb.NewAccountServiceClient(newTestClient(t, server))
+
+			ctx := newContextWithBearerToken(t, server.tokenMaker, tc.username, time.Minute)
+			rsp, err := client.GetAccount(ctx, &pb.GetAccountRequest{Id: account.ID})
+			require.Equal(t, tc.checkCode, status.Code(err))
+			if tc.checkCode == codes.OK {
+				require.Equal(t, account.ID, rsp.GetAccount().GetId())
+				require.Equal(t, account.Owner, rsp.GetAccount().GetOwner())
+				require.Equal(t, account.Balance, rsp.GetAccount().GetBalance())
+				require.Equal(t, account.Currency, rsp.GetAccount().GetCurrency())
+				require.Equal(t, string(account.Status), rsp.GetAccount().GetStatus())
+				require.Nil(t, rsp.GetAccount().GetClosedAt())
+			}
+		})
+	}
+}
+
+func TestListAccountsRPC(t *testing.T) {
+	user, _ := randomUser(t)
+	accounts := []db.Account{randomAccount(user.Username), randomAccount(user.Username)}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListAccounts(gomock.Any(), gomock.Eq(db.ListAccountsParams{
+			Owner:  user.Username,
+			Limit:  5,
+			Offset: 5,
+		})).
+		Times(1).
+		Return(accounts, nil)
+
+	server := newTestServer(t, store, nil)
+	client := pb.NewAccountServiceClient(newTestClient(t, server))
+	ctx := newContextWithBearerToken(t, server.tokenMaker, user.Username, time.Minute)
+
+	rsp, err := client.ListAccounts(ctx, &pb.ListAccountsRequest{PageId: 2, PageSize: 5})
+	require.NoError(t, err)
+	require.Len(t, rsp.GetAccounts(), len(accounts))
+	for i, account := range accounts {
+		require.Equal(t, account.ID, rsp.GetAccounts()[i].GetId())
+	}
+
+	_, err = client.ListAccounts(ctx, &pb.ListAccountsRequest{PageId: 1, PageSize: 100})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestCloseAccountRPC(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+	store.EXPECT().
+		UpdateAccountStatusTx(gomock.Any(), gomock.Eq(db.UpdateAccountStatusTxParams{
+			AccountID: account.ID,
+			Status:    db.AccountStatusClosed,
+		})).
+		Times(1).
+		Return(db.Account{}, fmt.Errorf("%w: balance %d", db.ErrNonZeroBalance, account.Balance))
+
+	server := newTestServer(t, store, nil)
+	client := pb.NewAccountServiceClient(newTestClient(t, server))
+	ctx := newContextWithBearerToken(t, server.tokenMaker, user.Username, time.Minute)
+
+	// 业务错误的错误码放在 ErrorInfo 中
+	_, err := client.CloseAccount(ctx, &pb.CloseAccountRequest{Id: account.ID})
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.FailedPrecondition, st.Code())
+	require.Len(t, st.Details(), 1)
+
+	info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	require.Equal(t, errCodeNonZeroBalance, info.GetReason())
+}
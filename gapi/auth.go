@@ -0,0 +1,82 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"simplebank/token"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	authorizationHeaderKey  = "authorization"
+	authorizationTypeBearer = "bearer"
+)
+
+// 不需要认证的方法
+var publicMethods = map[string]bool{
+	"/pb.UserService/CreateUser": true,
+	"/pb.UserService/LoginUser":  true,
+}
+
+type payloadContextKey struct{}
+
+// authInterceptor 与 HTTP 服务的 authMiddleware 相同，校验 authorization 元数据中的 bearer token
+func (server *Server) authInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if publicMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+
+	payload, err := server.authorizeUser(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	return handler(context.WithValue(ctx, payloadContextKey{}, payload), req)
+}
+
+func (server *Server) authorizeUser(ctx context.Context) (*token.Payload, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, errors.New("missing metadata")
+	}
+
+	values := md.Get(authorizationHeaderKey)
+	if len(values) == 0 {
+		return nil, errors.New("authorization header is not provided")
+	}
+
+	// 该头信息分为两部分：授权类型 + token值
+	fields := strings.Fields(values[0])
+	if len(fields) < 2 {
+		return nil, errors.New("invalid authorization header format")
+	}
+
+	authorizationType := strings.ToLower(fields[0])
+	if authorizationType != authorizationTypeBearer {
+		return nil, fmt.Errorf("unsupported authorization type: %v", authorizationType)
+	}
+
+	payload, err := server.tokenMaker.VerifyToken(fields[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := payload.VerifyType(token.TokenTypeAccess); err != nil {
+		return nil, err
+	}
+
+	if server.isRevoked != nil && server.isRevoked(ctx, payload) {
+		return nil, errors.New("token has been revoked")
+	}
+
+	return payload, nil
+}
+
+// authPayload 返回认证拦截器保存的 token 信息，只能在需要认证的方法中使用
+func authPayload(ctx context.Context) *token.Payload {
+	return ctx.Value(payloadContextKey{}).(*token.Payload)
+}
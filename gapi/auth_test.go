@@ -0,0 +1,113 @@
+package gapi
+
+import (
+	"context"
+	mockdb "simplebank/db/mock"
+	"simplebank/pb"
+	"simplebank/token"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthInterceptor(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name       string
+		revoked    bool
+		setupAuth  func(t *testing.T, tokenMaker token.Maker) context.Context
+		buildStubs func(store *mockdb.MockStore)
+		checkCode  codes.Code
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkCode: codes.OK,
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkCode: codes.Unauthenticated,
+		},
+		{
+			name: "UnsupportedAuthorizationType",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				accessToken, _, err := tokenMaker.CreateToken(user.Username, string(user.Role), token.TokenTypeAccess, time.Minute)
+				require.NoError(t, err)
+				return metadata.AppendToOutgoingContext(context.Background(), authorizationHeaderKey, "basic "+accessToken)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkCode: codes.Unauthenticated,
+		},
+		{
+			name: "ExpiredToken",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, -time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkCode: codes.Unauthenticated,
+		},
+		{
+			name: "RefreshToken",
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				refreshToken, _, err := tokenMaker.CreateToken(user.Username, string(user.Role), token.TokenTypeRefresh, time.Minute)
+				require.NoError(t, err)
+				return metadata.AppendToOutgoingContext(context.Background(), authorizationHeaderKey, authorizationTypeBearer+" "+refreshToken)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkCode: codes.Unauthenticated,
+		},
+		{
+			name:    "RevokedToken",
+			revoked: true,
+			setupAuth: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithBearerToken(t, tokenMaker, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkCode: codes.Unauthenticated,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, func(ctx context.Context, payload *token.Payload) bool {
+				return tc.revoked
+			})
+			client := pb.NewAccountServiceClient(newTestClient(t, server))
+
+			_, err := client.GetAccount(tc.setupAuth(t, server.tokenMaker), &pb.GetAccountRequest{Id: account.ID})
+			require.Equal(t, tc.checkCode, status.Code(err))
+		})
+	}
+}
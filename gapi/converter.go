@@ -0,0 +1,69 @@
+package gapi
+
+import (
+	db "simplebank/db/sqlc"
+	"simplebank/pb"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func convertUser(user db.User) *pb.User {
+	rsp := &pb.User{
+		Username:  user.Username,
+		Role:      string(user.Role),
+		FullName:  user.FullName,
+		Email:     user.Email,
+		CreatedAt: timestamppb.New(user.CreatedAt),
+	}
+	if user.PasswordChangedAt.Valid {
+		rsp.PasswordChangedAt = timestamppb.New(user.PasswordChangedAt.Time)
+	}
+	return rsp
+}
+
+func convertAccount(account db.Account) *pb.Account {
+	rsp := &pb.Account{
+		Id:             account.ID,
+		Owner:          account.Owner,
+		Balance:        account.Balance,
+		Currency:       account.Currency,
+		OverdraftLimit: account.OverdraftLimit,
+		Status:         string(account.Status),
+		CreatedAt:      timestamppb.New(account.CreatedAt),
+	}
+	if account.ClosedAt.Valid {
+		rsp.ClosedAt = timestamppb.New(account.ClosedAt.Time)
+	}
+	return rsp
+}
+
+func convertTransfer(transfer db.Transfer) *pb.Transfer {
+	rsp := &pb.Transfer{
+		Id:               transfer.ID,
+		FromAccountId:    transfer.FromAccountID,
+		ToAccountId:      transfer.ToAccountID,
+		Amount:           transfer.Amount,
+		ToAmount:         transfer.ToAmount,
+		ExchangeRate:     transfer.ExchangeRate,
+		ReversalStatus:   string(transfer.ReversalStatus),
+		ReversedAmount:   transfer.ReversedAmount,
+		ReversedToAmount: transfer.ReversedToAmount,
+		CreatedAt:        timestamppb.New(transfer.CreatedAt),
+	}
+	if transfer.FxQuoteID.Valid {
+		rsp.FxQuoteId = transfer.FxQuoteID.UUID.String()
+	}
+	return rsp
+}
+
+func convertEntry(entry db.Entry) *pb.Entry {
+	return &pb.Entry{
+		Id:         entry.ID,
+		AccountId:  entry.AccountID,
+		Amount:     entry.Amount,
+		TransferId: entry.TransferID.Int64,
+		EntryType:  string(entry.EntryType),
+		Memo:       entry.Memo,
+		CreatedAt:  timestamppb.New(entry.CreatedAt),
+	}
+}
@@ -0,0 +1,48 @@
+package gapi
+
+import (
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 业务错误码与 HTTP 接口返回的 code 相同，放在 ErrorInfo.Reason 中
+const (
+	errCodeInsufficientFunds       = "insufficient_funds"
+	errCodeInvalidQuote            = "invalid_quote"
+	errCodeAccountNotActive        = "account_not_active"
+	errCodeInvalidStatusTransition = "invalid_status_transition"
+	errCodeNonZeroBalance          = "non_zero_balance"
+)
+
+const errorDomain = "simplebank"
+
+func fieldViolation(field string, err error) *errdetails.BadRequest_FieldViolation {
+	return &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: err.Error(),
+	}
+}
+
+func invalidArgumentError(violations []*errdetails.BadRequest_FieldViolation) error {
+	st, err := status.New(codes.InvalidArgument, "invalid parameters").
+		WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "invalid parameters")
+	}
+	return st.Err()
+}
+
+func unauthenticatedError(err error) error {
+	return status.Errorf(codes.Unauthenticated, "unauthorized: %s", err)
+}
+
+// businessError 对应 HTTP 接口的 422 响应
+func businessError(code string, err error) error {
+	st, detailErr := status.New(codes.FailedPrecondition, err.Error()).
+		WithDetails(&errdetails.ErrorInfo{Reason: code, Domain: errorDomain})
+	if detailErr != nil {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return st.Err()
+}
@@ -0,0 +1,83 @@
+package gapi
+
+import (
+	"context"
+	"net"
+	db "simplebank/db/sqlc"
+	"simplebank/token"
+	"simplebank/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestServer(t *testing.T, store db.Store, isRevoked TokenRevocationChecker) *Server {
+	config := utils.Config{
+		TokenSymmetricKey:    utils.RandomString(32),
+		AccessTokenDuartion:  time.Minute,
+		RefreshTokenDuration: time.Minute,
+	}
+
+	maker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	require.NoError(t, err)
+
+	return NewServer(config, store, maker, isRevoked)
+}
+
+// newTestClient 在内存中启动 gRPC 服务，经过与线上相同的拦截器
+func newTestClient(t *testing.T, server *Server) *grpc.ClientConn {
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := server.newGRPCServer()
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func newContextWithBearerToken(t *testing.T, tokenMaker token.Maker, username string, duration time.Duration) context.Context {
+	accessToken, _, err := tokenMaker.CreateToken(username, string(db.UserRoleCustomer), token.TokenTypeAccess, duration)
+	require.NoError(t, err)
+
+	md := metadata.MD{
+		authorizationHeaderKey: []string{authorizationTypeBearer + " " + accessToken},
+	}
+	return metadata.NewOutgoingContext(context.Background(), md)
+}
+
+func randomUser(t *testing.T) (db.User, string) {
+	password := utils.RandomString(6)
+	hashedPassword, err := utils.HashPassword(password)
+	require.NoError(t, err)
+
+	return db.User{
+		Username:       utils.RandomOwner(),
+		HashedPassword: hashedPassword,
+		FullName:       utils.RandomOwner(),
+		Email:          utils.RandomEmail(),
+		Role:           db.UserRoleCustomer,
+	}, password
+}
+
+func randomAccount(owner string) db.Account {
+	return db.Account{
+		ID:       utils.RandomInt(1, 1000),
+		Owner:    owner,
+		Balance:  utils.RandomMoney(),
+		Currency: utils.RandomCurrency(),
+		Status:   db.AccountStatusActive,
+	}
+}
@@ -0,0 +1,88 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	db "simplebank/db/sqlc"
+	"simplebank/pb"
+	"simplebank/token"
+	"simplebank/utils"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// TokenRevocationChecker 判断 token 是否已被吊销，与 HTTP 服务共用同一份吊销缓存
+type TokenRevocationChecker func(ctx context.Context, payload *token.Payload) bool
+
+// Server serves gRPC requests for our banking service.
+type Server struct {
+	pb.UnimplementedUserServiceServer
+	pb.UnimplementedAccountServiceServer
+	pb.UnimplementedTransferServiceServer
+
+	config     utils.Config
+	store      db.Store
+	tokenMaker token.Maker
+	isRevoked  TokenRevocationChecker
+	grpcServer *grpc.Server
+}
+
+// NewServer creates a new gRPC server.
+// tokenMaker 与 isRevoked 由 HTTP 服务提供，两边签发和吊销的 token 互相通用
+func NewServer(config utils.Config, store db.Store, tokenMaker token.Maker, isRevoked TokenRevocationChecker) *Server {
+	server := &Server{
+		config:     config,
+		store:      store,
+		tokenMaker: tokenMaker,
+		isRevoked:  isRevoked,
+	}
+	server.grpcServer = server.newGRPCServer()
+	return server
+}
+
+// newGRPCServer 注册所有服务，并为需要认证的方法加上认证拦截器
+func (server *Server) newGRPCServer() *grpc.Server {
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(server.authInterceptor))
+	pb.RegisterUserServiceServer(grpcServer, server)
+	pb.RegisterAccountServiceServer(grpcServer, server)
+	pb.RegisterTransferServiceServer(grpcServer, server)
+
+	// 允许 grpcurl、evans 等工具查询服务定义
+	reflection.Register(grpcServer)
+	return grpcServer
+}
+
+// Start runs the gRPC server on GRPC_SERVER_ADDRESS.
+// Shutdown 之后返回 nil
+func (server *Server) Start() error {
+	listener, err := net.Listen("tcp", server.config.GRPCServerAddress)
+	if err != nil {
+		return fmt.Errorf("cannot create gRPC listener: %w", err)
+	}
+
+	log.Info().Str("address", listener.Addr().String()).Msg("start gRPC server")
+	// 启动前已经调用 Shutdown 时返回 ErrServerStopped
+	if err := server.grpcServer.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return fmt.Errorf("cannot start gRPC server: %w", err)
+	}
+	return nil
+}
+
+// Shutdown 停止接收新的请求并等待处理中的请求完成，ctx 超时后强制关闭所有连接
+func (server *Server) Shutdown(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		server.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.grpcServer.Stop()
+	}
+}
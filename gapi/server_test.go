@@ -0,0 +1,42 @@
+package gapi
+
+import (
+	"context"
+	"net"
+	mockdb "simplebank/db/mock"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestServerShutdown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// 先占用一个空闲端口得到地址
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl), nil)
+	server.config.GRPCServerAddress = address
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.Start()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, address, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	server.Shutdown(ctx)
+	require.NoError(t, <-errs)
+}
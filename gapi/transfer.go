@@ -0,0 +1,115 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "simplebank/db/sqlc"
+	"simplebank/pb"
+
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (server *Server) CreateTransfer(ctx context.Context, req *pb.CreateTransferRequest) (*pb.CreateTransferResponse, error) {
+	if violations := validateCreateTransferRequest(req); len(violations) > 0 {
+		return nil, invalidArgumentError(violations)
+	}
+
+	// 判断转账的货币类型与转出账户是否相同，以及转出账户是否属于本人
+	fromAccount, err := server.validAccountOwner(ctx, req.GetFromAccountId())
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCurrency(fromAccount, req.GetCurrency()); err != nil {
+		return nil, err
+	}
+
+	var result db.TransferTxResult
+	if len(req.GetQuoteId()) == 0 {
+		var toAccount db.Account
+		toAccount, err = server.store.GetAccount(ctx, req.GetToAccountId())
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, status.Errorf(codes.NotFound, "account not found")
+			}
+			return nil, status.Errorf(codes.Internal, "failed to get account: %s", err)
+		}
+		if err := checkCurrency(toAccount, req.GetCurrency()); err != nil {
+			return nil, err
+		}
+
+		result, err = server.store.TransferTx(ctx, db.TransferTxParams{
+			FromAccountId: req.GetFromAccountId(),
+			ToAccountId:   req.GetToAccountId(),
+			Amount:        req.GetAmount(),
+			Memo:          req.GetMemo(),
+		})
+	} else {
+		// 转入账户的币种由报价决定，在事务中校验
+		result, err = server.store.FXTransferTx(ctx, db.FXTransferTxParams{
+			FromAccountId: req.GetFromAccountId(),
+			ToAccountId:   req.GetToAccountId(),
+			Amount:        req.GetAmount(),
+			QuoteID:       uuid.MustParse(req.GetQuoteId()),
+			Memo:          req.GetMemo(),
+		})
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, status.Errorf(codes.NotFound, "%s", err)
+		case errors.Is(err, db.ErrInsufficientFunds):
+			return nil, businessError(errCodeInsufficientFunds, err)
+		case errors.Is(err, db.ErrInvalidQuote):
+			return nil, businessError(errCodeInvalidQuote, err)
+		case errors.Is(err, db.ErrAccountNotActive):
+			return nil, businessError(errCodeAccountNotActive, err)
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to create transfer: %s", err)
+		}
+	}
+
+	return &pb.CreateTransferResponse{
+		Transfer:    convertTransfer(result.Transfer),
+		FromAccount: convertAccount(result.FromAccount),
+		ToAccount:   convertAccount(result.ToAccount),
+		FromEntry:   convertEntry(result.FromEntry),
+		ToEntry:     convertEntry(result.ToEntry),
+	}, nil
+}
+
+func validateCreateTransferRequest(req *pb.CreateTransferRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := validateID(req.GetFromAccountId()); err != nil {
+		violations = append(violations, fieldViolation("from_account_id", err))
+	}
+	if err := validateID(req.GetToAccountId()); err != nil {
+		violations = append(violations, fieldViolation("to_account_id", err))
+	}
+	if req.GetAmount() <= 0 {
+		violations = append(violations, fieldViolation("amount", errors.New("must be greater than 0")))
+	}
+	if err := validateCurrency(req.GetCurrency()); err != nil {
+		violations = append(violations, fieldViolation("currency", err))
+	}
+	if len(req.GetQuoteId()) > 0 {
+		if _, err := uuid.Parse(req.GetQuoteId()); err != nil {
+			violations = append(violations, fieldViolation("quote_id", errors.New("must be a valid UUID")))
+		}
+	}
+	if err := validateStringLength(req.GetMemo(), 0, 255); err != nil {
+		violations = append(violations, fieldViolation("memo", err))
+	}
+	return violations
+}
+
+func checkCurrency(account db.Account, currency string) error {
+	if account.Currency != currency {
+		err := fmt.Errorf("account [%d] currency mismatch: [%v] vs [%v]", account.ID, account.Currency, currency)
+		return invalidArgumentError([]*errdetails.BadRequest_FieldViolation{fieldViolation("currency", err)})
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package gapi
+
+import (
+	"fmt"
+	mockdb "simplebank/db/mock"
+	db "simplebank/db/sqlc"
+	"simplebank/pb"
+	"simplebank/utils"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateTransferRPC(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account2.ID = account1.ID + 1
+	account1.Currency = utils.RMB
+	account2.Currency = utils.RMB
+
+	amount := int64(10)
+	result := db.TransferTxResult{
+		Transfer: db.Transfer{
+			ID:            1,
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        amount,
+			ToAmount:      amount,
+		},
+		FromAccount: account1,
+		ToAccount:   account2,
+		FromEntry:   db.Entry{ID: 1, AccountID: account1.ID, Amount: -amount},
+		ToEntry:     db.Entry{ID: 2, AccountID: account2.ID, Amount: amount},
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		req           *pb.CreateTransferRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rsp *pb.CreateTransferResponse, err error)
+	}{
+		{
+			name:     "OK",
+			username: user1.Username,
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      utils.RMB,
+				Memo:          "rent",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{
+						FromAccountId: account1.ID,
+						ToAccountId:   account2.ID,
+						Amount:        amount,
+						Memo:          "rent",
+					})).
+					Times(1).
+					Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, result.Transfer.ID, rsp.GetTransfer().GetId())
+				require.Equal(t, amount, rsp.GetTransfer().GetAmount())
+				require.Empty(t, rsp.GetTransfer().GetFxQuoteId())
+				require.Equal(t, account1.ID, rsp.GetFromAccount().GetId())
+				require.Equal(t, account2.ID, rsp.GetToAccount().GetId())
+				require.Equal(t, -amount, rsp.GetFromEntry().GetAmount())
+				require.Equal(t, amount, rsp.GetToEntry().GetAmount())
+			},
+		},
+		{
+			name:     "FXTransfer",
+			username: user1.Username,
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      utils.RMB,
+				QuoteId:       "0b5d6fd6-5bd2-4f5a-9a7a-7c1f7e0e8b1a",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				// 转入账户的币种由报价决定
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(0)
+				store.EXPECT().
+					FXTransferTx(gomock.Any(), gomock.Eq(db.FXTransferTxParams{
+						FromAccountId: account1.ID,
+						ToAccountId:   account2.ID,
+						Amount:        amount,
+						QuoteID:       uuid.MustParse("0b5d6fd6-5bd2-4f5a-9a7a-7c1f7e0e8b1a"),
+					})).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrInvalidQuote)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, err error) {
+				require.Equal(t, codes.FailedPrecondition, status.Code(err))
+			},
+		},
+		{
+			name:     "InsufficientFunds",
+			username: user1.Username,
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      utils.RMB,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, fmt.Errorf("%w: account [%d]", db.ErrInsufficientFunds, account1.ID))
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, err error) {
+				require.Equal(t, codes.FailedPrecondition, status.Code(err))
+			},
+		},
+		{
+			name:     "PermissionDenied",
+			username: user2.Username,
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      utils.RMB,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+			},
+		},
+		{
+			name:     "CurrencyMismatch",
+			username: user1.Username,
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        amount,
+				Currency:      utils.USD,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+		{
+			name:     "InvalidArguments",
+			username: user1.Username,
+			req: &pb.CreateTransferRequest{
+				FromAccountId: account1.ID,
+				ToAccountId:   account2.ID,
+				Amount:        -1,
+				Currency:      utils.RMB,
+				QuoteId:       "invalid",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateTransferResponse, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil)
+			client := pb.NewTransferServiceClient(newTestClient(t, server))
+
+			ctx := newContextWithBearerToken(t, server.tokenMaker, tc.username, time.Minute)
+			rsp, err := client.CreateTransfer(ctx, tc.req)
+			tc.checkResponse(t, rsp, err)
+		})
+	}
+}
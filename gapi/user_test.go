@@ -0,0 +1,221 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	mockdb "simplebank/db/mock"
+	db "simplebank/db/sqlc"
+	"simplebank/pb"
+	"simplebank/utils"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateUserRPC(t *testing.T) {
+	user, password := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		req           *pb.CreateUserRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rsp *pb.CreateUserResponse, err error)
+	}{
+		{
+			name: "OK",
+			req: &pb.CreateUserRequest{
+				Username: user.Username,
+				Password: password,
+				FullName: user.FullName,
+				Email:    user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateUserParams) (db.User, error) {
+						if arg.Username != user.Username || utils.CheckPassword(password, arg.HashedPassword) != nil {
+							return db.User{}, sql.ErrConnDone
+						}
+						return user, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, user.Username, rsp.GetUser().GetUsername())
+				require.Equal(t, user.FullName, rsp.GetUser().GetFullName())
+				require.Equal(t, user.Email, rsp.GetUser().GetEmail())
+				require.Nil(t, rsp.GetUser().GetPasswordChangedAt())
+			},
+		},
+		{
+			name: "InvalidArguments",
+			req: &pb.CreateUserRequest{
+				Username: user.Username,
+				Password: "123",
+				FullName: user.FullName,
+				Email:    "invalid-email",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+
+				// 每个不合法的字段都有一条说明
+				require.Len(t, st.Details(), 1)
+				badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+				require.True(t, ok)
+
+				fields := []string{}
+				for _, violation := range badRequest.GetFieldViolations() {
+					fields = append(fields, violation.GetField())
+				}
+				require.ElementsMatch(t, []string{"password", "email"}, fields)
+			},
+		},
+		{
+			name: "DuplicateUsername",
+			req: &pb.CreateUserRequest{
+				Username: user.Username,
+				Password: password,
+				FullName: user.FullName,
+				Email:    user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, &pq.Error{Code: "23505"})
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				require.Equal(t, codes.AlreadyExists, status.Code(err))
+			},
+		},
+		{
+			name: "InternalError",
+			req: &pb.CreateUserRequest{
+				Username: user.Username,
+				Password: password,
+				FullName: user.FullName,
+				Email:    user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				require.Equal(t, codes.Internal, status.Code(err))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil)
+			client := pb.NewUserServiceClient(newTestClient(t, server))
+
+			// 创建用户不需要认证
+			rsp, err := client.CreateUser(context.Background(), tc.req)
+			tc.checkResponse(t, rsp, err)
+		})
+	}
+}
+
+func TestLoginUserRPC(t *testing.T) {
+	user, password := randomUser(t)
+	var sessionArg db.CreateSessionParams
+
+	testCases := []struct {
+		name          string
+		req           *pb.LoginUserRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateSessionParams) (db.Session, error) {
+						// 在服务端的 goroutine 中不能调用 require，留到 checkResponse 中检查
+						sessionArg = arg
+						return db.Session{ID: arg.ID, Username: arg.Username}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, user.Username, rsp.GetUser().GetUsername())
+
+				require.Equal(t, user.Username, sessionArg.Username)
+				require.Equal(t, rsp.GetRefreshToken(), sessionArg.RefreshToken)
+				require.Contains(t, sessionArg.UserAgent, "grpc-go")
+				require.NotEmpty(t, sessionArg.ClientIp)
+
+				payload, err := server.tokenMaker.VerifyToken(rsp.GetAccessToken())
+				require.NoError(t, err)
+				require.Equal(t, user.Username, payload.Username)
+
+				payload, err = server.tokenMaker.VerifyToken(rsp.GetRefreshToken())
+				require.NoError(t, err)
+				require.Equal(t, payload.ID.String(), rsp.GetSessionId())
+			},
+		},
+		{
+			name: "UserNotFound",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				require.Equal(t, codes.NotFound, status.Code(err))
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			req:  &pb.LoginUserRequest{Username: user.Username, Password: "incorrect"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, rsp *pb.LoginUserResponse, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil)
+			client := pb.NewUserServiceClient(newTestClient(t, server))
+
+			rsp, err := client.LoginUser(context.Background(), tc.req)
+			tc.checkResponse(t, server, rsp, err)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package gapi
+
+import (
+	"fmt"
+	"net/mail"
+	"simplebank/utils"
+)
+
+func validateRequired(value string) error {
+	if len(value) == 0 {
+		return fmt.Errorf("is required")
+	}
+	return nil
+}
+
+func validateStringLength(value string, minLength, maxLength int) error {
+	if n := len(value); n < minLength || n > maxLength {
+		return fmt.Errorf("must contain from %d to %d characters", minLength, maxLength)
+	}
+	return nil
+}
+
+func validatePassword(value string) error {
+	return validateStringLength(value, 6, 100)
+}
+
+func validateEmail(value string) error {
+	if _, err := mail.ParseAddress(value); err != nil {
+		return fmt.Errorf("is not a valid email address")
+	}
+	return nil
+}
+
+func validateCurrency(value string) error {
+	if !utils.IsSupportCurrency(value) {
+		return fmt.Errorf("unsupported currency: %q", value)
+	}
+	return nil
+}
+
+func validateID(value int64) error {
+	if value < 1 {
+		return fmt.Errorf("must be a positive integer")
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// EnvironmentProduction 生产环境输出 JSON 日志，其他环境输出便于阅读的彩色日志
+const EnvironmentProduction = "production"
+
+// RequestIDKey 是 request ID 在 context 中的 key
+// 使用字符串是因为 *gin.Context 只会按字符串 key 查找 ctx.Set 保存的值，
+// 这样 handler 直接把 *gin.Context 传给 store 时也能取到 request ID
+const RequestIDKey = "request_id"
+
+// New 根据运行环境和日志级别创建 logger，级别为空时使用 info
+func New(w io.Writer, environment string, level string) (zerolog.Logger, error) {
+	logLevel := zerolog.InfoLevel
+	if len(level) > 0 {
+		var err error
+		logLevel, err = zerolog.ParseLevel(strings.ToLower(level))
+		if err != nil {
+			return zerolog.Logger{}, err
+		}
+	}
+
+	if environment != EnvironmentProduction {
+		w = zerolog.ConsoleWriter{Out: w, TimeFormat: time.RFC3339}
+	}
+	return zerolog.New(w).Level(logLevel).With().Timestamp().Logger(), nil
+}
+
+// Setup 创建写到标准错误的 logger 并替换全局的 logger，标准输出留给对账报告等命令输出
+func Setup(environment string, level string) error {
+	logger, err := New(os.Stderr, environment, level)
+	if err != nil {
+		return err
+	}
+
+	log.Logger = logger
+	return nil
+}
+
+// WithRequestID 返回带有 request ID 的 context
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, RequestIDKey, requestID)
+}
+
+// RequestID 返回 context 中的 request ID，没有时返回空字符串
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(RequestIDKey).(string)
+	return requestID
+}
+
+// Ctx 返回全局 logger，context 中的 request ID 和 trace ID 附加到日志字段中
+func Ctx(ctx context.Context) *zerolog.Logger {
+	fields := log.Logger.With()
+	if requestID := RequestID(ctx); len(requestID) > 0 {
+		fields = fields.Str(RequestIDKey, requestID)
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		fields = fields.Str("trace_id", spanContext.TraceID().String()).
+			Str("span_id", spanContext.SpanID().String())
+	}
+
+	logger := fields.Logger()
+	return &logger
+}
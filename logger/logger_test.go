@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name        string
+		environment string
+		level       string
+		check       func(t *testing.T, logger zerolog.Logger, buf *bytes.Buffer, err error)
+	}{
+		{
+			name:        "ProductionJSON",
+			environment: EnvironmentProduction,
+			level:       "warn",
+			check: func(t *testing.T, logger zerolog.Logger, buf *bytes.Buffer, err error) {
+				require.NoError(t, err)
+
+				logger.Info().Msg("ignored")
+				require.Zero(t, buf.Len())
+
+				logger.Warn().Str("key", "value").Msg("hello")
+				var entry map[string]interface{}
+				require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+				require.Equal(t, "warn", entry["level"])
+				require.Equal(t, "value", entry["key"])
+				require.Equal(t, "hello", entry["message"])
+				require.Contains(t, entry, "time")
+			},
+		},
+		{
+			name:        "DevelopmentConsole",
+			environment: "development",
+			level:       "DEBUG",
+			check: func(t *testing.T, logger zerolog.Logger, buf *bytes.Buffer, err error) {
+				require.NoError(t, err)
+
+				logger.Debug().Str("key", "value").Msg("hello")
+				require.False(t, json.Valid(buf.Bytes()))
+				require.Contains(t, buf.String(), "hello")
+				require.Contains(t, buf.String(), "value")
+			},
+		},
+		{
+			name: "DefaultLevel",
+			check: func(t *testing.T, logger zerolog.Logger, buf *bytes.Buffer, err error) {
+				require.NoError(t, err)
+				require.Equal(t, zerolog.InfoLevel, logger.GetLevel())
+			},
+		},
+		{
+			name:  "InvalidLevel",
+			level: "verbose",
+			check: func(t *testing.T, logger zerolog.Logger, buf *bytes.Buffer, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			logger, err := New(buf, tc.environment, tc.level)
+			tc.check(t, logger, buf, err)
+		})
+	}
+}
+
+func TestCtx(t *testing.T) {
+	buf := &bytes.Buffer{}
+	original := log.Logger
+	log.Logger = zerolog.New(buf)
+	defer func() {
+		log.Logger = original
+	}()
+
+	ctx := context.Background()
+	require.Empty(t, RequestID(ctx))
+
+	Ctx(ctx).Info().Msg("without request id")
+	require.NotContains(t, buf.String(), RequestIDKey)
+
+	buf.Reset()
+	ctx = WithRequestID(ctx, "request-1")
+	require.Equal(t, "request-1", RequestID(ctx))
+
+	Ctx(ctx).Info().Msg("with request id")
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.Equal(t, "request-1", entry[RequestIDKey])
+	require.NotContains(t, entry, "trace_id")
+
+	buf.Reset()
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	require.NoError(t, err)
+	ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+
+	Ctx(ctx).Info().Msg("with span")
+	entry = map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.Equal(t, traceID.String(), entry["trace_id"])
+	require.Equal(t, spanID.String(), entry["span_id"])
+}
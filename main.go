@@ -1,30 +1,371 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
 	"simplebank/api"
+	"simplebank/db/migration"
 	db "simplebank/db/sqlc"
+	"simplebank/gapi"
+	"simplebank/logger"
+	"simplebank/metrics"
+	"simplebank/reconcile"
+	"simplebank/scheduler"
+	"simplebank/tracing"
 	"simplebank/utils"
+	"simplebank/webhook"
+	"strconv"
+	"syscall"
 
 	_ "github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
 )
 
 func main() {
 	config, err := utils.LoadConig(".")
 	if err != nil {
-		log.Fatal("cannot load config:", err)
+		log.Fatal().Err(err).Msg("cannot load config")
 	}
+	if err := logger.Setup(config.Environment, config.LogLevel); err != nil {
+		log.Fatal().Err(err).Msg("cannot set up logger")
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(config, os.Args[2:]))
+	}
+
 	conn, err := sql.Open(config.DBDriver, config.DBSource)
 	if err != nil {
-		log.Fatal("cannot connect to db:", err)
+		log.Fatal().Err(err).Msg("cannot connect to db")
+	}
+
+	transferIsolation, err := db.ParseIsolationLevel(config.TransferIsolation)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid TRANSFER_ISOLATION")
+	}
+
+	store := db.NewStoreWithTxConfig(conn, db.TxConfig{
+		TransferIsolation: transferIsolation,
+		MaxAttempts:       config.TxMaxAttempts,
+		RetryBackoff:      config.TxRetryBackoff,
+	})
+	if err := metrics.RegisterDBStats(conn, databaseName(config)); err != nil {
+		log.Fatal().Err(err).Msg("cannot register db metrics")
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		os.Exit(runReconcile(store, config, os.Args[2:]))
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(),
+		config.TracingExporter, config.OTLPEndpoint, config.TracingSampleRatio)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot set up tracing")
+	}
+
+	// 数据库没有迁移到代码要求的版本时拒绝启动，避免导入汇率和处理请求时访问不存在的表或字段
+	if err := prepareSchema(config); err != nil {
+		log.Fatal().Err(err).Msg("database schema is not ready, run `migrate up` or set MIGRATE_ON_START=true")
+	}
+
+	if len(config.ExchangeRatesFile) > 0 {
+		err = loadExchangeRates(store, config.ExchangeRatesFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot load exchange rates")
+		}
 	}
 
-	store := db.NewStore(conn)
 	server, err := api.NewServer(config, store)
 	if err != nil {
-		log.Fatal("cannot create token maker:", err)
+		log.Fatal().Err(err).Msg("cannot create server")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = runServer(ctx, config, store, server)
+
+	// 导出缓冲中剩余的 span
+	if err := shutdownTracing(context.Background()); err != nil {
+		log.Error().Err(err).Msg("cannot shut down tracing")
+	}
+	conn.Close()
+
+	if err != nil {
+		log.Fatal().Err(err).Msg("server stopped with error")
+	}
+	log.Info().Msg("server stopped")
+}
+
+// runServer 启动 HTTP、gRPC 服务和后台任务，直到 ctx 被取消（收到 SIGTERM）或任意服务启动失败
+// 之后停止接收新的请求，在 SHUTDOWN_TIMEOUT 内等待处理中的请求完成，并等待后台任务退出
+func runServer(ctx context.Context, config utils.Config, store db.Store, server *api.Server) error {
+	group, ctx := errgroup.WithContext(ctx)
+
+	group.Go(func() error {
+		server.StartReconciler(ctx)
+		return nil
+	})
+
+	group.Go(func() error {
+		server.StartIdempotencyKeyPurger(ctx)
+		return nil
+	})
+
+	group.Go(func() error {
+		// 监听失败时 SSE 连接收不到推送，不影响其他接口
+		if err := server.StartActivityListener(ctx); err != nil {
+			log.Error().Err(err).Msg("cannot listen for account activity")
+		}
+		return nil
+	})
+
+	if config.SchedulerInterval > 0 {
+		worker := scheduler.NewWorker(store, config.SchedulerBatchSize,
+			config.ScheduledTransferMaxFailures, config.ScheduledTransferRetryInterval)
+		group.Go(func() error {
+			worker.Start(ctx, config.SchedulerInterval)
+			return nil
+		})
+	}
+
+	if config.WebhookDispatchInterval > 0 {
+		dispatcher := webhook.NewDispatcher(store, config.WebhookBatchSize,
+			config.WebhookMaxAttempts, config.WebhookRetryBackoff)
+		group.Go(func() error {
+			dispatcher.Start(ctx, config.WebhookDispatchInterval)
+			return nil
+		})
+	}
+
+	var grpcServer *gapi.Server
+	if len(config.GRPCServerAddress) > 0 {
+		grpcServer = gapi.NewServer(config, store, server.TokenMaker(), server.IsTokenRevoked)
+		group.Go(grpcServer.Start)
+	}
+
+	var metricsServer *http.Server
+	if len(config.MetricsAddress) > 0 {
+		metricsServer = metrics.NewServer(config.MetricsAddress)
+		group.Go(func() error {
+			log.Info().Str("address", config.MetricsAddress).Msg("start metrics server")
+			err := metricsServer.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("cannot start metrics server: %w", err)
+			}
+			return nil
+		})
+	}
+
+	group.Go(server.Start)
+
+	group.Go(func() error {
+		<-ctx.Done()
+		log.Info().Msg("shutting down")
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+		defer cancel()
+
+		if grpcServer != nil {
+			grpcServer.Shutdown(shutdownCtx)
+		}
+		if metricsServer != nil {
+			metricsServer.Shutdown(shutdownCtx)
+		}
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("cannot drain HTTP requests: %w", err)
+		}
+		return nil
+	})
+
+	return group.Wait()
+}
+
+// runReconcile 执行一次对账并输出报告
+// 退出码：0 账本一致，1 执行失败，2 发现差异
+func runReconcile(store db.Store, config utils.Config, args []string) int {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	format := flags.String("format", reconcile.FormatJSON, "report format: json or csv")
+	output := flags.String("output", "", "report file, defaults to stdout")
+	batchSize := flags.Int("batch-size", int(config.ReconcileBatchSize), "number of rows checked per query")
+	flags.Parse(args)
+
+	if *format != reconcile.FormatJSON && *format != reconcile.FormatCSV {
+		log.Error().Str("format", *format).Msg("unsupported report format")
+		return 1
 	}
 
-	log.Fatal(server.Start())
+	var w io.Writer = os.Stdout
+	if len(*output) > 0 {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Error().Err(err).Msg("cannot create report file")
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+
+	report, err := reconcile.NewReconciler(store, int32(*batchSize)).Run(context.Background())
+	if err != nil {
+		log.Error().Err(err).Msg("reconciliation failed")
+		return 1
+	}
+
+	if err := report.Write(w, *format); err != nil {
+		log.Error().Err(err).Msg("cannot write report")
+		return 1
+	}
+
+	log.Info().
+		Int64("accounts_checked", report.AccountsChecked).
+		Int64("transfers_checked", report.TransfersChecked).
+		Int("discrepancies", len(report.Discrepancies)).
+		Msg("reconciliation finished")
+	if !report.OK() {
+		return 2
+	}
+	return 0
+}
+
+// prepareSchema 在 MIGRATE_ON_START 开启时执行迁移，然后检查数据库是否已经迁移到最新版本
+// 多个实例同时启动时由 golang-migrate 的 advisory lock 保证迁移只执行一次
+func prepareSchema(config utils.Config) error {
+	migrator, err := migration.NewMigrator(config.DBSource)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	if config.MigrateOnStart {
+		if err := migrator.Up(0); err != nil {
+			return fmt.Errorf("cannot migrate database: %w", err)
+		}
+	}
+	return migrator.Check()
+}
+
+// runMigrate 使用嵌入的迁移文件迁移数据库
+// 用法：migrate up [N] | migrate down [N|all] | migrate version
+func runMigrate(config utils.Config, args []string) int {
+	command, steps, err := parseMigrateArgs(args)
+	if err != nil {
+		log.Error().Err(err).Msg("usage: migrate up [N] | down [N|all] | version")
+		return 1
+	}
+
+	migrator, err := migration.NewMigrator(config.DBSource)
+	if err != nil {
+		log.Error().Err(err).Msg("cannot connect to db")
+		return 1
+	}
+	defer migrator.Close()
+
+	switch command {
+	case "up":
+		err = migrator.Up(steps)
+	case "down":
+		if steps < 0 {
+			err = migrator.DownAll()
+		} else {
+			err = migrator.Down(steps)
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Str("command", command).Msg("migration failed")
+		return 1
+	}
+
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		log.Error().Err(err).Msg("cannot get schema version")
+		return 1
+	}
+	latest, err := migration.LatestVersion()
+	if err != nil {
+		log.Error().Err(err).Msg("cannot read migrations")
+		return 1
+	}
+
+	log.Info().
+		Uint("version", version).
+		Bool("dirty", dirty).
+		Uint("latest", latest).
+		Msg("schema version")
+	return 0
+}
+
+// parseMigrateArgs 解析 migrate 子命令的参数，up 不指定步数时迁移到最新版本
+// down 默认只回滚一个版本，回滚所有版本需要显式指定 all，steps 返回 -1
+func parseMigrateArgs(args []string) (command string, steps int, err error) {
+	if len(args) == 0 {
+		return "", 0, errors.New("missing migrate command")
+	}
+
+	command = args[0]
+	switch command {
+	case "up", "down":
+		if len(args) > 2 {
+			return "", 0, fmt.Errorf("too many arguments for migrate %s", command)
+		}
+		if command == "down" {
+			steps = 1
+		}
+		if command == "down" && len(args) == 2 && args[1] == "all" {
+			return command, -1, nil
+		}
+		if len(args) == 2 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return "", 0, fmt.Errorf("invalid number of steps: %q", args[1])
+			}
+		}
+	case "version":
+		if len(args) > 1 {
+			return "", 0, errors.New("too many arguments for migrate version")
+		}
+	default:
+		return "", 0, fmt.Errorf("unknown migrate command: %q", command)
+	}
+	return command, steps, nil
+}
+
+// loadExchangeRates 从本地 CSV 文件导入汇率，离线环境下也可以使用跨币种转账
+func loadExchangeRates(store db.Store, path string) error {
+	rates, err := utils.LoadExchangeRates(path)
+	if err != nil {
+		return err
+	}
+
+	for _, rate := range rates {
+		_, err = store.UpsertExchangeRate(context.Background(), db.UpsertExchangeRateParams{
+			BaseCurrency:  rate.BaseCurrency,
+			QuoteCurrency: rate.QuoteCurrency,
+			Rate:          rate.Rate,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Info().Int("count", len(rates)).Str("path", path).Msg("loaded exchange rates")
+	return nil
+}
+
+// databaseName 返回连接的数据库名称，作为连接池指标的标签
+func databaseName(config utils.Config) string {
+	source, err := url.Parse(config.DBSource)
+	if err != nil || len(source.Path) <= 1 {
+		return config.DBDriver
+	}
+	return source.Path[1:]
 }
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "simplebank"
+
+const readHeaderTimeout = 10 * time.Second
+
+// Path 是暴露指标的路径
+const Path = "/metrics"
+
+// 转账结果，失败时为对应的业务错误
+const (
+	TransferOutcomeSuccess           = "success"
+	TransferOutcomeInsufficientFunds = "insufficient_funds"
+	TransferOutcomeAccountNotActive  = "account_not_active"
+	TransferOutcomeAccountNotFound   = "account_not_found"
+	TransferOutcomeInvalidQuote      = "invalid_quote"
+	TransferOutcomeError             = "error"
+)
+
+// 事务结果
+const (
+	TxOutcomeCommitted      = "committed"
+	TxOutcomeRolledBack     = "rolled_back"
+	TxOutcomeCommitFailed   = "commit_failed"
+	TxOutcomeRollbackFailed = "rollback_failed"
+)
+
+var (
+	// HTTPRequests 按 Gin 路由统计的请求数量，未匹配路由的请求 route 为空
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests by route, method and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration 按 Gin 路由统计的请求耗时
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of HTTP requests by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// Transfers 按转出币种和结果统计的转账数量
+	Transfers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_total",
+		Help:      "Number of transfers by source currency and outcome.",
+	}, []string{"currency", "outcome"})
+
+	// TransferAmount 按转出币种统计的成功转账金额，单位与账户余额相同
+	TransferAmount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfer_amount_total",
+		Help:      "Total amount of successful transfers by source currency, in minor units.",
+	}, []string{"currency"})
+
+	// Transactions 按结果统计的数据库事务数量
+	Transactions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "transactions_total",
+		Help:      "Number of database transactions by outcome.",
+	}, []string{"outcome"})
+
+	// TransactionRetries 因序列化失败或死锁而重试的数据库事务次数
+	TransactionRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "transaction_retries_total",
+		Help:      "Number of database transactions retried after a serialization failure or deadlock.",
+	})
+)
+
+// RegisterDBStats 注册数据库连接池的指标
+func RegisterDBStats(db *sql.DB, dbName string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// Handler 返回输出所有指标的 handler
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// NewServer 返回在单独的地址上提供指标的服务，避免指标暴露在对外的 API 端口上
+func NewServer(address string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(Path, Handler())
+
+	return &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+}
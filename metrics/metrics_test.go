@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	Transfers.WithLabelValues("USD", TransferOutcomeSuccess).Inc()
+	TransferAmount.WithLabelValues("USD").Add(100)
+	Transactions.WithLabelValues(TxOutcomeCommitted).Inc()
+
+	// sql.Open 不会连接数据库，连接池指标只读取 db.Stats()
+	db, err := sql.Open("postgres", "postgresql://localhost/simple_bank")
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, RegisterDBStats(db, "simple_bank"))
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, Path, nil)
+	require.NoError(t, err)
+
+	Handler().ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	body := recorder.Body.String()
+	require.Contains(t, body, `simplebank_transfers_total{currency="USD",outcome="success"}`)
+	require.Contains(t, body, `simplebank_transfer_amount_total{currency="USD"}`)
+	require.Contains(t, body, `simplebank_db_transactions_total{outcome="committed"}`)
+	require.Contains(t, body, "simplebank_db_transaction_retries_total 0")
+	require.Contains(t, body, `go_sql_open_connections{db_name="simple_bank"} 0`)
+}
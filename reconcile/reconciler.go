@@ -0,0 +1,162 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	db "simplebank/db/sqlc"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultBatchSize 每批检查的账户或转账数量
+const DefaultBatchSize = 1000
+
+// Reconciler 核对账本：账户余额等于流水合计，每笔转账恰好有一条扣账和一条入账流水
+type Reconciler struct {
+	store     db.Querier
+	batchSize int32
+
+	mu   sync.RWMutex
+	last *Report
+}
+
+// NewReconciler creates a new Reconciler
+func NewReconciler(store db.Querier, batchSize int32) *Reconciler {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	return &Reconciler{
+		store:     store,
+		batchSize: batchSize,
+	}
+}
+
+// Run 分批扫描整个账本并返回对账报告，成功完成的报告会作为最近一次结果保存
+func (r *Reconciler) Run(ctx context.Context) (Report, error) {
+	report := Report{
+		StartedAt:     time.Now(),
+		Discrepancies: []Discrepancy{},
+	}
+
+	if err := r.checkBalances(ctx, &report); err != nil {
+		return report, fmt.Errorf("cannot check account balances: %w", err)
+	}
+
+	if err := r.checkTransfers(ctx, &report); err != nil {
+		return report, fmt.Errorf("cannot check transfers: %w", err)
+	}
+
+	report.FinishedAt = time.Now()
+
+	r.mu.Lock()
+	r.last = &report
+	r.mu.Unlock()
+
+	return report, nil
+}
+
+// Last 返回最近一次完成的对账报告
+func (r *Reconciler) Last() (Report, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.last == nil {
+		return Report{}, false
+	}
+	return *r.last, true
+}
+
+// Start 按 interval 定期对账，直到 ctx 被取消
+func (r *Reconciler) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := r.Run(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("reconciliation failed")
+				continue
+			}
+			if !report.OK() {
+				log.Warn().Int("discrepancies", len(report.Discrepancies)).Msg("reconciliation found discrepancies")
+			}
+		}
+	}
+}
+
+func (r *Reconciler) checkBalances(ctx context.Context, report *Report) error {
+	var afterID int64
+	for {
+		rows, err := r.store.ListAccountBalances(ctx, db.ListAccountBalancesParams{
+			AfterID: afterID,
+			Limit:   r.batchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			if row.Balance != row.EntriesTotal {
+				report.Discrepancies = append(report.Discrepancies, Discrepancy{
+					Kind:      KindBalanceMismatch,
+					AccountID: row.ID,
+					Expected:  row.EntriesTotal,
+					Actual:    row.Balance,
+				})
+			}
+		}
+		report.AccountsChecked += int64(len(rows))
+
+		if len(rows) < int(r.batchSize) {
+			return nil
+		}
+		afterID = rows[len(rows)-1].ID
+	}
+}
+
+func (r *Reconciler) checkTransfers(ctx context.Context, report *Report) error {
+	var afterID int64
+	for {
+		rows, err := r.store.ListTransferEntryCounts(ctx, db.ListTransferEntryCountsParams{
+			AfterID: afterID,
+			Limit:   r.batchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			if row.DebitEntries != 1 {
+				report.Discrepancies = append(report.Discrepancies, Discrepancy{
+					Kind:       KindTransferDebit,
+					AccountID:  row.FromAccountID,
+					TransferID: row.ID,
+					Expected:   1,
+					Actual:     row.DebitEntries,
+				})
+			}
+			if row.CreditEntries != 1 {
+				report.Discrepancies = append(report.Discrepancies, Discrepancy{
+					Kind:       KindTransferCredit,
+					AccountID:  row.ToAccountID,
+					TransferID: row.ID,
+					Expected:   1,
+					Actual:     row.CreditEntries,
+				})
+			}
+		}
+		report.TransfersChecked += int64(len(rows))
+
+		if len(rows) < int(r.batchSize) {
+			return nil
+		}
+		afterID = rows[len(rows)-1].ID
+	}
+}
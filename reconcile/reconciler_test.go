@@ -0,0 +1,86 @@
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	mockdb "simplebank/db/mock"
+	db "simplebank/db/sqlc"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcilerRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	// 每批 2 条，账户分 2 批返回，转账 1 批返回
+	gomock.InOrder(
+		store.EXPECT().
+			ListAccountBalances(gomock.Any(), gomock.Eq(db.ListAccountBalancesParams{AfterID: 0, Limit: 2})).
+			Times(1).
+			Return([]db.ListAccountBalancesRow{
+				{ID: 1, Balance: 100, EntriesTotal: 100},
+				{ID: 2, Balance: 50, EntriesTotal: 40},
+			}, nil),
+		store.EXPECT().
+			ListAccountBalances(gomock.Any(), gomock.Eq(db.ListAccountBalancesParams{AfterID: 2, Limit: 2})).
+			Times(1).
+			Return([]db.ListAccountBalancesRow{
+				{ID: 3, Balance: 0, EntriesTotal: 0},
+			}, nil),
+	)
+	store.EXPECT().
+		ListTransferEntryCounts(gomock.Any(), gomock.Eq(db.ListTransferEntryCountsParams{AfterID: 0, Limit: 2})).
+		Times(1).
+		Return([]db.ListTransferEntryCountsRow{
+			{ID: 7, FromAccountID: 1, ToAccountID: 2, Amount: 10, ToAmount: 10, DebitEntries: 1, CreditEntries: 0},
+		}, nil)
+
+	reconciler := NewReconciler(store, 2)
+
+	_, ok := reconciler.Last()
+	require.False(t, ok)
+
+	report, err := reconciler.Run(context.Background())
+	require.NoError(t, err)
+	require.False(t, report.OK())
+	require.Equal(t, int64(3), report.AccountsChecked)
+	require.Equal(t, int64(1), report.TransfersChecked)
+	require.False(t, report.FinishedAt.Before(report.StartedAt))
+	require.Equal(t, []Discrepancy{
+		{Kind: KindBalanceMismatch, AccountID: 2, Expected: 40, Actual: 50},
+		{Kind: KindTransferCredit, AccountID: 2, TransferID: 7, Expected: 1, Actual: 0},
+	}, report.Discrepancies)
+
+	last, ok := reconciler.Last()
+	require.True(t, ok)
+	require.Equal(t, report, last)
+}
+
+func TestReconcilerRunError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListAccountBalances(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil, sql.ErrConnDone)
+	store.EXPECT().
+		ListTransferEntryCounts(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	reconciler := NewReconciler(store, 0)
+	require.Equal(t, int32(DefaultBatchSize), reconciler.batchSize)
+
+	_, err := reconciler.Run(context.Background())
+	require.ErrorIs(t, err, sql.ErrConnDone)
+
+	// 失败的对账不覆盖最近一次结果
+	_, ok := reconciler.Last()
+	require.False(t, ok)
+}
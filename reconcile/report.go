@@ -0,0 +1,99 @@
+package reconcile
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// 对账差异类型
+const (
+	// KindBalanceMismatch 账户余额与流水合计不一致，Expected 为流水合计，Actual 为账户余额
+	KindBalanceMismatch = "balance_mismatch"
+	// KindTransferDebit 转账对应的扣账流水数量不为 1
+	KindTransferDebit = "transfer_debit_entries"
+	// KindTransferCredit 转账对应的入账流水数量不为 1
+	KindTransferCredit = "transfer_credit_entries"
+)
+
+// Discrepancy 一条对账差异
+type Discrepancy struct {
+	Kind       string `json:"kind"`
+	AccountID  int64  `json:"account_id"`
+	TransferID int64  `json:"transfer_id,omitempty"`
+	Expected   int64  `json:"expected"`
+	Actual     int64  `json:"actual"`
+}
+
+// Report 一次对账的结果
+type Report struct {
+	StartedAt        time.Time     `json:"started_at"`
+	FinishedAt       time.Time     `json:"finished_at"`
+	AccountsChecked  int64         `json:"accounts_checked"`
+	TransfersChecked int64         `json:"transfers_checked"`
+	Discrepancies    []Discrepancy `json:"discrepancies"`
+}
+
+// OK 账本没有差异时返回 true
+func (report Report) OK() bool {
+	return len(report.Discrepancies) == 0
+}
+
+// 支持的报告格式
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// Write 按 format 输出报告，JSON 包含完整报告，CSV 只包含差异明细
+func (report Report) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		return report.WriteJSON(w)
+	case FormatCSV:
+		return report.WriteCSV(w)
+	default:
+		return fmt.Errorf("unsupported report format: %q", format)
+	}
+}
+
+// WriteJSON writes the report as indented JSON.
+func (report Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// WriteCSV writes the discrepancies as CSV with a header line.
+func (report Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write([]string{"kind", "account_id", "transfer_id", "expected", "actual"})
+	if err != nil {
+		return err
+	}
+
+	for _, d := range report.Discrepancies {
+		transferID := ""
+		if d.TransferID > 0 {
+			transferID = strconv.FormatInt(d.TransferID, 10)
+		}
+
+		err = writer.Write([]string{
+			d.Kind,
+			strconv.FormatInt(d.AccountID, 10),
+			transferID,
+			strconv.FormatInt(d.Expected, 10),
+			strconv.FormatInt(d.Actual, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,59 @@
+package reconcile
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func randomReport() Report {
+	return Report{
+		StartedAt:        time.Now().UTC().Truncate(time.Second),
+		FinishedAt:       time.Now().UTC().Truncate(time.Second),
+		AccountsChecked:  2,
+		TransfersChecked: 1,
+		Discrepancies: []Discrepancy{
+			{Kind: KindBalanceMismatch, AccountID: 1, Expected: 40, Actual: 50},
+			{Kind: KindTransferDebit, AccountID: 1, TransferID: 3, Expected: 1, Actual: 2},
+		},
+	}
+}
+
+func TestReportWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := randomReport().Write(&buf, FormatCSV)
+	require.NoError(t, err)
+
+	expected := "kind,account_id,transfer_id,expected,actual\n" +
+		"balance_mismatch,1,,40,50\n" +
+		"transfer_debit_entries,1,3,1,2\n"
+	require.Equal(t, expected, buf.String())
+}
+
+func TestReportWriteJSON(t *testing.T) {
+	report := randomReport()
+
+	var buf bytes.Buffer
+	err := report.Write(&buf, FormatJSON)
+	require.NoError(t, err)
+
+	var gotReport Report
+	err = json.Unmarshal(buf.Bytes(), &gotReport)
+	require.NoError(t, err)
+	require.Equal(t, report, gotReport)
+}
+
+func TestReportWriteUnsupportedFormat(t *testing.T) {
+	var buf bytes.Buffer
+	err := randomReport().Write(&buf, "xml")
+	require.Error(t, err)
+	require.Empty(t, buf.String())
+}
+
+func TestReportOK(t *testing.T) {
+	require.True(t, Report{}.OK())
+	require.False(t, randomReport().OK())
+}
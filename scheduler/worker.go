@@ -0,0 +1,92 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	db "simplebank/db/sqlc"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultBatchSize 每轮最多执行的定时转账数量
+	DefaultBatchSize = 100
+	// DefaultMaxFailures 连续余额不足达到该次数后暂停定时转账
+	DefaultMaxFailures = 3
+)
+
+// Worker 定期执行到期的定时转账
+// 每笔定时转账在独立的事务中执行，多个实例可以同时运行
+type Worker struct {
+	store         db.Store
+	batchSize     int
+	maxFailures   int32
+	retryInterval time.Duration
+}
+
+// NewWorker creates a new Worker
+func NewWorker(store db.Store, batchSize int, maxFailures int32, retryInterval time.Duration) *Worker {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	if maxFailures <= 0 {
+		maxFailures = DefaultMaxFailures
+	}
+
+	return &Worker{
+		store:         store,
+		batchSize:     batchSize,
+		maxFailures:   maxFailures,
+		retryInterval: retryInterval,
+	}
+}
+
+// RunOnce 依次执行到期的定时转账，直到没有到期的记录或达到 batchSize，返回执行的数量
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	for count := 0; count < w.batchSize; count++ {
+		result, err := w.store.ExecuteScheduledTransferTx(ctx, db.ExecuteScheduledTransferTxParams{
+			Now:           now,
+			MaxFailures:   w.maxFailures,
+			RetryInterval: w.retryInterval,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return count, nil
+			}
+			return count, err
+		}
+
+		execution := result.Execution
+		if execution.Status == db.ExecutionStatusFailed {
+			log.Warn().
+				Int64("scheduled_transfer_id", execution.ScheduledTransferID).
+				Int32("failure_count", result.ScheduledTransfer.FailureCount).
+				Str("status", string(result.ScheduledTransfer.Status)).
+				Str("error", execution.Error).
+				Msg("scheduled transfer failed")
+		}
+	}
+
+	return w.batchSize, nil
+}
+
+// Start 按 interval 定期执行到期的定时转账，直到 ctx 被取消
+func (w *Worker) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := w.RunOnce(ctx); err != nil {
+				log.Error().Err(err).Msg("cannot run scheduled transfers")
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	mockdb "simplebank/db/mock"
+	db "simplebank/db/sqlc"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerRunOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	checkParams := func(_ context.Context, arg db.ExecuteScheduledTransferTxParams) {
+		require.Equal(t, int32(5), arg.MaxFailures)
+		require.Equal(t, time.Hour, arg.RetryInterval)
+		require.WithinDuration(t, time.Now(), arg.Now, time.Second)
+	}
+
+	// 两笔到期的定时转账，一笔成功一笔余额不足，之后没有到期的记录
+	gomock.InOrder(
+		store.EXPECT().
+			ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			Do(checkParams).
+			Return(db.ExecuteScheduledTransferTxResult{
+				Execution: db.ScheduledTransferExecution{ScheduledTransferID: 1, Status: db.ExecutionStatusSucceeded},
+			}, nil),
+		store.EXPECT().
+			ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			Do(checkParams).
+			Return(db.ExecuteScheduledTransferTxResult{
+				ScheduledTransfer: db.ScheduledTransfer{ID: 2, Status: db.ScheduledTransferStatusActive, FailureCount: 1},
+				Execution:         db.ScheduledTransferExecution{ScheduledTransferID: 2, Status: db.ExecutionStatusFailed, Error: "insufficient funds"},
+			}, nil),
+		store.EXPECT().
+			ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(db.ExecuteScheduledTransferTxResult{}, sql.ErrNoRows),
+	)
+
+	worker := NewWorker(store, 10, 5, time.Hour)
+	count, err := worker.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+}
+
+func TestWorkerRunOnceBatchSize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+		Times(3).
+		Return(db.ExecuteScheduledTransferTxResult{}, nil)
+
+	// 每轮最多执行 batchSize 笔，剩余的留到下一轮
+	worker := NewWorker(store, 3, 0, time.Hour)
+	count, err := worker.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+}
+
+func TestWorkerRunOnceError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().
+			ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(db.ExecuteScheduledTransferTxResult{}, nil),
+		store.EXPECT().
+			ExecuteScheduledTransferTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(db.ExecuteScheduledTransferTxResult{}, sql.ErrConnDone),
+	)
+
+	worker := NewWorker(store, 10, 3, time.Hour)
+	count, err := worker.RunOnce(context.Background())
+	require.ErrorIs(t, err, sql.ErrConnDone)
+	require.Equal(t, 1, count)
+}
@@ -0,0 +1,158 @@
+package stream
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+// Channel 与 NotifyAccountActivity 查询中的频道名一致，通知内容为账户 id
+const Channel = "account_activity"
+
+const (
+	minReconnectInterval = 10 * time.Second
+	maxReconnectInterval = time.Minute
+	// 定期 ping 以尽早发现断开的连接
+	pingInterval = 90 * time.Second
+)
+
+// Broker 把 Postgres 的账户动态通知分发给订阅了该账户的连接
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[int64]map[chan struct{}]struct{}
+	// onListenerEvent 不为空时收到 listener 的连接事件，测试中用于确认 Start 正在等待连接
+	onListenerEvent pq.EventCallbackType
+}
+
+// NewBroker creates a new Broker
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[int64]map[chan struct{}]struct{}),
+	}
+}
+
+// Subscribe 订阅账户动态，返回的 channel 在账户有新流水时收到信号
+// 订阅方处理不及时时多次通知会合并为一次，订阅方收到信号后应自行查询新数据
+func (b *Broker) Subscribe(accountID int64) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	b.mu.Lock()
+	if b.subscribers[accountID] == nil {
+		b.subscribers[accountID] = make(map[chan struct{}]struct{})
+	}
+	b.subscribers[accountID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subscribers[accountID], ch)
+		if len(b.subscribers[accountID]) == 0 {
+			delete(b.subscribers, accountID)
+		}
+	}
+	return ch, unsubscribe
+}
+
+// Publish 通知订阅了该账户的所有连接
+func (b *Broker) Publish(accountID int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[accountID] {
+		signal(ch)
+	}
+}
+
+// 重新连接期间的通知可能丢失，通知所有订阅方重新查询
+func (b *Broker) publishAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, subscribers := range b.subscribers {
+		for ch := range subscribers {
+			signal(ch)
+		}
+	}
+}
+
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// Start 连接数据库并监听账户动态，直到 ctx 被取消
+func (b *Broker) Start(ctx context.Context, dataSourceName string) error {
+	listener := pq.NewListener(dataSourceName, minReconnectInterval, maxReconnectInterval,
+		func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				log.Warn().Err(err).Msg("account activity listener")
+			}
+			if b.onListenerEvent != nil {
+				b.onListenerEvent(event, err)
+			}
+		})
+	defer listener.Close()
+
+	// 数据库不可用时 Listen 会一直等待连接，ctx 被取消时关闭 listener 结束等待
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			listener.Close()
+		case <-done:
+		}
+	}()
+
+	if err := listener.Listen(Channel); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	log.Info().Str("channel", Channel).Msg("listening for account activity")
+	b.Listen(ctx, listener.Notify, listener.Ping)
+	return nil
+}
+
+// Listen 把 notifications 中的通知分发给订阅方，直到 ctx 被取消或 notifications 被关闭
+// 重新连接后 pq 会发送 nil 通知
+func (b *Broker) Listen(ctx context.Context, notifications <-chan *pq.Notification, ping func() error) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n, ok := <-notifications:
+			if !ok {
+				return
+			}
+			if n == nil {
+				b.publishAll()
+				continue
+			}
+
+			accountID, err := strconv.ParseInt(n.Extra, 10, 64)
+			if err != nil {
+				log.Warn().Err(err).Str("payload", n.Extra).Msg("invalid account activity notification")
+				continue
+			}
+			b.Publish(accountID)
+		case <-ticker.C:
+			if err := ping(); err != nil {
+				log.Warn().Err(err).Msg("account activity listener ping failed")
+			}
+		}
+	}
+}
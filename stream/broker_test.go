@@ -0,0 +1,115 @@
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func requireSignaled(t *testing.T, ch <-chan struct{}) {
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("subscriber was not signaled")
+	}
+}
+
+func requireNotSignaled(t *testing.T, ch <-chan struct{}) {
+	select {
+	case <-ch:
+		t.Fatal("subscriber was signaled unexpectedly")
+	default:
+	}
+}
+
+func TestBrokerPublish(t *testing.T) {
+	broker := NewBroker()
+
+	ch1, unsubscribe1 := broker.Subscribe(1)
+	ch2, unsubscribe2 := broker.Subscribe(1)
+	ch3, unsubscribe3 := broker.Subscribe(2)
+	defer unsubscribe2()
+	defer unsubscribe3()
+
+	broker.Publish(1)
+	requireSignaled(t, ch1)
+	requireSignaled(t, ch2)
+	requireNotSignaled(t, ch3)
+
+	// 未处理的多次通知合并为一次
+	broker.Publish(2)
+	broker.Publish(2)
+	requireSignaled(t, ch3)
+	requireNotSignaled(t, ch3)
+
+	unsubscribe1()
+	broker.Publish(1)
+	requireNotSignaled(t, ch1)
+	requireSignaled(t, ch2)
+}
+
+func TestBrokerListen(t *testing.T) {
+	broker := NewBroker()
+
+	ch1, unsubscribe1 := broker.Subscribe(1)
+	ch2, unsubscribe2 := broker.Subscribe(2)
+	defer unsubscribe1()
+	defer unsubscribe2()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	notifications := make(chan *pq.Notification)
+	done := make(chan struct{})
+	go func() {
+		broker.Listen(ctx, notifications, func() error { return nil })
+		close(done)
+	}()
+
+	notifications <- &pq.Notification{Channel: Channel, Extra: "invalid"}
+	notifications <- &pq.Notification{Channel: Channel, Extra: "2"}
+	requireSignaled(t, ch2)
+	requireNotSignaled(t, ch1)
+
+	// 重新连接后通知所有订阅方
+	notifications <- nil
+	requireSignaled(t, ch1)
+	requireSignaled(t, ch2)
+
+	cancel()
+	<-done
+}
+
+func TestBrokerStartCanceledWhileConnecting(t *testing.T) {
+	broker := NewBroker()
+	attempted := make(chan struct{}, 1)
+	broker.onListenerEvent = func(event pq.ListenerEventType, err error) {
+		if event == pq.ListenerEventConnectionAttemptFailed {
+			signal(attempted)
+		}
+	}
+
+	// 数据库不可用时 Start 一直等待连接，ctx 被取消后返回
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- broker.Start(ctx, "postgresql://root@127.0.0.1:1/simple_bank?sslmode=disable&connect_timeout=1")
+	}()
+
+	require.Eventually(t, func() bool { return len(attempted) > 0 }, 5*time.Second, 10*time.Millisecond)
+	require.Empty(t, done, "Start returned before ctx was canceled")
+	cancel()
+
+	var err error
+	require.Eventually(t, func() bool {
+		select {
+		case err = <-done:
+			return true
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond, "broker did not stop after ctx was canceled")
+	require.NoError(t, err)
+}
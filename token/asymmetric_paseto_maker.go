@@ -0,0 +1,119 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/o1egl/paseto"
+)
+
+// PublicKey 用于验证 token 签名的公钥，ID 保存在 token 的 footer 中
+type PublicKey struct {
+	ID  string
+	Key ed25519.PublicKey
+}
+
+// KeySet is implemented by makers that sign tokens with asymmetric keys.
+// 其他服务只需要公钥即可验证 token
+type KeySet interface {
+	// PublicKeys returns all keys that can be used to verify tokens
+	PublicKeys() []PublicKey
+}
+
+// token footer，记录签名使用的密钥 ID
+type keyFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// AsymmetricPasetoMaker is a PASETO v2.public token maker.
+// 使用当前私钥签名，可以使用多个公钥验证，以便轮换密钥时旧 token 仍然有效
+type AsymmetricPasetoMaker struct {
+	paseto     *paseto.V2
+	keyID      string
+	privateKey ed25519.PrivateKey
+	publicKeys []PublicKey
+}
+
+// NewAsymmetricPasetoMaker creates a maker that signs tokens with privateKey.
+// verificationKeys 为轮换前仍然有效的旧公钥，当前私钥对应的公钥会自动加入
+func NewAsymmetricPasetoMaker(keyID string, privateKey ed25519.PrivateKey, verificationKeys ...PublicKey) (Maker, error) {
+	if len(keyID) == 0 {
+		return nil, errors.New("key id must not be empty")
+	}
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key size: must be exactly %d bytes", ed25519.PrivateKeySize)
+	}
+
+	maker := &AsymmetricPasetoMaker{
+		paseto:     paseto.NewV2(),
+		keyID:      keyID,
+		privateKey: privateKey,
+		publicKeys: []PublicKey{{ID: keyID, Key: privateKey.Public().(ed25519.PublicKey)}},
+	}
+
+	for _, key := range verificationKeys {
+		if len(key.Key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid public key size for key %q: must be exactly %d bytes", key.ID, ed25519.PublicKeySize)
+		}
+		if _, ok := maker.publicKey(key.ID); ok {
+			return nil, fmt.Errorf("duplicate key id: %q", key.ID)
+		}
+		maker.publicKeys = append(maker.publicKeys, key)
+	}
+
+	return maker, nil
+}
+
+// CreateToken creates a new token signed by the current private key
+func (maker *AsymmetricPasetoMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, tokenType, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, err := maker.paseto.Sign(maker.privateKey, payload, keyFooter{KeyID: maker.keyID})
+	return token, payload, err
+}
+
+// VerifyToken checks if the token is signed by one of the public keys
+func (maker *AsymmetricPasetoMaker) VerifyToken(token string) (*Payload, error) {
+	// 先根据 footer 中的密钥 ID 找到对应的公钥
+	var footer keyFooter
+	if err := paseto.ParseFooter(token, &footer); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	publicKey, ok := maker.publicKey(footer.KeyID)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	payload := &Payload{}
+	if err := maker.paseto.Verify(token, publicKey, payload, nil); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if err := payload.Valid(); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// PublicKeys returns the current public key first, followed by the older ones
+func (maker *AsymmetricPasetoMaker) PublicKeys() []PublicKey {
+	keys := make([]PublicKey, len(maker.publicKeys))
+	copy(keys, maker.publicKeys)
+	return keys
+}
+
+func (maker *AsymmetricPasetoMaker) publicKey(keyID string) (ed25519.PublicKey, bool) {
+	for _, key := range maker.publicKeys {
+		if key.ID == keyID {
+			return key.Key, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,133 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"simplebank/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func randomPrivateKey(t *testing.T) ed25519.PrivateKey {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return privateKey
+}
+
+func TestAsymmetricPasetoMaker(t *testing.T) {
+	maker, err := NewAsymmetricPasetoMaker("key-1", randomPrivateKey(t))
+	require.NoError(t, err)
+
+	username := utils.RandomOwner()
+	role := "admin"
+	issuedAt := time.Now()
+	duration := time.Minute
+	expiredAt := issuedAt.Add(duration)
+
+	token, payload, err := maker.CreateToken(username, role, TokenTypeAccess, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	require.NotZero(t, payload.ID)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.Equal(t, TokenTypeAccess, payload.Type)
+}
+
+func TestExpiredAsymmetricPasetoMaker(t *testing.T) {
+	maker, err := NewAsymmetricPasetoMaker("key-1", randomPrivateKey(t))
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(utils.RandomOwner(), "customer", TokenTypeAccess, -time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.EqualError(t, err, ErrTokenExpired.Error())
+	require.Nil(t, payload)
+}
+
+func TestAsymmetricPasetoMakerKeyRotation(t *testing.T) {
+	oldKey := randomPrivateKey(t)
+	oldMaker, err := NewAsymmetricPasetoMaker("key-1", oldKey)
+	require.NoError(t, err)
+
+	oldToken, _, err := oldMaker.CreateToken(utils.RandomOwner(), "customer", TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	// 轮换后使用新私钥签名，旧公钥仍可验证之前签发的 token
+	newMaker, err := NewAsymmetricPasetoMaker("key-2", randomPrivateKey(t), PublicKey{
+		ID:  "key-1",
+		Key: oldKey.Public().(ed25519.PublicKey),
+	})
+	require.NoError(t, err)
+
+	_, err = newMaker.VerifyToken(oldToken)
+	require.NoError(t, err)
+
+	newToken, _, err := newMaker.CreateToken(utils.RandomOwner(), "customer", TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+	_, err = newMaker.VerifyToken(newToken)
+	require.NoError(t, err)
+
+	// 旧的 maker 不认识新密钥
+	_, err = oldMaker.VerifyToken(newToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+
+	keys := newMaker.(KeySet).PublicKeys()
+	require.Len(t, keys, 2)
+	require.Equal(t, "key-2", keys[0].ID)
+	require.Equal(t, "key-1", keys[1].ID)
+}
+
+func TestInvalidAsymmetricPasetoMaker(t *testing.T) {
+	privateKey := randomPrivateKey(t)
+	maker, err := NewAsymmetricPasetoMaker("key-1", privateKey)
+	require.NoError(t, err)
+
+	// 相同的密钥 ID 但私钥不同，签名无法通过验证
+	forgedMaker, err := NewAsymmetricPasetoMaker("key-1", randomPrivateKey(t))
+	require.NoError(t, err)
+
+	token, _, err := forgedMaker.CreateToken(utils.RandomOwner(), "customer", TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+
+	// 对称加密的 token 没有密钥 ID
+	symmetricMaker, err := NewPasetoMaker(utils.RandomString(32))
+	require.NoError(t, err)
+
+	token, _, err = symmetricMaker.CreateToken(utils.RandomOwner(), "customer", TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	payload, err = maker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestNewAsymmetricPasetoMakerInvalidKeys(t *testing.T) {
+	privateKey := randomPrivateKey(t)
+
+	_, err := NewAsymmetricPasetoMaker("", privateKey)
+	require.Error(t, err)
+
+	_, err = NewAsymmetricPasetoMaker("key-1", privateKey[:10])
+	require.Error(t, err)
+
+	_, err = NewAsymmetricPasetoMaker("key-1", privateKey, PublicKey{
+		ID:  "key-1",
+		Key: privateKey.Public().(ed25519.PublicKey),
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,54 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// ParsePrivateKey decodes a base64 encoded Ed25519 private key.
+// 支持 32 字节的 seed 或 64 字节的完整私钥
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode private key: %w", err)
+	}
+
+	switch len(key) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(key), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(key), nil
+	default:
+		return nil, fmt.Errorf("invalid private key size: %d bytes", len(key))
+	}
+}
+
+// ParsePublicKeys decodes a comma separated list of "id:base64" public keys.
+func ParsePublicKeys(s string) ([]PublicKey, error) {
+	keys := []PublicKey{}
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if len(field) == 0 {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(field, ":")
+		if !ok || len(id) == 0 {
+			return nil, fmt.Errorf("invalid public key format: %q", field)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode public key %q: %w", id, err)
+		}
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid public key size for key %q: %d bytes", id, len(key))
+		}
+
+		keys = append(keys, PublicKey{ID: id, Key: ed25519.PublicKey(key)})
+	}
+
+	return keys, nil
+}
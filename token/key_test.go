@@ -0,0 +1,51 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePrivateKey(t *testing.T) {
+	privateKey := randomPrivateKey(t)
+
+	key, err := ParsePrivateKey(base64.StdEncoding.EncodeToString(privateKey))
+	require.NoError(t, err)
+	require.Equal(t, privateKey, key)
+
+	// 只提供 seed 时生成相同的私钥
+	key, err = ParsePrivateKey(base64.StdEncoding.EncodeToString(privateKey.Seed()))
+	require.NoError(t, err)
+	require.Equal(t, privateKey, key)
+
+	_, err = ParsePrivateKey("invalid")
+	require.Error(t, err)
+
+	_, err = ParsePrivateKey(base64.StdEncoding.EncodeToString([]byte("short")))
+	require.Error(t, err)
+}
+
+func TestParsePublicKeys(t *testing.T) {
+	key1 := randomPrivateKey(t).Public().(ed25519.PublicKey)
+	key2 := randomPrivateKey(t).Public().(ed25519.PublicKey)
+
+	s := fmt.Sprintf("key-1:%s, key-2:%s",
+		base64.StdEncoding.EncodeToString(key1),
+		base64.StdEncoding.EncodeToString(key2))
+	keys, err := ParsePublicKeys(s)
+	require.NoError(t, err)
+	require.Equal(t, []PublicKey{{ID: "key-1", Key: key1}, {ID: "key-2", Key: key2}}, keys)
+
+	keys, err = ParsePublicKeys("")
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	_, err = ParsePublicKeys("key-1")
+	require.Error(t, err)
+
+	_, err = ParsePublicKeys("key-1:" + base64.StdEncoding.EncodeToString([]byte("short")))
+	require.Error(t, err)
+}
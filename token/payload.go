@@ -10,17 +10,29 @@ import (
 var (
 	ErrInvalidToken = errors.New("token is invalid")
 	ErrTokenExpired = errors.New("token is expired")
+	// ErrInvalidTokenType token 的类型与用途不符，例如使用 refresh token 访问接口
+	ErrInvalidTokenType = errors.New("token type is invalid")
+)
+
+// TokenType 区分 access token 和 refresh token，两者使用相同的密钥签发，不能互相代替
+type TokenType string
+
+const (
+	TokenTypeAccess  TokenType = "access"
+	TokenTypeRefresh TokenType = "refresh"
 )
 
 // token 元数据
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
 	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	Type      TokenType `json:"type"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
 }
 
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+func NewPayload(username string, role string, tokenType TokenType, duration time.Duration) (*Payload, error) {
 	tokenId, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -28,6 +40,8 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 	payload := &Payload{
 		ID:        tokenId,
 		Username:  username,
+		Role:      role,
+		Type:      tokenType,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 	}
@@ -43,3 +57,12 @@ func (payload *Payload) Valid() error {
 
 	return nil
 }
+
+// VerifyType checks if the token is issued for the given purpose
+func (payload *Payload) VerifyType(tokenType TokenType) error {
+	if payload.Type != tokenType {
+		return ErrInvalidTokenType
+	}
+
+	return nil
+}
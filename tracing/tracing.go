@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 导出 span 的方式，为空时不记录 span
+const (
+	ExporterNone   = ""
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+const (
+	serviceName = "simplebank"
+	tracerName  = "simplebank"
+)
+
+// Tracer 返回本服务使用的 tracer，Setup 之前返回的 tracer 不会记录 span
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Setup 设置全局的 TracerProvider 和 W3C trace context 传播方式
+// endpoint 为空时 OTLP 使用 OTEL_EXPORTER_OTLP_ENDPOINT 等标准环境变量
+// 返回的函数在退出前调用，导出缓冲中剩余的 span
+func Setup(ctx context.Context, exporterName string, endpoint string, sampleRatio float64) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch exporterName {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		var options []otlptracegrpc.Option
+		if len(endpoint) > 0 {
+			options = append(options, otlptracegrpc.WithEndpoint(endpoint))
+		}
+		exporter, err = otlptracegrpc.New(ctx, options...)
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter: %q", exporterName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot create tracing exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	// 上游已经决定采样时沿用上游的决定
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestSetup(t *testing.T) {
+	testCases := []struct {
+		name     string
+		exporter string
+		check    func(t *testing.T, shutdown func(context.Context) error, err error)
+	}{
+		{
+			name:     "Disabled",
+			exporter: ExporterNone,
+			check: func(t *testing.T, shutdown func(context.Context) error, err error) {
+				require.NoError(t, err)
+				require.NoError(t, shutdown(context.Background()))
+
+				_, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+				require.False(t, ok)
+			},
+		},
+		{
+			name:     "Stdout",
+			exporter: ExporterStdout,
+			check: func(t *testing.T, shutdown func(context.Context) error, err error) {
+				require.NoError(t, err)
+
+				_, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+				require.True(t, ok)
+				require.NoError(t, shutdown(context.Background()))
+			},
+		},
+		{
+			name:     "Unsupported",
+			exporter: "jaeger",
+			check: func(t *testing.T, shutdown func(context.Context) error, err error) {
+				require.Error(t, err)
+				require.Nil(t, shutdown)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			shutdown, err := Setup(context.Background(), tc.exporter, "", 1)
+			tc.check(t, shutdown, err)
+
+			// 无论是否启用导出，都使用 W3C trace context 传播
+			require.Contains(t, otel.GetTextMapPropagator().Fields(), "traceparent")
+		})
+	}
+}
@@ -9,11 +9,60 @@ import (
 // Config stores all configuration of the application.
 // The values are read by viper from a config file or environment variables.
 type Config struct {
-	DBDriver            string        `mapstructure:"DB_DRIVER"`
-	DBSource            string        `mapstructure:"DB_SOURCE"`
-	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuartion time.Duration `mapstructure:"ACCESS_TOKEN_DUARTION"`
+	Environment          string        `mapstructure:"ENVIRONMENT"`
+	LogLevel             string        `mapstructure:"LOG_LEVEL"`
+	DBDriver             string        `mapstructure:"DB_DRIVER"`
+	DBSource             string        `mapstructure:"DB_SOURCE"`
+	ServerAddress        string        `mapstructure:"SERVER_ADDRESS"`
+	GRPCServerAddress    string        `mapstructure:"GRPC_SERVER_ADDRESS"`
+	MetricsAddress       string        `mapstructure:"METRICS_ADDRESS"`
+	TokenSymmetricKey    string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	TokenKeyID           string        `mapstructure:"TOKEN_KEY_ID"`
+	TokenPrivateKey      string        `mapstructure:"TOKEN_PRIVATE_KEY"`
+	TokenPublicKeys      string        `mapstructure:"TOKEN_PUBLIC_KEYS"`
+	AccessTokenDuartion  time.Duration `mapstructure:"ACCESS_TOKEN_DUARTION"`
+	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	// 从数据库刷新 token 吊销缓存的间隔，为 0 时只使用本实例的吊销记录
+	TokenRevocationRefreshInterval time.Duration `mapstructure:"TOKEN_REVOCATION_REFRESH_INTERVAL"`
+	IdempotencyKeyTTL              time.Duration `mapstructure:"IDEMPOTENCY_KEY_TTL"`
+	// 清理过期幂等键的间隔，为 0 时不启用
+	IdempotencyKeyPurgeInterval time.Duration `mapstructure:"IDEMPOTENCY_KEY_PURGE_INTERVAL"`
+	FXQuoteDuration             time.Duration `mapstructure:"FX_QUOTE_DURATION"`
+	ExchangeRatesFile           string        `mapstructure:"EXCHANGE_RATES_FILE"`
+	// 服务内定期对账的间隔，为 0 时不启用
+	ReconcileInterval  time.Duration `mapstructure:"RECONCILE_INTERVAL"`
+	ReconcileBatchSize int32         `mapstructure:"RECONCILE_BATCH_SIZE"`
+	// 执行定时转账的间隔，为 0 时不启用
+	SchedulerInterval  time.Duration `mapstructure:"SCHEDULER_INTERVAL"`
+	SchedulerBatchSize int           `mapstructure:"SCHEDULER_BATCH_SIZE"`
+	// 定时转账连续余额不足达到该次数后暂停，之前每隔重试间隔重试一次
+	ScheduledTransferMaxFailures   int32         `mapstructure:"SCHEDULED_TRANSFER_MAX_FAILURES"`
+	ScheduledTransferRetryInterval time.Duration `mapstructure:"SCHEDULED_TRANSFER_RETRY_INTERVAL"`
+	// 投递 webhook 事件的间隔，为 0 时不启用
+	WebhookDispatchInterval time.Duration `mapstructure:"WEBHOOK_DISPATCH_INTERVAL"`
+	WebhookBatchSize        int32         `mapstructure:"WEBHOOK_BATCH_SIZE"`
+	// 投递失败后按指数退避重试，达到最大次数后进入死信
+	WebhookMaxAttempts  int32         `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookRetryBackoff time.Duration `mapstructure:"WEBHOOK_RETRY_BACKOFF"`
+	// SSE 连接发送心跳的间隔
+	StreamHeartbeatInterval time.Duration `mapstructure:"STREAM_HEARTBEAT_INTERVAL"`
+	// span 导出方式：为空时不启用，stdout 用于本地调试，otlp 发送到 OTLP_ENDPOINT
+	TracingExporter    string  `mapstructure:"TRACING_EXPORTER"`
+	OTLPEndpoint       string  `mapstructure:"OTLP_ENDPOINT"`
+	TracingSampleRatio float64 `mapstructure:"TRACING_SAMPLE_RATIO"`
+	// HTTP 服务的超时，为 0 时不限制；SSE 连接不受写超时限制
+	HTTPReadTimeout  time.Duration `mapstructure:"HTTP_READ_TIMEOUT"`
+	HTTPWriteTimeout time.Duration `mapstructure:"HTTP_WRITE_TIMEOUT"`
+	HTTPIdleTimeout  time.Duration `mapstructure:"HTTP_IDLE_TIMEOUT"`
+	// 收到 SIGTERM 后等待处理中的请求和后台任务结束的最长时间
+	ShutdownTimeout time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	// 转账事务的隔离级别，例如 read_committed（默认）或 serializable
+	TransferIsolation string `mapstructure:"TRANSFER_ISOLATION"`
+	// 事务因序列化失败或死锁中止时最多执行的次数，以及第一次重试前的等待时间
+	TxMaxAttempts  int           `mapstructure:"TX_MAX_ATTEMPTS"`
+	TxRetryBackoff time.Duration `mapstructure:"TX_RETRY_BACKOFF"`
+	// 启动时把数据库迁移到最新版本；关闭时需要先执行 migrate up，否则拒绝启动
+	MigrateOnStart bool `mapstructure:"MIGRATE_ON_START"`
 }
 
 // LoadConig reads configuration from config file or environment variables.
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 汇率以整数保存，实际汇率 = rate / ExchangeRateScale，避免浮点误差
+const (
+	ExchangeRateScale    = 100000000
+	exchangeRateDecimals = 8
+)
+
+// ExchangeRate 1 单位 BaseCurrency 可兑换 Rate / ExchangeRateScale 单位 QuoteCurrency
+type ExchangeRate struct {
+	BaseCurrency  string
+	QuoteCurrency string
+	Rate          int64
+}
+
+// ParseExchangeRate parses a decimal string such as "7.12345678" into a scaled rate.
+func ParseExchangeRate(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if len(intPart) == 0 || len(fracPart) > exchangeRateDecimals {
+		return 0, fmt.Errorf("invalid exchange rate: %q", s)
+	}
+
+	fracPart += strings.Repeat("0", exchangeRateDecimals-len(fracPart))
+	rate, err := strconv.ParseInt(intPart+fracPart, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid exchange rate: %q", s)
+	}
+	if rate <= 0 {
+		return 0, fmt.Errorf("exchange rate must be positive: %q", s)
+	}
+
+	return rate, nil
+}
+
+// FormatExchangeRate formats a scaled rate as a decimal string.
+func FormatExchangeRate(rate int64) string {
+	s := fmt.Sprintf("%d.%08d", rate/ExchangeRateScale, rate%ExchangeRateScale)
+	return strings.TrimRight(strings.TrimRight(s, "0"), ".")
+}
+
+// ConvertAmount converts amount with a scaled rate, rounding down.
+// 使用大整数计算，避免 amount * rate 溢出
+func ConvertAmount(amount, rate int64) int64 {
+	result := new(big.Int).Mul(big.NewInt(amount), big.NewInt(rate))
+	result.Quo(result, big.NewInt(ExchangeRateScale))
+	return result.Int64()
+}
+
+// ProrateAmount returns amount * numerator / denominator, rounding down.
+// 用于按原转账的比例折算部分冲正的金额
+func ProrateAmount(amount, numerator, denominator int64) int64 {
+	result := new(big.Int).Mul(big.NewInt(amount), big.NewInt(numerator))
+	result.Quo(result, big.NewInt(denominator))
+	return result.Int64()
+}
+
+// LoadExchangeRates reads exchange rates from a CSV file.
+// 文件第一行为表头：base_currency,quote_currency,rate
+func LoadExchangeRates(path string) ([]ExchangeRate, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadExchangeRates(file)
+}
+
+// ReadExchangeRates reads exchange rates in CSV format from r.
+func ReadExchangeRates(r io.Reader) ([]ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read exchange rates: %w", err)
+	}
+
+	rates := []ExchangeRate{}
+	for i, record := range records {
+		// 跳过表头
+		if i == 0 {
+			continue
+		}
+
+		base, quote := strings.ToUpper(record[0]), strings.ToUpper(record[1])
+		if !IsSupportCurrency(base) || !IsSupportCurrency(quote) {
+			return nil, fmt.Errorf("line %d: unsupported currency pair %s/%s", i+1, base, quote)
+		}
+
+		rate, err := ParseExchangeRate(record[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		rates = append(rates, ExchangeRate{
+			BaseCurrency:  base,
+			QuoteCurrency: quote,
+			Rate:          rate,
+		})
+	}
+
+	return rates, nil
+}
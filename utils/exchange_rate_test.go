@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExchangeRate(t *testing.T) {
+	rate, err := ParseExchangeRate("7.12345678")
+	require.NoError(t, err)
+	require.Equal(t, int64(712345678), rate)
+	require.Equal(t, "7.12345678", FormatExchangeRate(rate))
+
+	rate, err = ParseExchangeRate("10")
+	require.NoError(t, err)
+	require.Equal(t, int64(10*ExchangeRateScale), rate)
+	require.Equal(t, "10", FormatExchangeRate(rate))
+
+	rate, err = ParseExchangeRate("0.5")
+	require.NoError(t, err)
+	require.Equal(t, int64(ExchangeRateScale/2), rate)
+	require.Equal(t, "0.5", FormatExchangeRate(rate))
+
+	for _, s := range []string{"", "abc", "1.123456789", "0", "-1", ".5"} {
+		_, err = ParseExchangeRate(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestConvertAmount(t *testing.T) {
+	rate, err := ParseExchangeRate("7.2")
+	require.NoError(t, err)
+	require.Equal(t, int64(720), ConvertAmount(100, rate))
+
+	// 向下取整
+	rate, err = ParseExchangeRate("0.1388")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), ConvertAmount(10, rate))
+	require.Equal(t, int64(0), ConvertAmount(1, rate))
+
+	// 大金额不会溢出
+	require.Equal(t, int64(1)<<62, ConvertAmount(int64(1)<<62, ExchangeRateScale))
+}
+
+func TestReadExchangeRates(t *testing.T) {
+	data := "base_currency,quote_currency,rate\nUSD,RMB,7.2\neur, usd, 1.087\n"
+	rates, err := ReadExchangeRates(strings.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, []ExchangeRate{
+		{BaseCurrency: USD, QuoteCurrency: RMB, Rate: 720000000},
+		{BaseCurrency: EUR, QuoteCurrency: USD, Rate: 108700000},
+	}, rates)
+
+	_, err = ReadExchangeRates(strings.NewReader("base_currency,quote_currency,rate\nUSD,JPY,150\n"))
+	require.Error(t, err)
+
+	_, err = ReadExchangeRates(strings.NewReader("base_currency,quote_currency,rate\nUSD,RMB\n"))
+	require.Error(t, err)
+}
+
+func TestLoadExchangeRates(t *testing.T) {
+	rates, err := LoadExchangeRates("../exchange_rates.csv")
+	require.NoError(t, err)
+	require.NotEmpty(t, rates)
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// ParseCronExpr parses a standard 5-field cron expression.
+// 支持 @daily、@monthly 等描述符，以及 CRON_TZ=Asia/Shanghai 前缀指定时区
+func ParseCronExpr(expr string) (cron.Schedule, error) {
+	return cron.ParseStandard(expr)
+}
+
+// NextRunTime returns the first time after t that matches the cron expression.
+func NextRunTime(expr string, t time.Time) (time.Time, error) {
+	schedule, err := ParseCronExpr(expr)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return schedule.Next(t), nil
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextRunTime(t *testing.T) {
+	now := time.Date(2023, 3, 15, 10, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"@monthly", time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9 1 * *", time.Date(2023, 4, 1, 9, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2023, 3, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 0 * * MON", time.Date(2023, 3, 20, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range testCases {
+		next, err := NextRunTime(tc.expr, now)
+		require.NoError(t, err, tc.expr)
+		require.True(t, tc.want.Equal(next), "%s: want %v, got %v", tc.expr, tc.want, next)
+	}
+}
+
+func TestNextRunTimeInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * *", "61 * * * *", "@every-day"} {
+		_, err := NextRunTime(expr, time.Now())
+		require.Error(t, err, expr)
+	}
+}
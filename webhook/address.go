@@ -0,0 +1,135 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrNonPublicAddress webhook 地址指向本机、内网或链路本地地址
+// 投递请求由服务端发出，不拒绝的话用户可以通过 webhook 访问内部服务和云平台的元数据接口
+var ErrNonPublicAddress = errors.New("webhook address is not public")
+
+// nonPublicNetworks net.IP 的方法没有覆盖的保留网段
+// IPv4 网段同样匹配 IPv4-mapped 地址（::ffff:a.b.c.d），见 net.IPNet.Contains
+var nonPublicNetworks = mustParseCIDRs(
+	"0.0.0.0/8",      // 本网络，部分系统上连接 0.x.x.x 会连到本机
+	"100.64.0.0/10",  // 运营商级 NAT 共享地址
+	"192.0.0.0/24",   // IETF 协议分配
+	"198.18.0.0/15",  // 网络设备基准测试
+	"240.0.0.0/4",    // 保留地址和受限广播地址
+	"::/96",          // 已废弃的 IPv4 兼容地址，可以内嵌任意 IPv4 地址
+	"64:ff9b::/96",   // NAT64，由网关转换为内嵌的 IPv4 地址
+	"64:ff9b:1::/48", // 本地 NAT64
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// IsPublicIP 判断 IP 是否可以作为投递目标
+func IsPublicIP(ip net.IP) bool {
+	if ip == nil ||
+		ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() {
+		return false
+	}
+
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateURL 检查注册的 webhook 地址：必须是带 host 的 http/https 绝对地址，requireHTTPS 时只允许 https
+// host 为 IP 或 localhost 时必须是公网地址；域名在投递时解析后再检查，见 dialPublic
+func ValidateURL(rawURL string, requireHTTPS bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && !requireHTTPS:
+	default:
+		return fmt.Errorf("unsupported scheme: %q", u.Scheme)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if len(host) == 0 {
+		return errors.New("missing host")
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrNonPublicAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && !IsPublicIP(ip) {
+		return ErrNonPublicAddress
+	}
+	return nil
+}
+
+// newHTTPClient 投递使用的 HTTP 客户端，每次建立连接时检查解析得到的 IP
+// 注册时检查过的域名之后可能被解析到内网地址，重定向的目标也需要检查
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// 通过代理连接时检查的是代理的地址，投递请求不使用代理
+	transport.Proxy = nil
+	transport.DialContext = dialPublic
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   requestTimeout,
+	}
+}
+
+// dialPublic 解析 host 后只连接检查过的 IP，避免检查与连接之间 DNS 解析结果发生变化
+// 解析结果中只要有非公网地址就拒绝连接
+func dialPublic(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+	for _, addr := range addrs {
+		if !IsPublicIP(addr.IP) {
+			return nil, fmt.Errorf("%w: %s resolves to %s", ErrNonPublicAddress, host, addr.IP)
+		}
+	}
+
+	var dialer net.Dialer
+	for _, addr := range addrs {
+		var conn net.Conn
+		conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(addr.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
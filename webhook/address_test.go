@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	public := []string{
+		"8.8.8.8", "1.1.1.1", "2001:4860:4860::8888", "::ffff:8.8.8.8",
+		// 保留网段两侧的公网地址
+		"1.0.0.0", "100.63.255.255", "100.128.0.0", "192.0.1.0", "198.17.255.255", "198.20.0.0",
+		"223.255.255.255", "64:ff9b:0:0:1::808:808",
+	}
+	for _, ip := range public {
+		require.True(t, IsPublicIP(net.ParseIP(ip)), ip)
+	}
+
+	require.False(t, IsPublicIP(nil))
+
+	testCases := []struct {
+		name string
+		ips  []string
+	}{
+		{"loopback", []string{"127.0.0.1", "127.255.255.255", "::1"}},
+		{"private", []string{"10.0.0.1", "172.16.0.1", "192.168.1.1", "fd00::1"}},
+		{"link local", []string{"169.254.169.254", "fe80::1"}},
+		{"multicast", []string{"224.0.0.1", "ff02::1"}},
+		{"unspecified", []string{"0.0.0.0", "::"}},
+		{"this network 0.0.0.0/8", []string{"0.0.0.1", "0.255.255.255"}},
+		{"shared address 100.64.0.0/10", []string{"100.64.0.0", "100.100.100.200", "100.127.255.255"}},
+		{"ietf protocol 192.0.0.0/24", []string{"192.0.0.0", "192.0.0.170", "192.0.0.255"}},
+		{"benchmarking 198.18.0.0/15", []string{"198.18.0.0", "198.19.255.255"}},
+		{"reserved 240.0.0.0/4", []string{"240.0.0.1", "255.255.255.255"}},
+		{"ipv4 compatible ::/96", []string{"::7f00:1", "::a00:1", "::808:808"}},
+		{"nat64 64:ff9b::/96", []string{"64:ff9b::7f00:1", "64:ff9b::a9fe:a9fe", "64:ff9b::808:808"}},
+		{"local nat64 64:ff9b:1::/48", []string{"64:ff9b:1::a00:1", "64:ff9b:1:ffff::1"}},
+		{"ipv4 mapped", []string{
+			"::ffff:127.0.0.1", "::ffff:10.0.0.1", "::ffff:169.254.169.254", "::ffff:0.0.0.1",
+			"::ffff:100.64.0.1", "::ffff:192.0.0.1", "::ffff:198.18.0.1", "::ffff:240.0.0.1",
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, ip := range tc.ips {
+				parsed := net.ParseIP(ip)
+				require.NotNil(t, parsed, ip)
+				require.False(t, IsPublicIP(parsed), ip)
+			}
+		})
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	testCases := []struct {
+		url          string
+		requireHTTPS bool
+		ok           bool
+	}{
+		{"https://example.com/hooks", true, true},
+		{"http://example.com/hooks", false, true},
+		{"http://example.com/hooks", true, false},
+		{"https://8.8.8.8/hooks", true, true},
+		{"ftp://example.com/hooks", false, false},
+		{"/hooks", false, false},
+		{"https:///hooks", false, false},
+		{"http://localhost:8080/hooks", false, false},
+		{"http://api.localhost/hooks", false, false},
+		{"http://127.0.0.1/hooks", false, false},
+		{"http://[::1]/hooks", false, false},
+		{"http://10.1.2.3/hooks", false, false},
+		{"http://192.168.0.10/hooks", false, false},
+		{"http://169.254.169.254/latest/meta-data", false, false},
+		{"http://100.100.100.200/latest/meta-data", false, false},
+		{"http://[::ffff:10.0.0.1]/hooks", false, false},
+		{"http://[64:ff9b::a9fe:a9fe]/latest/meta-data", false, false},
+	}
+
+	for _, tc := range testCases {
+		err := ValidateURL(tc.url, tc.requireHTTPS)
+		if tc.ok {
+			require.NoError(t, err, tc.url)
+		} else {
+			require.Error(t, err, tc.url)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	db "simplebank/db/sqlc"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultBatchSize 每轮最多分发的事件数量和投递数量
+	DefaultBatchSize = 100
+	// DefaultMaxAttempts 投递失败达到该次数后进入死信
+	DefaultMaxAttempts = 8
+	// DefaultBackoff 第一次重试的间隔，之后每次翻倍
+	DefaultBackoff = 30 * time.Second
+
+	maxBackoff     = 6 * time.Hour
+	requestTimeout = 10 * time.Second
+	// 领取投递后的租约，进程退出时租约到期后会被重新投递
+	// 每次只领取一个投递，租约需要大于一次投递的耗时，即请求超时加上数据库操作的时间
+	leaseDuration = time.Minute
+)
+
+// Event 投递给 webhook 的请求体
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Dispatcher 把 outbox 中的事件投递到用户注册的 webhook
+// 投递至少成功一次，接收方应当按 X-Webhook-Event-Id 去重
+type Dispatcher struct {
+	store       db.Store
+	client      *http.Client
+	batchSize   int32
+	maxAttempts int32
+	backoff     time.Duration
+}
+
+// NewDispatcher creates a new Dispatcher
+func NewDispatcher(store db.Store, batchSize int32, maxAttempts int32, backoff time.Duration) *Dispatcher {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+
+	return &Dispatcher{
+		store:       store,
+		client:      newHTTPClient(),
+		batchSize:   batchSize,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+	}
+}
+
+// RunOnce 分发新的 outbox 事件，然后逐个领取并投递到期的 webhook 请求，每轮最多投递 batchSize 个
+func (d *Dispatcher) RunOnce(ctx context.Context) error {
+	if _, err := d.store.PublishOutboxTx(ctx, d.batchSize); err != nil {
+		return fmt.Errorf("cannot publish outbox events: %w", err)
+	}
+
+	// 投递是逐个发送的，一次领取多个时排在后面的投递可能在发送前租约就已到期，被其他实例重复领取
+	for i := int32(0); i < d.batchSize && ctx.Err() == nil; i++ {
+		now := time.Now()
+		deliveries, err := d.store.ClaimWebhookDeliveries(ctx, db.ClaimWebhookDeliveriesParams{
+			LeaseUntil: now.Add(leaseDuration),
+			Now:        now,
+			Limit:      1,
+		})
+		if err != nil {
+			return fmt.Errorf("cannot claim webhook deliveries: %w", err)
+		}
+		if len(deliveries) == 0 {
+			break
+		}
+
+		if err := d.deliver(ctx, deliveries[0]); err != nil {
+			return fmt.Errorf("cannot deliver webhook delivery [%d]: %w", deliveries[0].ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Start 按 interval 定期投递，直到 ctx 被取消
+func (d *Dispatcher) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.RunOnce(ctx); err != nil {
+				log.Error().Err(err).Msg("webhook dispatch failed")
+			}
+		}
+	}
+}
+
+// deliver 发送一次请求并记录结果，只有数据库操作失败时返回错误
+func (d *Dispatcher) deliver(ctx context.Context, delivery db.WebhookDelivery) error {
+	webhook, err := d.store.GetWebhook(ctx, delivery.WebhookID)
+	if err != nil {
+		// webhook 已被删除，投递记录随之删除
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	event, err := d.store.GetOutboxEvent(ctx, delivery.EventID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	update := db.UpdateWebhookDeliveryParams{
+		ID:            delivery.ID,
+		Status:        db.DeliveryStatusSucceeded,
+		Attempts:      delivery.Attempts + 1,
+		NextAttemptAt: delivery.NextAttemptAt,
+		DeliveredAt:   sql.NullTime{Time: now, Valid: true},
+	}
+
+	if err := d.send(ctx, webhook, event); err != nil {
+		update.LastError = err.Error()
+		update.DeliveredAt = sql.NullTime{}
+		if update.Attempts >= d.maxAttempts {
+			update.Status = db.DeliveryStatusDead
+		} else {
+			update.Status = db.DeliveryStatusPending
+			update.NextAttemptAt = now.Add(Backoff(d.backoff, update.Attempts))
+		}
+	}
+
+	_, err = d.store.UpdateWebhookDelivery(ctx, update)
+	return err
+}
+
+func (d *Dispatcher) send(ctx context.Context, webhook db.Webhook, event db.Outbox) error {
+	body, err := json.Marshal(Event{
+		ID:        event.ID,
+		Type:      event.EventType,
+		CreatedAt: event.CreatedAt,
+		Data:      event.Payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := time.Now().Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(HeaderEventID, strconv.FormatInt(event.ID, 10))
+	request.Header.Set(HeaderEventType, event.EventType)
+	request.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, body))
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 4096))
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+	return nil
+}
+
+// Backoff 返回第 attempts 次失败后的重试间隔：base * 2^(attempts-1)，最长 6 小时
+func Backoff(base time.Duration, attempts int32) time.Duration {
+	delay := base
+	for i := int32(1); i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
@@ -0,0 +1,271 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	mockdb "simplebank/db/mock"
+	db "simplebank/db/sqlc"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// newReceiver 启动一个校验签名的 webhook 接收方，按 statusCode 返回响应
+func newReceiver(t *testing.T, secret string, statusCode int, received chan<- Event) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, Verify(secret, r.Header, body, time.Minute))
+
+		var event Event
+		require.NoError(t, json.Unmarshal(body, &event))
+		require.Equal(t, strconv.FormatInt(event.ID, 10), r.Header.Get(HeaderEventID))
+		require.Equal(t, event.Type, r.Header.Get(HeaderEventType))
+		received <- event
+
+		w.WriteHeader(statusCode)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestDispatcher 测试的接收方监听在本机地址，不检查投递地址
+func newTestDispatcher(store db.Store) *Dispatcher {
+	dispatcher := NewDispatcher(store, 10, 3, time.Minute)
+	dispatcher.client = &http.Client{Timeout: requestTimeout}
+	return dispatcher
+}
+
+func randomDelivery(attempts int32) (db.Webhook, db.Outbox, db.WebhookDelivery) {
+	webhook := db.Webhook{ID: 1, Owner: "alice", Secret: "secret"}
+	event := db.Outbox{
+		ID:        7,
+		EventType: db.EventTransferCreated,
+		Username:  "alice",
+		Payload:   json.RawMessage(`{"id":3,"amount":10}`),
+		CreatedAt: time.Now().Truncate(time.Second).UTC(),
+	}
+	delivery := db.WebhookDelivery{
+		ID:            5,
+		WebhookID:     webhook.ID,
+		EventID:       event.ID,
+		Status:        db.DeliveryStatusPending,
+		Attempts:      attempts,
+		NextAttemptAt: time.Now().Add(leaseDuration),
+	}
+	return webhook, event, delivery
+}
+
+func expectClaim(t *testing.T, store *mockdb.MockStore, webhook db.Webhook, event db.Outbox, delivery db.WebhookDelivery) {
+	store.EXPECT().
+		PublishOutboxTx(gomock.Any(), gomock.Eq(int32(10))).
+		Times(1).
+		Return(1, nil)
+	// 每次领取一个投递，没有到期的投递时结束
+	gomock.InOrder(
+		store.EXPECT().
+			ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, arg db.ClaimWebhookDeliveriesParams) ([]db.WebhookDelivery, error) {
+				require.Equal(t, int32(1), arg.Limit)
+				require.Equal(t, leaseDuration, arg.LeaseUntil.Sub(arg.Now))
+				return []db.WebhookDelivery{delivery}, nil
+			}),
+		store.EXPECT().
+			ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(nil, nil),
+	)
+	store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(webhook, nil)
+	store.EXPECT().GetOutboxEvent(gomock.Any(), gomock.Eq(event.ID)).Times(1).Return(event, nil)
+}
+
+func TestDispatcherDelivered(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	webhook, event, delivery := randomDelivery(0)
+	received := make(chan Event, 1)
+	webhook.Url = newReceiver(t, webhook.Secret, http.StatusNoContent, received).URL
+
+	store := mockdb.NewMockStore(ctrl)
+	expectClaim(t, store, webhook, event, delivery)
+	store.EXPECT().
+		UpdateWebhookDelivery(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.UpdateWebhookDeliveryParams) (db.WebhookDelivery, error) {
+			require.Equal(t, delivery.ID, arg.ID)
+			require.Equal(t, db.DeliveryStatusSucceeded, arg.Status)
+			require.Equal(t, int32(1), arg.Attempts)
+			require.True(t, arg.DeliveredAt.Valid)
+			require.Empty(t, arg.LastError)
+			return db.WebhookDelivery{}, nil
+		})
+
+	err := newTestDispatcher(store).RunOnce(context.Background())
+	require.NoError(t, err)
+
+	got := <-received
+	require.Equal(t, event.ID, got.ID)
+	require.Equal(t, event.EventType, got.Type)
+	require.True(t, event.CreatedAt.Equal(got.CreatedAt))
+	require.JSONEq(t, string(event.Payload), string(got.Data))
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	webhook, event, delivery := randomDelivery(1)
+	received := make(chan Event, 1)
+	webhook.Url = newReceiver(t, webhook.Secret, http.StatusInternalServerError, received).URL
+
+	store := mockdb.NewMockStore(ctrl)
+	expectClaim(t, store, webhook, event, delivery)
+	store.EXPECT().
+		UpdateWebhookDelivery(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.UpdateWebhookDeliveryParams) (db.WebhookDelivery, error) {
+			// 第二次失败，等待 2 倍的基础间隔后重试
+			require.Equal(t, db.DeliveryStatusPending, arg.Status)
+			require.Equal(t, int32(2), arg.Attempts)
+			require.WithinDuration(t, time.Now().Add(2*time.Minute), arg.NextAttemptAt, time.Second)
+			require.False(t, arg.DeliveredAt.Valid)
+			require.Contains(t, arg.LastError, "500")
+			return db.WebhookDelivery{}, nil
+		})
+
+	err := newTestDispatcher(store).RunOnce(context.Background())
+	require.NoError(t, err)
+	<-received
+}
+
+func TestDispatcherDeadLetter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	webhook, event, delivery := randomDelivery(2)
+	received := make(chan Event, 1)
+	webhook.Url = newReceiver(t, webhook.Secret, http.StatusBadRequest, received).URL
+
+	store := mockdb.NewMockStore(ctrl)
+	expectClaim(t, store, webhook, event, delivery)
+	store.EXPECT().
+		UpdateWebhookDelivery(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.UpdateWebhookDeliveryParams) (db.WebhookDelivery, error) {
+			// 重试次数用尽后进入死信
+			require.Equal(t, db.DeliveryStatusDead, arg.Status)
+			require.Equal(t, int32(3), arg.Attempts)
+			require.Contains(t, arg.LastError, "400")
+			return db.WebhookDelivery{}, nil
+		})
+
+	err := newTestDispatcher(store).RunOnce(context.Background())
+	require.NoError(t, err)
+	<-received
+}
+
+func TestDispatcherWebhookDeleted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	webhook, _, delivery := randomDelivery(0)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().PublishOutboxTx(gomock.Any(), gomock.Any()).Times(1).Return(0, nil)
+	gomock.InOrder(
+		store.EXPECT().
+			ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return([]db.WebhookDelivery{delivery}, nil),
+		store.EXPECT().
+			ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(nil, nil),
+	)
+	store.EXPECT().
+		GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).
+		Times(1).
+		Return(db.Webhook{}, sql.ErrNoRows)
+	store.EXPECT().UpdateWebhookDelivery(gomock.Any(), gomock.Any()).Times(0)
+
+	err := newTestDispatcher(store).RunOnce(context.Background())
+	require.NoError(t, err)
+}
+
+func TestDispatcherPublishError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().PublishOutboxTx(gomock.Any(), gomock.Any()).Times(1).Return(0, sql.ErrConnDone)
+	store.EXPECT().ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).Times(0)
+
+	err := newTestDispatcher(store).RunOnce(context.Background())
+	require.ErrorIs(t, err, sql.ErrConnDone)
+}
+
+func TestDispatcherRejectsNonPublicAddress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	webhook, event, delivery := randomDelivery(0)
+	received := make(chan Event, 1)
+	// 域名在注册时无法检查，投递时解析到本机地址
+	receiver := newReceiver(t, webhook.Secret, http.StatusNoContent, received)
+	webhook.Url = strings.Replace(receiver.URL, "127.0.0.1", "localhost", 1)
+
+	store := mockdb.NewMockStore(ctrl)
+	expectClaim(t, store, webhook, event, delivery)
+	store.EXPECT().
+		UpdateWebhookDelivery(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.UpdateWebhookDeliveryParams) (db.WebhookDelivery, error) {
+			require.Equal(t, db.DeliveryStatusPending, arg.Status)
+			require.False(t, arg.DeliveredAt.Valid)
+			require.Contains(t, arg.LastError, ErrNonPublicAddress.Error())
+			return db.WebhookDelivery{}, nil
+		})
+
+	err := NewDispatcher(store, 10, 3, time.Minute).RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, received)
+}
+
+func TestDispatcherClaimsOneAtATime(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	webhook, event, delivery := randomDelivery(0)
+	received := make(chan Event, 3)
+	webhook.Url = newReceiver(t, webhook.Secret, http.StatusNoContent, received).URL
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().PublishOutboxTx(gomock.Any(), gomock.Any()).Times(1).Return(0, nil)
+	// 每个投递发送前才领取，租约从领取时开始计算；达到 batchSize 后本轮结束
+	store.EXPECT().
+		ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Times(3).
+		DoAndReturn(func(_ context.Context, arg db.ClaimWebhookDeliveriesParams) ([]db.WebhookDelivery, error) {
+			require.Equal(t, int32(1), arg.Limit)
+			return []db.WebhookDelivery{delivery}, nil
+		})
+	store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).Times(3).Return(webhook, nil)
+	store.EXPECT().GetOutboxEvent(gomock.Any(), gomock.Eq(event.ID)).Times(3).Return(event, nil)
+	store.EXPECT().UpdateWebhookDelivery(gomock.Any(), gomock.Any()).Times(3).Return(db.WebhookDelivery{}, nil)
+
+	dispatcher := newTestDispatcher(store)
+	dispatcher.batchSize = 3
+	err := dispatcher.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Len(t, received, 3)
+}
@@ -0,0 +1,50 @@
+package webhook
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func signedHeader(secret string, timestamp int64, body []byte) http.Header {
+	header := http.Header{}
+	header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	header.Set(HeaderSignature, Sign(secret, timestamp, body))
+	return header
+}
+
+func TestVerify(t *testing.T) {
+	secret, err := NewSecret()
+	require.NoError(t, err)
+	require.Len(t, secret, 64)
+
+	body := []byte(`{"id":1}`)
+	now := time.Now().Unix()
+
+	require.NoError(t, Verify(secret, signedHeader(secret, now, body), body, time.Minute))
+
+	// 密钥、请求体或时间戳被篡改
+	require.ErrorIs(t, Verify("wrong", signedHeader(secret, now, body), body, time.Minute), ErrInvalidSignature)
+	require.ErrorIs(t, Verify(secret, signedHeader(secret, now, body), []byte(`{"id":2}`), time.Minute), ErrInvalidSignature)
+
+	header := signedHeader(secret, now, body)
+	header.Set(HeaderTimestamp, strconv.FormatInt(now+1, 10))
+	require.ErrorIs(t, Verify(secret, header, body, time.Minute), ErrInvalidSignature)
+
+	require.ErrorIs(t, Verify(secret, http.Header{}, body, time.Minute), ErrInvalidSignature)
+
+	// 签名正确但时间戳过期
+	old := time.Now().Add(-time.Hour).Unix()
+	require.ErrorIs(t, Verify(secret, signedHeader(secret, old, body), body, time.Minute), ErrExpiredTimestamp)
+	require.NoError(t, Verify(secret, signedHeader(secret, old, body), body, 0))
+}
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, 30*time.Second, Backoff(30*time.Second, 1))
+	require.Equal(t, time.Minute, Backoff(30*time.Second, 2))
+	require.Equal(t, 4*time.Minute, Backoff(30*time.Second, 4))
+	require.Equal(t, maxBackoff, Backoff(30*time.Second, 20))
+}